@@ -10,10 +10,25 @@ import (
 
 func main() {
 	app := &cli.App{
+		Flags: []cli.Flag{
+			subcommands.SeedFlag,
+		},
 		Commands: []*cli.Command{
 			&subcommands.Keygen,
 			&subcommands.SendSignal,
+			&subcommands.EncryptSlot,
 			&subcommands.Search,
+			&subcommands.DKG,
+			&subcommands.DeriveShare,
+			&subcommands.Combine,
+			&subcommands.Verify,
+			&subcommands.VerifyKey,
+			&subcommands.RegisterMemoKey,
+			&subcommands.Info,
+			&subcommands.AddParty,
+			&subcommands.Revoke,
+			&subcommands.Rotate,
+			&subcommands.TestVectors,
 		},
 	}
 	err := app.Run(os.Args)
@@ -0,0 +1,67 @@
+package subcommands
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/authority"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/recipient"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/rounds"
+)
+
+var (
+	AddParty = cli.Command{
+		Action: addParty,
+		Name:   "add-party",
+		Usage:  "Issues key of the next reserved slot to a new recipient",
+	}
+)
+
+func addParty(_ *cli.Context) error {
+	repo, err := rounds.OpenRepository("stand/repo")
+	if err != nil {
+		return errors.Wrap(err, "cannot open repository")
+	}
+	epochs, err := repo.KeyEpochs()
+	if err != nil {
+		return errors.Wrap(err, "cannot retrieve key epochs")
+	}
+	epoch := len(epochs) - 1
+	mpk := epochs[epoch].MPK
+	membership, err := repo.GetMembership()
+	if err != nil {
+		return errors.Wrap(err, "cannot retrieve membership")
+	}
+	i, err := membership.NextFree(mpk.L)
+	if err != nil {
+		return errors.Wrap(err, "no reserved slots left, run keygen with --reserve")
+	}
+
+	msk, err := authority.LoadMSK("stand/authority")
+	if err != nil {
+		return errors.Wrap(err, "cannot load msk")
+	}
+	sk, err := gofe.DeriveKey(mpk, msk, i)
+	if err != nil {
+		return errors.Wrapf(err, "cannot derive key of party %d", i+1)
+	}
+
+	// Slot is assigned first: if saving the key fails, slot stays unusable
+	// rather than ends up with two keys issued for it
+	err = repo.AssignSlot(i)
+	if err != nil {
+		return errors.Wrap(err, "cannot assign slot")
+	}
+	party := &recipient.Party{Secret: sk, Epoch: epoch}
+	party.Pin(epoch, mpk)
+	err = party.SaveRecipient("stand/parties")
+	if err != nil {
+		return errors.Wrapf(err, "cannot save party %d", i+1)
+	}
+
+	fmt.Printf("Party %d added! Its key is saved at stand/parties/party_%d.json\n", i+1, i+1)
+	return nil
+}
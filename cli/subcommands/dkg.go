@@ -0,0 +1,153 @@
+package subcommands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/dkg"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/recipient"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/rounds"
+)
+
+var (
+	dkgArgs struct {
+		parties, party int
+		scheme         string
+	}
+
+	DKG = cli.Command{
+		Name:  "dkg",
+		Usage: "Runs key generation without trusted party",
+		Subcommands: []*cli.Command{
+			{
+				Action: dkgInit,
+				Name:   "init",
+				Usage:  "Publishes public parameters of the scheme",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:        "parties",
+						Usage:       "Total amount of recipients `N` (N >= 2)",
+						Destination: &dkgArgs.parties,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "scheme",
+						Usage:       "Inner-product FE scheme, one of: " + strings.Join(distributedSchemeIDs(), ", "),
+						Destination: &dkgArgs.scheme,
+						Value:       gofe.DefaultScheme.ID(),
+					},
+				},
+			},
+			{
+				Action: dkgContribute,
+				Name:   "contribute",
+				Usage:  "Generates recipient's part of master key and its secret key",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:        "party",
+						Usage:       "Contributing recipient `j` (1 <= j <= N)",
+						Destination: &dkgArgs.party,
+						Required:    true,
+					},
+				},
+			},
+			{
+				Action: dkgFinalize,
+				Name:   "finalize",
+				Usage:  "Verifies contributions and creates repository",
+			},
+		},
+	}
+)
+
+func dkgInit(_ *cli.Context) error {
+	if dkgArgs.parties < 2 {
+		return errors.New("expected at least 2 parties!")
+	}
+	scheme, err := dkg.LookupScheme(dkgArgs.scheme)
+	if err != nil {
+		return err
+	}
+
+	transport, err := dkg.NewFileTransport("stand/dkg")
+	if err != nil {
+		return errors.Wrap(err, "cannot open dkg transport")
+	}
+	err = dkg.Init(randomness("dkg/init"), transport, scheme, dkgArgs.parties)
+	if err != nil {
+		return errors.Wrap(err, "dkg init failed")
+	}
+
+	fmt.Println("Ceremony initialized! Every party should now contribute")
+	return nil
+}
+
+func dkgContribute(_ *cli.Context) error {
+	if _, err := recipient.LoadRecipient("stand/parties", dkgArgs.party); err == nil {
+		return errors.Errorf("party %d already has a secret key", dkgArgs.party)
+	}
+
+	transport, err := dkg.NewFileTransport("stand/dkg")
+	if err != nil {
+		return errors.Wrap(err, "cannot open dkg transport")
+	}
+	sk, err := dkg.Contribute(randomness(fmt.Sprintf("dkg/contribute/%d", dkgArgs.party)), transport, dkgArgs.party-1)
+	if err != nil {
+		return errors.Wrap(err, "contribution failed")
+	}
+
+	party := &recipient.Party{Secret: sk}
+	err = party.SaveRecipient("stand/parties")
+	if err != nil {
+		return errors.Wrapf(err, "cannot save party %d", dkgArgs.party)
+	}
+
+	fmt.Printf("Party %d contributed to the ceremony!\n", dkgArgs.party)
+	return nil
+}
+
+func dkgFinalize(_ *cli.Context) error {
+	transport, err := dkg.NewFileTransport("stand/dkg")
+	if err != nil {
+		return errors.Wrap(err, "cannot open dkg transport")
+	}
+	mpk, err := dkg.Finalize(transport)
+	if err != nil {
+		return errors.Wrap(err, "dkg finalize failed")
+	}
+
+	_, err = rounds.NewEmptyRepository("stand/repo", mpk)
+	if err != nil {
+		return errors.Wrap(err, "cannot create empty repository")
+	}
+
+	// Contributions are made before mpk exists, so keys are pinned once it's
+	// assembled
+	for j := 1; j <= mpk.L; j++ {
+		party, err := recipient.LoadRecipient("stand/parties", j)
+		if err != nil {
+			return errors.Wrapf(err, "cannot load party %d", j)
+		}
+		party.Pin(party.Epoch, mpk)
+		if err := party.UpdateRecipient("stand/parties"); err != nil {
+			return errors.Wrapf(err, "cannot pin mpk fingerprint of party %d", j)
+		}
+	}
+
+	fmt.Println("Keygen completed!")
+	return nil
+}
+
+func distributedSchemeIDs() []string {
+	var ids []string
+	for _, id := range gofe.SchemeIDs() {
+		if _, err := dkg.LookupScheme(id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
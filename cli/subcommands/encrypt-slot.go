@@ -0,0 +1,94 @@
+package subcommands
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/client"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/dkg"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/rounds"
+)
+
+var (
+	slotClient int
+
+	EncryptSlot = cli.Command{
+		Action: encryptSlot,
+		Name:   "encrypt-slot",
+		Usage:  "Encrypts client's slot of requested signal, the last client publishes the signal (multi-client schemes only)",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:        "client",
+				Usage:       "Client `j` encrypting its slot (1 <= j <= N)",
+				Destination: &slotClient,
+				Required:    true,
+			},
+		},
+	}
+)
+
+// Client loads only its own key, requested signal is the one of the round
+// following the last published round
+func encryptSlot(_ *cli.Context) error {
+	repo, err := rounds.OpenRepository("stand/repo")
+	if err != nil {
+		return errors.Wrap(err, "cannot open repository")
+	}
+	mpk, err := repo.GetMPK()
+	if err != nil {
+		return errors.Wrap(err, "cannot retrieve MPK")
+	}
+	scheme, err := gofe.LookupScheme(mpk.Scheme)
+	if err != nil {
+		return err
+	}
+	if _, ok := scheme.(gofe.MultiClientScheme); !ok {
+		return errors.Errorf("scheme %s has no clients, send-signal encrypts its signals", scheme.ID())
+	}
+	if slotClient <= 0 || slotClient > mpk.L {
+		return errors.Errorf("expected client in range [1; %d]", mpk.L)
+	}
+	c, err := client.LoadClient("stand/clients", slotClient)
+	if err != nil {
+		return errors.Wrapf(err, "cannot load client %d", slotClient)
+	}
+
+	n, previousCiphertext, err := repo.GetLastRound()
+	var malformed *rounds.MalformedRoundError
+	if errors.As(err, &malformed) {
+		return errors.Wrapf(err, "refusing to accumulate signal into malformed round %d", malformed.Round)
+	} else if err != nil {
+		return errors.Wrap(err, "cannot retrieve last round")
+	}
+
+	transport, err := dkg.NewFileTransport("stand/signals")
+	if err != nil {
+		return errors.Wrap(err, "cannot open signals transport")
+	}
+	if err := c.EncryptSlot(transport, mpk, n+1); err != nil {
+		return errors.Wrapf(err, "client %d cannot encrypt its slot", slotClient)
+	}
+	fmt.Printf("Client %d encrypted its slot of signal in round %d!\n", slotClient, n+1)
+
+	delta, sealed, err := client.Combine(transport, mpk, n+1)
+	if errors.Is(err, dkg.ErrNotPublished) {
+		fmt.Println("Waiting for other clients to encrypt their slots")
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "cannot combine slots")
+	}
+	epochStarts, err := publishSignal(repo, mpk, n+1, previousCiphertext, &data.Round{Delta: &delta, Memo: sealed})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Every client encrypted its slot, signal is published in round %d!\n", n+1)
+	if epochStarts {
+		printChainStart(repo, n+1)
+	}
+	return nil
+}
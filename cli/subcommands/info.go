@@ -0,0 +1,132 @@
+package subcommands
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/rounds"
+)
+
+var (
+	Info = cli.Command{
+		Action: info,
+		Name:   "info",
+		Usage:  "Reports scheme, security level and signal capacity of repository",
+	}
+)
+
+func info(_ *cli.Context) error {
+	repo, err := rounds.OpenRepository("stand/repo")
+	if err != nil {
+		return errors.Wrap(err, "cannot open repository")
+	}
+	mpk, err := repo.GetMPK()
+	if err != nil {
+		return errors.Wrap(err, "cannot retrieve MPK")
+	}
+	n, _, err := repo.GetLastRound()
+	if err != nil {
+		return errors.Wrap(err, "cannot retrieve last round")
+	}
+
+	membership, err := repo.GetMembership()
+	if err != nil {
+		return errors.Wrap(err, "cannot retrieve membership")
+	}
+	epochs, err := repo.KeyEpochs()
+	if err != nil {
+		return errors.Wrap(err, "cannot retrieve key epochs")
+	}
+	length, err := gofe.EpochLength(mpk)
+	if err != nil {
+		return errors.Wrap(err, "cannot determine epoch length")
+	}
+	start, err := repo.ChainStart(n)
+	if err != nil {
+		return errors.Wrap(err, "cannot determine start of accumulator chain")
+	}
+
+	fmt.Printf("Scheme: %s\n", mpk.Scheme)
+	fmt.Printf("MPK fingerprint: %s\n", mpk.Fingerprint())
+	fmt.Printf("Parties: %d (%d slots reserved, %d revoked)\n",
+		len(membership.Assigned)-len(membership.Revoked), mpk.L-len(membership.Assigned), len(membership.Revoked))
+	fmt.Printf("Rounds: %d\n", n)
+	current := epochs[len(epochs)-1]
+	printKeyEpochs(epochs, n)
+	printProfile(mpk)
+	printEpoch(n, current.FirstRound, start, length)
+	return nil
+}
+
+// Prints ranges of rounds encrypted under mpk of every key epoch
+func printKeyEpochs(epochs []data.KeyEpoch, n int) {
+	fmt.Printf("Key epoch: %d\n", len(epochs)-1)
+	if len(epochs) == 1 {
+		return
+	}
+	for k, epoch := range epochs {
+		last := n
+		if k+1 < len(epochs) {
+			last = epochs[k+1].FirstRound - 1
+		}
+		switch {
+		case last < epoch.FirstRound:
+			fmt.Printf("  epoch %d: no rounds\n", k)
+		case k+1 == len(epochs):
+			fmt.Printf("  epoch %d: rounds [%d;%d], current\n", k, epoch.FirstRound, last)
+		default:
+			fmt.Printf("  epoch %d: rounds [%d;%d]\n", k, epoch.FirstRound, last)
+		}
+	}
+}
+
+// Prints how close the current epoch is to accumulator's capacity, rounds of
+// current key epoch start at `first` and accumulator chain of n-th round
+// starts at `start`
+//
+// Every round signals exactly one recipient, so amount of rounds in the epoch
+// bounds everyone's count without revealing any of them.
+func printEpoch(n, first, start, length int) {
+	if length == 0 {
+		fmt.Println("Epochs: accumulator never needs to be restarted")
+		return
+	}
+	epoch, used := rounds.EpochOf(n-first+1, length), 0
+	if n >= first {
+		used = n - start + 1
+	}
+	fmt.Printf("Epoch: %d (%d rounds per epoch)\n", epoch, length)
+	fmt.Printf("Epoch usage: %d/%d rounds (%.1f%%), no recipient received more than %d signals in this epoch\n",
+		used, length, 100*float64(used)/float64(length), used)
+}
+
+// Prints security profile and max amount recorded in mpk
+func printProfile(mpk data.MPK) {
+	if mpk.MaxAmount > 0 {
+		fmt.Printf("Amounts: up to %d per signal\n", mpk.MaxAmount)
+	}
+	profile := mpk.Profile
+	if profile == nil {
+		fmt.Println("Security profile: unknown (keys were generated with scheme defaults)")
+		return
+	}
+
+	name := profile.Name
+	if name == "" {
+		name = "custom"
+	}
+	fmt.Printf("Security profile: %s\n", name)
+	if profile.ModulusBits != 0 {
+		fmt.Printf("Modulus: %d bits\n", profile.ModulusBits)
+	}
+	if profile.SecurityBits != 0 {
+		fmt.Printf("Security level: ~%d bits\n", profile.SecurityBits)
+	} else {
+		fmt.Println("Security level: not estimated")
+	}
+	fmt.Printf("Signal capacity: %d signals per recipient\n", profile.MaxSignals)
+}
@@ -2,6 +2,13 @@ package subcommands
 
 import (
 	"fmt"
+	"strings"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/authority"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/client"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/dkg"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/memo"
 	"github.com/ZenGo-X/fe-hackaton-demo/internal/recipient"
 	"github.com/ZenGo-X/fe-hackaton-demo/internal/rounds"
 	"github.com/pkg/errors"
@@ -11,7 +18,15 @@ import (
 )
 
 var (
-	keygenParties int
+	keygenParties     int
+	keygenScheme      string
+	keygenAuthorities int
+	keygenThreshold   int
+	keygenProfile     string
+	keygenModulusBits int
+	keygenMaxSignals  int64
+	keygenReserve     int
+	keygenMaxAmount   int64
 
 	Keygen = cli.Command{
 		Action: keygen,
@@ -24,6 +39,50 @@ var (
 				Destination: &keygenParties,
 				Required:    true,
 			},
+			&cli.StringFlag{
+				Name:        "scheme",
+				Usage:       "Inner-product FE scheme, one of: " + strings.Join(gofe.SchemeIDs(), ", "),
+				Destination: &keygenScheme,
+				Value:       gofe.DefaultScheme.ID(),
+			},
+			&cli.IntFlag{
+				Name:        "authorities",
+				Usage:       "Split msk between `n` authorities instead of deriving keys of recipients",
+				Destination: &keygenAuthorities,
+			},
+			&cli.IntFlag{
+				Name:        "threshold",
+				Usage:       "Amount of authorities `k` required to derive recipient's key",
+				Destination: &keygenThreshold,
+			},
+			&cli.StringFlag{
+				Name:        "profile",
+				Usage:       "Security profile, one of: " + strings.Join(gofe.ProfileNames(), ", "),
+				Destination: &keygenProfile,
+				Value:       gofe.Profiles[0].Name,
+			},
+			&cli.IntFlag{
+				Name:        "modulus-bits",
+				Usage:       "Bit length of modulus, overrides the profile",
+				Destination: &keygenModulusBits,
+				DefaultText: "from profile",
+			},
+			&cli.Int64Flag{
+				Name:        "max-signals",
+				Usage:       "Amount of signals every recipient is able to receive, overrides the profile",
+				Destination: &keygenMaxSignals,
+				DefaultText: "from profile",
+			},
+			&cli.IntFlag{
+				Name:        "reserve",
+				Usage:       "Reserve `R` more slots for recipients added later by add-party",
+				Destination: &keygenReserve,
+			},
+			&cli.Int64Flag{
+				Name:        "max-amount",
+				Usage:       "Let signals carry amounts in range [1; `A`], every round proves its amount is within the range",
+				Destination: &keygenMaxAmount,
+			},
 		},
 	}
 )
@@ -32,25 +91,177 @@ func keygen(_ *cli.Context) error {
 	if keygenParties < 2 {
 		return errors.New("expected at least 2 parties!")
 	}
-	mpk, sk, err := gofe.GenerateMasterKeys(keygenParties)
+	scheme, err := gofe.LookupScheme(keygenScheme)
 	if err != nil {
-		return errors.Wrap(err, "keygen failed")
+		return err
+	}
+	if err := refuseUnseedable(scheme); err != nil {
+		return err
+	}
+	if keygenMaxAmount < 0 {
+		return errors.New("expected non-negative max amount")
+	}
+	if _, ok := scheme.(gofe.AmountScheme); keygenMaxAmount > 0 && !ok {
+		return errors.Errorf("scheme %s can't carry amounts", scheme.ID())
+	}
+	scheme, profile, err := configureScheme(scheme)
+	if err != nil {
+		return err
+	}
+	if keygenReserve < 0 {
+		return errors.New("expected non-negative amount of reserved slots")
+	}
+	if keygenAuthorities > 0 {
+		if keygenReserve > 0 {
+			return errors.New("reserved slots aren't supported with threshold authorities, they derive keys of any slot anyway")
+		}
+		return thresholdKeygen(scheme, profile)
+	}
+
+	var mpk data.MPK
+	var sk []data.RecipientSecretKey
+	if keygenReserve > 0 {
+		if _, ok := scheme.(gofe.MultiClientScheme); ok {
+			return errors.Errorf("scheme %s has no msk to derive keys of reserved slots", scheme.ID())
+		}
+		var msk data.MSK
+		mpk, msk, sk, err = gofe.GenerateReservedKeys(randomness("keygen"), scheme, keygenParties, keygenParties+keygenReserve)
+		if err != nil {
+			return errors.Wrap(err, "keygen failed")
+		}
+		err = authority.SaveMSK("stand/authority", msk)
+		if err != nil {
+			return errors.Wrap(err, "cannot save msk")
+		}
+	} else if multiClient, ok := scheme.(gofe.MultiClientScheme); ok {
+		var clients []data.ClientKey
+		mpk, clients, sk, err = gofe.GenerateClientKeysWith(multiClient, keygenParties)
+		if err != nil {
+			return errors.Wrap(err, "keygen failed")
+		}
+		transport, err := dkg.NewFileTransport("stand/signals")
+		if err != nil {
+			return errors.Wrap(err, "cannot open signals transport")
+		}
+		for i, key := range clients {
+			c := &client.Client{Secret: key}
+			c.Memo, err = memo.GenerateKey(randomness(fmt.Sprintf("keygen/client/%d", i+1)))
+			if err != nil {
+				return errors.Wrapf(err, "cannot generate memo key of client %d", i+1)
+			}
+			err = c.SaveClient("stand/clients")
+			if err != nil {
+				return errors.Wrapf(err, "cannot save client %d", i+1)
+			}
+			if err := c.PublishKey(transport); err != nil {
+				return errors.Wrapf(err, "cannot publish key of client %d", i+1)
+			}
+		}
+	} else {
+		mpk, sk, err = gofe.GenerateMasterKeysFrom(randomness("keygen"), scheme, keygenParties)
+		if err != nil {
+			return errors.Wrap(err, "keygen failed")
+		}
+	}
+
+	// Both are recorded before pinning, max amount affects mpk's fingerprint
+	mpk.Profile = &profile
+	mpk.MaxAmount = keygenMaxAmount
+	if err := gofe.ValidateMPK(mpk); err != nil {
+		return err
 	}
 
 	for j, skj := range sk {
 		party := &recipient.Party{Secret: skj}
+		party.Pin(0, mpk)
 		err := party.SaveRecipient("stand/parties")
 		if err != nil {
 			return errors.Wrapf(err, "cannot save party %d", j+1)
 		}
 	}
 
-	_, err = rounds.NewEmptyRepository("stand/repo", mpk)
+	repo, err := rounds.NewEmptyRepository("stand/repo", mpk)
 	if err != nil {
 		return errors.Wrap(err, "cannot create empty repository")
 	}
+	if keygenReserve > 0 {
+		err = repo.ReserveSlots(keygenParties)
+		if err != nil {
+			return errors.Wrap(err, "cannot reserve slots")
+		}
+	}
 
 	fmt.Println("Keygen completed!")
+	if keygenReserve > 0 {
+		fmt.Printf("%d slots are reserved, run add-party to issue their keys\n", keygenReserve)
+	}
+	printProfile(mpk)
+
+	return nil
+}
+
+func thresholdKeygen(scheme gofe.Scheme, profile data.Profile) error {
+	threshold, ok := scheme.(gofe.ThresholdScheme)
+	if !ok {
+		return errors.Errorf("scheme %s doesn't support threshold key derivation", scheme.ID())
+	}
+	if keygenThreshold < 1 || keygenThreshold > keygenAuthorities {
+		return errors.Errorf("expected threshold in range [1; %d]", keygenAuthorities)
+	}
+	mpk, shares, err := gofe.GenerateThresholdKeysFrom(randomness("keygen"), threshold, keygenParties, keygenThreshold, keygenAuthorities)
+	if err != nil {
+		return errors.Wrap(err, "keygen failed")
+	}
+
+	for _, share := range shares {
+		a := &authority.Authority{Share: share}
+		err := a.SaveAuthority("stand/authorities")
+		if err != nil {
+			return errors.Wrapf(err, "cannot save authority %d", share.Authority)
+		}
+	}
 
+	mpk.Profile = &profile
+	mpk.MaxAmount = keygenMaxAmount
+	if err := gofe.ValidateMPK(mpk); err != nil {
+		return err
+	}
+	_, err = rounds.NewEmptyRepository("stand/repo", mpk)
+	if err != nil {
+		return errors.Wrap(err, "cannot create empty repository")
+	}
+
+	fmt.Printf("Keygen completed! Any %d of %d authorities can now derive keys of recipients\n", keygenThreshold, keygenAuthorities)
+	printProfile(mpk)
 	return nil
 }
+
+// Configures scheme according to chosen profile and explicit overrides,
+// warnings about weak choices are printed
+func configureScheme(scheme gofe.Scheme) (gofe.Scheme, data.Profile, error) {
+	profile, err := gofe.LookupProfile(keygenProfile)
+	if err != nil {
+		return nil, data.Profile{}, err
+	}
+	if keygenModulusBits != 0 {
+		profile.ModulusBits = keygenModulusBits
+		profile.Name = ""
+	}
+	if keygenMaxSignals != 0 {
+		profile.MaxSignals = keygenMaxSignals
+		profile.Name = ""
+	}
+
+	configured, security, warnings, err := gofe.ConfigureScheme(scheme, profile)
+	if err != nil {
+		return nil, data.Profile{}, errors.Wrap(err, "invalid security profile")
+	}
+	// lwe records no modulus length, it derives its security level from the flag
+	if keygenModulusBits != 0 && security.ModulusBits != 0 && security.ModulusBits != keygenModulusBits {
+		warnings = append(warnings, fmt.Sprintf("scheme %s uses %d-bit modulus, --modulus-bits %d is ignored", scheme.ID(), security.ModulusBits, keygenModulusBits))
+	}
+	for _, warning := range warnings {
+		fmt.Println("WARNING:", warning)
+	}
+	return configured, security, nil
+}
@@ -0,0 +1,55 @@
+package subcommands
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/recipient"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/rounds"
+)
+
+// Loads j-th party and checks mpk of every key epoch it holds key of against
+// fingerprints pinned in its file
+//
+// Keys are pinned when they're issued, so a missing fingerprint means the
+// file was tampered with. Only legacy files, which have no fingerprints at
+// all, are pinned on first use.
+func loadParty(repo *rounds.Repository, j int) (*recipient.Party, error) {
+	party, err := recipient.LoadRecipient("stand/parties", j)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot load party %d", j)
+	}
+	epochs, err := repo.KeyEpochs()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot retrieve key epochs")
+	}
+
+	legacy := len(party.Pinned) == 0
+	for _, epoch := range party.Epochs() {
+		if epoch >= len(epochs) {
+			return nil, errors.Errorf("party %d holds key of epoch %d, but repository has only %d key epochs", j, epoch, len(epochs))
+		}
+		mpk := epochs[epoch].MPK
+		ok, err := party.CheckPinned(epoch, mpk)
+		if err != nil {
+			return nil, errors.Wrapf(err, "party %d doesn't trust repository", j)
+		}
+		if !ok && !legacy {
+			return nil, errors.Errorf("party %d has no mpk fingerprint pinned for key epoch %d", j, epoch)
+		}
+		if !ok {
+			party.Pin(epoch, mpk)
+		}
+	}
+	if legacy {
+		if err := party.UpdateRecipient("stand/parties"); err != nil {
+			return nil, errors.Wrapf(err, "cannot pin mpk fingerprints of party %d", j)
+		}
+		fmt.Printf("WARNING: party %d has no mpk fingerprints pinned, trusting mpk of repository on first use\n", j)
+		for _, epoch := range party.Epochs() {
+			fmt.Printf("Party %d pinned mpk of key epoch %d: %s\n", j, epoch, party.Pinned[epoch])
+		}
+	}
+	return party, nil
+}
@@ -0,0 +1,98 @@
+package subcommands
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/memo"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/recipient"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/rounds"
+)
+
+var (
+	memoKeyParty int
+
+	RegisterMemoKey = cli.Command{
+		Action: registerMemoKey,
+		Name:   "register-memo-key",
+		Usage:  "Generates party's memo key and registers its public part in directory, so senders can attach memos to signals",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:        "party",
+				Usage:       "Number of party",
+				Required:    true,
+				Destination: &memoKeyParty,
+			},
+		},
+	}
+)
+
+// Party keeps its memo key once generated, so running it again only
+// re-registers the same public key
+func registerMemoKey(_ *cli.Context) error {
+	repo, err := rounds.OpenRepository("stand/repo")
+	if err != nil {
+		return errors.Wrap(err, "cannot open repository")
+	}
+	party, err := loadParty(repo, memoKeyParty)
+	if err != nil {
+		return err
+	}
+
+	if party.Memo == nil {
+		party.Memo, err = memo.GenerateKey(randomness(fmt.Sprintf("memo-key/%d", memoKeyParty)))
+		if err != nil {
+			return errors.Wrap(err, "cannot generate memo key")
+		}
+		if err := party.UpdateRecipient("stand/parties"); err != nil {
+			return errors.Wrapf(err, "cannot save memo key of party %d", memoKeyParty)
+		}
+	}
+	if err := repo.RegisterMemoKey(memoKeyParty-1, party.Memo.Public); err != nil {
+		return errors.Wrap(err, "cannot register memo key")
+	}
+
+	fmt.Printf("Party %d registered memo key %s\n", memoKeyParty, hex.EncodeToString(party.Memo.Public))
+	return nil
+}
+
+// Warns if directory holds memo key other than the one party has, e.g.
+// repository operator replaced it to read party's memos
+func checkMemoKey(repo *rounds.Repository, party *recipient.Party) {
+	if party.Memo == nil {
+		return
+	}
+	registered, err := repo.GetMemoKey(party.Secret.I)
+	if err != nil {
+		fmt.Printf("WARNING: %v, senders can't attach memos to its signals\n", err)
+		return
+	}
+	if !bytes.Equal(registered, party.Memo.Public) {
+		fmt.Printf("WARNING: directory holds memo key %s of party %d, which isn't the party's key\n", hex.EncodeToString(registered), party.Secret.I+1)
+	}
+}
+
+// Prints memo attached to n-th round, party is the recipient of its signal
+func printMemo(repo *rounds.Repository, party *recipient.Party, n int) error {
+	round, err := repo.GetRoundWithProof(n)
+	if err != nil {
+		return errors.Wrapf(err, "retrieve round %d", n)
+	}
+	if round.Memo == nil || party.Memo == nil {
+		return nil
+	}
+	text, err := party.Memo.Open(n, round.Memo)
+	if err == memo.ErrNotRecipient {
+		// Sender attached no memo, round carries a dummy one
+		return nil
+	} else if err != nil {
+		fmt.Printf("Round %d carries a memo party can't open: %v\n", n, err)
+		return nil
+	}
+	fmt.Printf("Memo: %q\n", text)
+	return nil
+}
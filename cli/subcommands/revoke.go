@@ -0,0 +1,51 @@
+package subcommands
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/rounds"
+)
+
+var (
+	revokeRekey bool
+
+	Revoke = cli.Command{
+		Action: revoke,
+		Name:   "revoke",
+		Usage:  "Retires slots of recipients, so they're never signalled again",
+		Flags: []cli.Flag{
+			&cli.IntSliceFlag{
+				Name:     "party",
+				Usage:    "Revoked recipient `j`, can be repeated",
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:        "rekey",
+				Usage:       "Issue new keys to remaining recipients under a new MPK",
+				Destination: &revokeRekey,
+			},
+		},
+	}
+)
+
+func revoke(c *cli.Context) error {
+	repo, err := rounds.OpenRepository("stand/repo")
+	if err != nil {
+		return errors.Wrap(err, "cannot open repository")
+	}
+	for _, j := range c.IntSlice("party") {
+		err := repo.RevokeSlot(j - 1)
+		if err != nil {
+			return errors.Wrapf(err, "cannot revoke party %d", j)
+		}
+		fmt.Printf("Party %d is revoked, it won't receive signals anymore\n", j)
+	}
+
+	if !revokeRekey {
+		return nil
+	}
+	return rekey(repo)
+}
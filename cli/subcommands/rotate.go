@@ -0,0 +1,165 @@
+package subcommands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/authority"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/recipient"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/rounds"
+)
+
+var (
+	rotateAfterRounds int
+
+	Rotate = cli.Command{
+		Action: rotate,
+		Name:   "rotate",
+		Usage:  "Begins a new key epoch under a new MPK and issues its keys to recipients",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:        "after-rounds",
+				Usage:       "Rotate only if current key epoch has at least `n` rounds, so it can be run on schedule",
+				Destination: &rotateAfterRounds,
+			},
+		},
+	}
+)
+
+func rotate(_ *cli.Context) error {
+	repo, err := rounds.OpenRepository("stand/repo")
+	if err != nil {
+		return errors.Wrap(err, "cannot open repository")
+	}
+	if rotateAfterRounds > 0 {
+		epochs, err := repo.KeyEpochs()
+		if err != nil {
+			return errors.Wrap(err, "cannot retrieve key epochs")
+		}
+		n, _, err := repo.GetLastRound()
+		if err != nil {
+			return errors.Wrap(err, "cannot retrieve last round")
+		}
+		if used := n - epochs[len(epochs)-1].FirstRound + 1; used < rotateAfterRounds {
+			fmt.Printf("Key epoch %d has %d of %d rounds, rotation isn't due yet\n", len(epochs)-1, used, rotateAfterRounds)
+			return nil
+		}
+	}
+	return rekey(repo)
+}
+
+// Begins a new key epoch under freshly generated mpk and issues its keys to
+// all assigned recipients which aren't revoked
+//
+// Recipients keep keys of previous epochs, so they can still search rounds
+// published before. If some slots are still reserved, msk of the new epoch
+// replaces the stored one, so add-party issues keys of the new epoch.
+// Otherwise stored msk is deleted, as it's of no use anymore.
+func rekey(repo *rounds.Repository) error {
+	if _, err := os.Stat("stand/authorities"); err == nil {
+		return errors.New("msk is split between authorities, run threshold keygen for a new repository instead")
+	}
+	mpk, err := repo.GetMPK()
+	if err != nil {
+		return errors.Wrap(err, "cannot retrieve MPK")
+	}
+	scheme, err := gofe.LookupScheme(mpk.Scheme)
+	if err != nil {
+		return err
+	}
+	if _, ok := scheme.(gofe.MultiClientScheme); ok {
+		return errors.Errorf("scheme %s has no msk to derive keys from, run keygen for a new repository instead", scheme.ID())
+	}
+	if err := refuseUnseedable(scheme); err != nil {
+		return err
+	}
+	membership, err := repo.GetMembership()
+	if err != nil {
+		return errors.Wrap(err, "cannot retrieve membership")
+	}
+
+	epochs, err := repo.KeyEpochs()
+	if err != nil {
+		return errors.Wrap(err, "cannot retrieve key epochs")
+	}
+
+	rekeyed, msk, err := gofe.RegenerateMasterKeys(randomness(fmt.Sprintf("key-epoch/%d", len(epochs))), mpk)
+	if err != nil {
+		return errors.Wrap(err, "keygen failed")
+	}
+	var parties []*recipient.Party
+	var keys []data.RecipientSecretKey
+	for _, i := range membership.Assigned {
+		if membership.IsRevoked(i) {
+			continue
+		}
+		party, err := loadParty(repo, i+1)
+		if err != nil {
+			return err
+		}
+		sk, err := gofe.DeriveKey(rekeyed, msk, i)
+		if err != nil {
+			return errors.Wrapf(err, "cannot derive key of party %d", i+1)
+		}
+		parties = append(parties, party)
+		keys = append(keys, sk)
+	}
+
+	// Party files are rewritten before the key epoch begins, and restored if
+	// either of them fails, so no party is left without keys of an epoch or
+	// with keys of an epoch which doesn't exist
+	epoch := len(epochs)
+	var originals []*recipient.Party
+	restore := func() {
+		for _, original := range originals {
+			if err := original.UpdateRecipient("stand/parties"); err != nil {
+				fmt.Printf("WARNING: cannot restore party %d: %v\n", original.Secret.I+1, err)
+			}
+		}
+	}
+	for p, party := range parties {
+		original, err := recipient.LoadRecipient("stand/parties", party.Secret.I+1)
+		if err != nil {
+			restore()
+			return errors.Wrapf(err, "cannot load party %d", party.Secret.I+1)
+		}
+		originals = append(originals, original)
+		if err := party.Rekey(epoch, keys[p]); err != nil {
+			restore()
+			return errors.Wrapf(err, "cannot rekey party %d", party.Secret.I+1)
+		}
+		party.Pin(epoch, rekeyed)
+		if err := party.UpdateRecipient("stand/parties"); err != nil {
+			restore()
+			return errors.Wrapf(err, "cannot save party %d", party.Secret.I+1)
+		}
+	}
+	begun, err := repo.BeginKeyEpoch(rekeyed)
+	if err == nil && begun != epoch {
+		err = errors.Errorf("key epoch %d began concurrently", epoch)
+	}
+	if err != nil {
+		restore()
+		return errors.Wrap(err, "cannot begin key epoch")
+	}
+	if _, err := membership.NextFree(rekeyed.L); err == nil {
+		err = authority.ReplaceMSK("stand/authority", msk)
+	} else {
+		err = authority.DeleteMSK("stand/authority")
+	}
+	if err != nil {
+		return errors.Wrap(err, "cannot update msk")
+	}
+
+	n, _, err := repo.GetLastRound()
+	if err != nil {
+		return errors.Wrap(err, "cannot retrieve last round")
+	}
+	fmt.Printf("Key epoch %d begins at round %d, %d parties received new keys\n", epoch, n+1, len(parties))
+	return nil
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
 
-	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
 	"github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
 	"github.com/ZenGo-X/fe-hackaton-demo/internal/recipient"
 	"github.com/ZenGo-X/fe-hackaton-demo/internal/rounds"
@@ -45,52 +44,35 @@ var (
 )
 
 func search(_ *cli.Context) error {
-	party, err := recipient.LoadRecipient("stand/parties", searchArgs.party)
-	if err != nil {
-		return errors.Wrap(err, "load party secret")
-	}
-
 	repo, err := rounds.OpenRepository("stand/repo")
 	if err != nil {
 		return errors.Wrap(err, "open repository")
 	}
 
-	mpk, err := repo.GetMPK()
+	party, err := loadParty(repo, searchArgs.party)
 	if err != nil {
-		return errors.Wrap(err, "couldn't retrieve MPK")
+		return err
 	}
+	checkMemoKey(repo, party)
+
+	counter := &signalCounter{party: party, repo: repo, tables: map[int]*gofe.DlogTable{}, bases: map[int]*big.Int{}}
 
 	t1 := searchArgs.from
-	var v1 *big.Int
-	if t1 > 0 {
-		ciphertext1, err := repo.GetRound(t1)
-		if err != nil {
-			return errors.Wrapf(err, "retrieve round %d", t1)
-		}
-		v1, err = gofe.Decrypt(mpk, party.Secret, ciphertext1)
-		if err != nil {
-			return errors.Wrapf(err, "decrypt ciphertext from round %d", t1)
-		}
-	} else {
-		v1 = big.NewInt(0)
+	v1, err := counter.count(t1)
+	if err != nil {
+		return err
 	}
 
 	t2 := searchArgs.to
-	var ciphertext2 *data.Ciphertext
-	if searchArgs.to != 0 {
-		ciphertext2, err = repo.GetRound(t2)
-		if err != nil {
-			return errors.Wrapf(err, "retrieve round %d", t2)
-		}
-	} else {
-		t2, ciphertext2, err = repo.GetLastRound()
+	if t2 == 0 {
+		t2, _, err = repo.GetLastRound()
 		if err != nil {
 			return errors.Wrap(err, "retrieve last round")
 		}
 	}
-	v2, err := gofe.Decrypt(mpk, party.Secret, ciphertext2)
+	v2, err := counter.count(t2)
 	if err != nil {
-		return errors.Wrapf(err, "decrypt ciphertext from round %d", t2)
+		return err
 	}
 
 	if v1.Cmp(v2) == 0 {
@@ -98,22 +80,33 @@ func search(_ *cli.Context) error {
 		return nil
 	}
 
+	if _, e1, err := repo.GetMPKOf(t1 + 1); err == nil {
+		if _, e2, err := repo.GetMPKOf(t2); err == nil && e1 != e2 {
+			fmt.Printf("Rounds [%d;%d] span key epochs %d to %d, each round is decrypted by key of its epoch\n", t1, t2, e1, e2)
+		}
+	}
 	fmt.Println("Party received signal(s)!")
+	if carriesAmounts(repo, t2) {
+		fmt.Printf("Total amount received within rounds [%d;%d]: %s\n", t1, t2, new(big.Int).Sub(v2, v1))
+	}
 	for {
 		fmt.Printf("Searching received signal within rounds [%d;%d]\n", t1, t2)
-		ti, err := findFirstSignal(party, repo, mpk, t1, v1, t2)
+		ti, err := findFirstSignal(counter, t1, v1, t2)
 		if err != nil {
 			return errors.Wrap(err, "search failed")
 		}
-		fmt.Printf("Received signal at round %d!\n", ti+1)
-
-		ciphertext, err := repo.GetRound(ti + 1)
+		vi, err := counter.count(ti + 1)
 		if err != nil {
-			return errors.Wrapf(err, "cannot retrieve round %d", ti+1)
+			return err
 		}
-		vi, err := gofe.Decrypt(mpk, party.Secret, ciphertext)
-		if err != nil {
-			return errors.Wrapf(err, "decrypt ciphertext from round %d", ti+1)
+		if carriesAmounts(repo, ti+1) {
+			// Round ti+1 is the only one within [t1+1; ti+1] party received
+			fmt.Printf("Received amount %s at round %d!\n", new(big.Int).Sub(vi, v1), ti+1)
+		} else {
+			fmt.Printf("Received signal at round %d!\n", ti+1)
+		}
+		if err := printMemo(repo, party, ti+1); err != nil {
+			return err
 		}
 
 		if vi.Cmp(v2) == 0 {
@@ -126,7 +119,13 @@ func search(_ *cli.Context) error {
 	}
 }
 
-func findFirstSignal(party *recipient.Party, repo *rounds.Repository, mpk data.MPK, t1 int, v1 *big.Int, t2 int) (int, error) {
+// Checks whether signals of n-th round carry amounts rather than count as 1
+func carriesAmounts(repo *rounds.Repository, n int) bool {
+	mpk, _, err := repo.GetMPKOf(n)
+	return err == nil && mpk.MaxAmount > 0
+}
+
+func findFirstSignal(counter *signalCounter, t1 int, v1 *big.Int, t2 int) (int, error) {
 	if t1 == t2 {
 		return t1, nil
 	}
@@ -135,20 +134,102 @@ func findFirstSignal(party *recipient.Party, repo *rounds.Repository, mpk data.M
 	if ((t1 + t2) & 1) == 1 {
 		m += 1
 	}
-	ciphertext, err := repo.GetRound(m)
+	vm, err := counter.count(m)
 	if err != nil {
-		return 0, errors.Wrapf(err, "cannot retrieve round %d", m)
-	}
-	vm, err := gofe.Decrypt(mpk, party.Secret, ciphertext)
-	if err != nil {
-		return 0, errors.Wrapf(err, "decrypt ciphertext from round %d", m)
+		return 0, err
 	}
 
 	if v1.Cmp(vm) == 0 {
 		fmt.Printf("Accessing round %d... v_%d == v_%d\n", m, t1, m)
-		return findFirstSignal(party, repo, mpk, m, vm, t2)
+		return findFirstSignal(counter, m, vm, t2)
 	} else {
 		fmt.Printf("Accessing round %d... v_%d != v_%d\n", m, t1, m)
-		return findFirstSignal(party, repo, mpk, t1, v1, m-1)
+		return findFirstSignal(counter, t1, v1, m-1)
+	}
+}
+
+// Counts signals received by party, so that counts are comparable across
+// accumulator chains and key epochs
+//
+// If signals carry amounts, count is the total amount received.
+type signalCounter struct {
+	party *recipient.Party
+	repo  *rounds.Repository
+	// Discrete logarithm tables of every key epoch
+	tables map[int]*gofe.DlogTable
+	// Amount of signals received within rounds [1; n], it's cached for
+	// the last rounds of accumulator chains
+	bases map[int]*big.Int
+}
+
+// Returns amount of signals received within rounds [1; t]
+//
+// Round t decrypts to amount of signals received since its accumulator chain
+// started, they're added up with signals received in all previous chains.
+// Rounds of key epochs party held no key in don't count.
+func (c *signalCounter) count(t int) (*big.Int, error) {
+	if t == 0 {
+		return big.NewInt(0), nil
+	}
+	v, err := c.decrypt(t)
+	if err != nil {
+		return nil, err
+	}
+	start, err := c.repo.ChainStart(t)
+	if err != nil {
+		return nil, errors.Wrapf(err, "determine start of chain of round %d", t)
+	}
+	base, err := c.base(start - 1)
+	if err != nil {
+		return nil, err
+	}
+	return v.Add(v, base), nil
+}
+
+// Decrypts round t by party's key of its key epoch
+func (c *signalCounter) decrypt(t int) (*big.Int, error) {
+	mpk, epoch, err := c.repo.GetMPKOf(t)
+	if err != nil {
+		return nil, errors.Wrapf(err, "retrieve MPK of round %d", t)
+	}
+	sk, ok := c.party.KeyOf(epoch)
+	if !ok {
+		return big.NewInt(0), nil
+	}
+	table, ok := c.tables[epoch]
+	if !ok {
+		table, err = gofe.LoadDlogTable("stand/cache", mpk)
+		if err != nil {
+			return nil, errors.Wrap(err, "load discrete logarithm table")
+		}
+		c.tables[epoch] = table
+	}
+
+	// Repository validates rounds it returns
+	ciphertext, err := c.repo.GetRound(t)
+	if err != nil {
+		return nil, errors.Wrapf(err, "retrieve round %d", t)
+	}
+	v, err := gofe.DecryptValidated(mpk, sk, ciphertext, table)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decrypt ciphertext from round %d", t)
+	}
+	return v, nil
+}
+
+// Returns amount of signals received within rounds [1; n], where n is the
+// last round of some accumulator chain
+func (c *signalCounter) base(n int) (*big.Int, error) {
+	if n == 0 {
+		return big.NewInt(0), nil
+	}
+	if base, ok := c.bases[n]; ok {
+		return base, nil
+	}
+	base, err := c.count(n)
+	if err != nil {
+		return nil, err
 	}
+	c.bases[n] = base
+	return base, nil
 }
@@ -0,0 +1,46 @@
+package subcommands
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
+)
+
+var (
+	seed string
+
+	SeedFlag = &cli.StringFlag{
+		Name:        "seed",
+		Usage:       "INSECURE: derive all randomness from `SEED`, so keys and rounds are reproducible. Use it only for tests and bug reports",
+		Destination: &seed,
+	}
+)
+
+// Returns source of randomness for operation identified by label
+//
+// Without --seed it's crypto/rand.Reader. Otherwise it's deterministic stream
+// derived from seed and label, so labels must differ between operations
+// which must not share randomness, e.g. different rounds.
+func randomness(label string) io.Reader {
+	if seed == "" {
+		return rand.Reader
+	}
+	fmt.Printf("WARNING: randomness of %s is derived from --seed, anyone knowing the seed can reproduce it\n", label)
+	return gofe.NewSeededReader([]byte(seed), label)
+}
+
+// Fails if --seed is set, but scheme draws randomness inside gofe library
+//
+// It's checked before anything is generated, so seeded run never leaves
+// partially generated state behind.
+func refuseUnseedable(scheme gofe.Scheme) error {
+	if _, ok := scheme.(gofe.SeedableScheme); seed != "" && !ok {
+		return errors.Errorf("scheme %s can't be seeded, it draws randomness inside gofe library", scheme.ID())
+	}
+	return nil
+}
@@ -1,19 +1,27 @@
 package subcommands
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"math/big"
 
 	gofe "github.com/fentec-project/gofe/data"
 	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
 
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/client"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/dkg"
 	gofe2 "github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/memo"
 	"github.com/ZenGo-X/fe-hackaton-demo/internal/rounds"
 )
 
 var (
 	recipientParty int
+	signalMemo     string
+	signalAmount   int64
 
 	SendSignal = cli.Command{
 		Action: sendSignal,
@@ -26,6 +34,17 @@ var (
 				Destination: &recipientParty,
 				Required:    true,
 			},
+			&cli.StringFlag{
+				Name:        "memo",
+				Usage:       fmt.Sprintf("Attach `memo` (up to %d bytes) which only the recipient can read, it must have registered memo key", memo.MaxSize),
+				Destination: &signalMemo,
+			},
+			&cli.Int64Flag{
+				Name:        "amount",
+				Usage:       "Amount `A` carried by the signal, keys must be generated with --max-amount",
+				Destination: &signalAmount,
+				Value:       1,
+			},
 		},
 	}
 )
@@ -40,36 +59,166 @@ func sendSignal(_ *cli.Context) error {
 	if err != nil {
 		return errors.Wrap(err, "cannot retrieve MPK")
 	}
+	scheme, err := gofe2.LookupScheme(mpk.Scheme)
+	if err != nil {
+		return err
+	}
+	if err := refuseUnseedable(scheme); err != nil {
+		return err
+	}
+
+	if recipientParty <= 0 || recipientParty > mpk.L {
+		return errors.Errorf("expected recipient in range [1; %d]", mpk.L)
+	}
+	if signalAmount != 1 && mpk.MaxAmount == 0 {
+		return errors.New("mpk doesn't allow amounts, run keygen with --max-amount")
+	}
+	membership, err := repo.GetMembership()
+	if err != nil {
+		return errors.Wrap(err, "cannot retrieve membership")
+	}
+	if !membership.IsAssigned(recipientParty - 1) {
+		return errors.Errorf("slot %d is reserved, no recipient holds its key yet", recipientParty)
+	}
+	if membership.IsRevoked(recipientParty - 1) {
+		return errors.Errorf("party %d is revoked", recipientParty)
+	}
 
-	if recipientParty <= 0 || recipientParty > mpk.DDH.Params.L {
-		return errors.Errorf("expected recipient in range [1; %d]", mpk.DDH.Params.L)
+	var memoKey []byte
+	if signalMemo != "" {
+		memoKey, err = repo.GetMemoKey(recipientParty - 1)
+		if err != nil {
+			return errors.Wrap(err, "cannot attach memo")
+		}
 	}
 
 	n, previousCiphertext, err := repo.GetLastRound()
-	if err != nil {
+	var malformed *rounds.MalformedRoundError
+	if errors.As(err, &malformed) {
+		return errors.Wrapf(err, "refusing to accumulate signal into malformed round %d", malformed.Round)
+	} else if err != nil {
 		return errors.Wrap(err, "cannot retrieve last round")
 	}
 
-	plaintext := gofe.NewConstantVector(mpk.DDH.Params.L, big.NewInt(0))
-	plaintext[recipientParty-1] = big.NewInt(1)
-
-	ciphertext, err := gofe2.Encrypt(mpk, plaintext)
+	random := randomness(fmt.Sprintf("send-signal/%d", n+1))
+	if _, ok := scheme.(gofe2.MultiClientScheme); ok {
+		return requestSignal(random, mpk, n+1, memoKey)
+	}
+	delta, proof, err := encryptSignal(random, mpk, recipientParty-1, signalAmount)
 	if err != nil {
 		return errors.Wrap(err, "can't encrypt a signal")
 	}
+	sealed, err := sealMemo(random, memoKey, n+1)
+	if err != nil {
+		return errors.Wrap(err, "cannot seal memo")
+	}
+
+	epochStarts, err := publishSignal(repo, mpk, n+1, previousCiphertext, &data.Round{Delta: &delta, Proof: proof, Memo: sealed})
+	if err != nil {
+		return err
+	}
+
+	if mpk.MaxAmount > 0 {
+		fmt.Printf("You successfully sent encrypted signal carrying amount %d to party %d in round %d!\n", signalAmount, recipientParty, n+1)
+	} else {
+		fmt.Printf("You successfully sent encrypted signal to party %d in round %d!\n", recipientParty, n+1)
+	}
+	if epochStarts {
+		printChainStart(repo, n+1)
+	}
+	return nil
+}
 
-	if n > 0 {
-		err = ciphertext.Mul(previousCiphertext)
+// Publishes n-th round carrying given delta, which is accumulated into
+// previous round unless n-th round starts a new accumulator chain, reports
+// whether it starts one after the first round
+func publishSignal(repo *rounds.Repository, mpk data.MPK, n int, previous *data.Ciphertext, round *data.Round) (bool, error) {
+	start, err := repo.ChainStart(n)
+	if err != nil {
+		return false, errors.Wrap(err, "cannot determine start of accumulator chain")
+	}
+	epochStarts := n == start
+
+	ciphertext := round.Delta.Copy()
+	if !epochStarts {
+		ciphertext = previous.Copy()
+		err = gofe2.Accumulate(mpk, ciphertext, round.Delta)
 		if err != nil {
-			return errors.Wrap(err, "calculating ciphertext * previousCiphertext")
+			return false, errors.Wrap(err, "accumulating ciphertext with previousCiphertext")
 		}
 	}
+	round.Ciphertext = *ciphertext
+
+	err = repo.PublishRound(n, round)
+	if err != nil {
+		return false, errors.Wrap(err, "publish encrypted signal error")
+	}
+	return epochStarts && n > 1, nil
+}
 
-	err = repo.PublishRound(n+1, &ciphertext)
+// Requests clients of multi-client scheme to encrypt one-hot signal as n-th
+// round, sender holds no client keys
+func requestSignal(random io.Reader, mpk data.MPK, n int, memoKey []byte) error {
+	transport, err := dkg.NewFileTransport("stand/signals")
 	if err != nil {
-		return errors.Wrap(err, "publish encrypted signal error")
+		return errors.Wrap(err, "cannot open signals transport")
+	}
+	sealed, err := sealMemo(random, memoKey, n)
+	if err != nil {
+		return errors.Wrap(err, "cannot seal memo")
+	}
+	plaintext := gofe.NewConstantVector(mpk.L, big.NewInt(0))
+	plaintext[recipientParty-1] = big.NewInt(1)
+	err = client.RequestSignal(random, transport, mpk, n, plaintext, sealed)
+	if err != nil {
+		return errors.Wrapf(err, "cannot request signal of round %d", n)
 	}
 
-	fmt.Printf("You successfully sent encrypted signal to party %d in round %d!\n", recipientParty, n+1)
+	fmt.Printf("You requested signal to party %d in round %d! Every client should now encrypt its slot\n", recipientParty, n)
 	return nil
 }
+
+// Seals memo attached to n-th round to recipient's memo key
+//
+// Round without memo carries a dummy one sealed to a fresh key, otherwise
+// presence of memo would tell that recipient has registered memo key.
+func sealMemo(random io.Reader, recipient []byte, n int) ([]byte, error) {
+	if signalMemo == "" {
+		dummy, err := memo.GenerateKey(random)
+		if err != nil {
+			return nil, err
+		}
+		recipient = dummy.Public
+	}
+	return memo.Seal(random, recipient, n, []byte(signalMemo))
+}
+
+// Explains why n-th round starts a new accumulator chain
+func printChainStart(repo *rounds.Repository, n int) {
+	epochs, err := repo.KeyEpochs()
+	if err != nil {
+		return
+	}
+	epoch := epochs[len(epochs)-1]
+	if epoch.FirstRound == n {
+		fmt.Printf("Round %d is the first round of key epoch %d\n", n, len(epochs)-1)
+		return
+	}
+	length, err := gofe2.EpochLength(epoch.MPK)
+	if err != nil {
+		return
+	}
+	fmt.Printf("Accumulator reached its capacity of %d signals, round %d starts epoch %d\n",
+		length, n, rounds.EpochOf(n-epoch.FirstRound+1, length))
+}
+
+// Encrypts signal to i-th party (0-indexed) under mpk along with proof of
+// its well-formedness if scheme supports it
+//
+// Amount is carried only if mpk allows amounts, otherwise it must be 1.
+func encryptSignal(random io.Reader, mpk data.MPK, i int, amount int64) (data.Ciphertext, json.RawMessage, error) {
+	if mpk.MaxAmount > 0 {
+		return gofe2.EncryptAmountFrom(random, mpk, i, amount)
+	}
+	return gofe2.EncryptSignalFrom(random, mpk, i)
+}
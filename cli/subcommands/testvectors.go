@@ -0,0 +1,120 @@
+package subcommands
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/testvectors"
+)
+
+var (
+	testvectorsArgs struct {
+		suite, outputs string
+	}
+
+	TestVectors = cli.Command{
+		Name:  "testvectors",
+		Usage: "Generates and verifies known-answer test vectors for other implementations",
+		Subcommands: []*cli.Command{
+			{
+				Action: testvectorsGenerate,
+				Name:   "generate",
+				Usage:  "Generates suite of keys, rounds and expected decryptions (derived from --seed if it's set)",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "out",
+						Usage:       "Write suite to `FILE`",
+						Destination: &testvectorsArgs.suite,
+						Value:       "testvectors.json",
+					},
+					&cli.StringSliceFlag{
+						Name:  "scheme",
+						Usage: "Scheme to generate vectors for, can be repeated",
+						Value: cli.NewStringSlice("ddh", "ecddh"),
+					},
+					&cli.IntSliceFlag{
+						Name:  "parties",
+						Usage: "Amount of parties to generate vectors for, can be repeated",
+						Value: cli.NewIntSlice(2, 3, 5),
+					},
+				},
+			},
+			{
+				Action: testvectorsVerify,
+				Name:   "verify",
+				Usage:  "Checks suite consistency and compares implementation's outputs with it",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "suite",
+						Usage:       "Read suite from `FILE`",
+						Destination: &testvectorsArgs.suite,
+						Value:       "testvectors.json",
+					},
+					&cli.StringFlag{
+						Name:        "outputs",
+						Usage:       "Outputs of implementation under test `FILE`",
+						Destination: &testvectorsArgs.outputs,
+						DefaultText: "only suite is checked",
+					},
+				},
+			},
+		},
+	}
+)
+
+func testvectorsGenerate(c *cli.Context) error {
+	suiteSeed := seed
+	if suiteSeed == "" {
+		bytes := make([]byte, 16)
+		if _, err := rand.Read(bytes); err != nil {
+			return errors.Wrap(err, "cannot generate seed")
+		}
+		suiteSeed = hex.EncodeToString(bytes)
+	}
+
+	suite, err := testvectors.Generate(suiteSeed, c.StringSlice("scheme"), c.IntSlice("parties"))
+	if err != nil {
+		return errors.Wrap(err, "cannot generate test vectors")
+	}
+	err = suite.Save(testvectorsArgs.suite)
+	if err != nil {
+		return errors.Wrap(err, "cannot save test vectors")
+	}
+
+	fmt.Printf("Generated %d cases with seed %q and saved them to %s\n", len(suite.Cases), suiteSeed, testvectorsArgs.suite)
+	fmt.Println("WARNING: keys of test vectors are public, never use them for real signals")
+	return nil
+}
+
+func testvectorsVerify(_ *cli.Context) error {
+	suite, err := testvectors.LoadSuite(testvectorsArgs.suite)
+	if err != nil {
+		return err
+	}
+	err = suite.Check()
+	if err != nil {
+		return errors.Wrap(err, "suite is inconsistent")
+	}
+	fmt.Printf("Suite of %d cases is consistent\n", len(suite.Cases))
+	if testvectorsArgs.outputs == "" {
+		return nil
+	}
+
+	outputs, err := testvectors.LoadOutputs(testvectorsArgs.outputs)
+	if err != nil {
+		return err
+	}
+	mismatches := testvectors.Compare(suite, outputs)
+	for _, mismatch := range mismatches {
+		fmt.Println(mismatch)
+	}
+	if len(mismatches) > 0 {
+		return errors.Errorf("outputs don't conform to the suite: %d mismatches", len(mismatches))
+	}
+	fmt.Println("Outputs conform to the suite")
+	return nil
+}
@@ -0,0 +1,111 @@
+package subcommands
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/authority"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/recipient"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/rounds"
+)
+
+var (
+	thresholdArgs struct {
+		authority, party int
+	}
+
+	DeriveShare = cli.Command{
+		Action: deriveShare,
+		Name:   "derive-share",
+		Usage:  "Derives authority's share of recipient's key",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:        "authority",
+				Usage:       "Deriving authority `a` (1 <= a <= n)",
+				Destination: &thresholdArgs.authority,
+				Required:    true,
+			},
+			&cli.IntFlag{
+				Name:        "party",
+				Usage:       "Recipient `j` (1 <= j <= N)",
+				Destination: &thresholdArgs.party,
+				Required:    true,
+			},
+		},
+	}
+
+	Combine = cli.Command{
+		Action: combine,
+		Name:   "combine",
+		Usage:  "Combines shares of recipient's key derived by authorities",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:        "party",
+				Usage:       "Recipient `j` (1 <= j <= N)",
+				Destination: &thresholdArgs.party,
+				Required:    true,
+			},
+		},
+	}
+)
+
+func deriveShare(_ *cli.Context) error {
+	repo, err := rounds.OpenRepository("stand/repo")
+	if err != nil {
+		return errors.Wrap(err, "cannot open repository")
+	}
+	mpk, err := repo.GetMPK()
+	if err != nil {
+		return errors.Wrap(err, "cannot retrieve MPK")
+	}
+
+	a, err := authority.LoadAuthority("stand/authorities", thresholdArgs.authority)
+	if err != nil {
+		return errors.Wrapf(err, "cannot load authority %d", thresholdArgs.authority)
+	}
+	share, err := gofe.DeriveThresholdShare(mpk, a.Share, thresholdArgs.party-1)
+	if err != nil {
+		return errors.Wrap(err, "cannot derive key share")
+	}
+
+	err = authority.SaveKeyShare("stand/shares", share)
+	if err != nil {
+		return errors.Wrap(err, "cannot save key share")
+	}
+
+	fmt.Printf("Authority %d derived share of key of party %d!\n", thresholdArgs.authority, thresholdArgs.party)
+	return nil
+}
+
+func combine(_ *cli.Context) error {
+	repo, err := rounds.OpenRepository("stand/repo")
+	if err != nil {
+		return errors.Wrap(err, "cannot open repository")
+	}
+	mpk, err := repo.GetMPK()
+	if err != nil {
+		return errors.Wrap(err, "cannot retrieve MPK")
+	}
+
+	shares, err := authority.LoadKeyShares("stand/shares", thresholdArgs.party)
+	if err != nil {
+		return errors.Wrap(err, "cannot load key shares")
+	}
+	sk, err := gofe.CombineThresholdShares(mpk, thresholdArgs.party-1, shares)
+	if err != nil {
+		return errors.Wrap(err, "cannot combine key shares")
+	}
+
+	party := &recipient.Party{Secret: sk}
+	party.Pin(party.Epoch, mpk)
+	err = party.SaveRecipient("stand/parties")
+	if err != nil {
+		return errors.Wrapf(err, "cannot save party %d", thresholdArgs.party)
+	}
+
+	fmt.Printf("Key of party %d is combined out of %d shares!\n", thresholdArgs.party, len(shares))
+	return nil
+}
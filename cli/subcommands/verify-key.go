@@ -0,0 +1,56 @@
+package subcommands
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/rounds"
+)
+
+var (
+	verifyKeyParty int
+
+	VerifyKey = cli.Command{
+		Action: verifyKey,
+		Name:   "verify-key",
+		Usage:  "Checks that keys of party are derived for its own slot of MPK of every key epoch",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:        "party",
+				Usage:       "Number of party",
+				Required:    true,
+				Destination: &verifyKeyParty,
+			},
+		},
+	}
+)
+
+// Checks every key party holds against mpk of its key epoch, so party can
+// make sure authority didn't issue key which decrypts signals of other parties
+func verifyKey(_ *cli.Context) error {
+	repo, err := rounds.OpenRepository("stand/repo")
+	if err != nil {
+		return errors.Wrap(err, "cannot open repository")
+	}
+	party, err := loadParty(repo, verifyKeyParty)
+	if err != nil {
+		return err
+	}
+	epochs, err := repo.KeyEpochs()
+	if err != nil {
+		return errors.Wrap(err, "cannot retrieve key epochs")
+	}
+
+	for _, epoch := range party.Epochs() {
+		sk, _ := party.KeyOf(epoch)
+		err := gofe.VerifyKey(epochs[epoch].MPK, sk, verifyKeyParty-1)
+		if err != nil {
+			return errors.Wrapf(err, "key of party %d for key epoch %d is invalid", verifyKeyParty, epoch)
+		}
+		fmt.Printf("Key of party %d for key epoch %d is derived for its own slot\n", verifyKeyParty, epoch)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package subcommands
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/rounds"
+)
+
+var (
+	Verify = cli.Command{
+		Action: verify,
+		Name:   "verify",
+		Usage:  "Recomputes accumulator chain and verifies one-hot (or amount) proofs of all published rounds",
+	}
+)
+
+func verify(_ *cli.Context) error {
+	repo, err := rounds.OpenRepository("stand/repo")
+	if err != nil {
+		return errors.Wrap(err, "cannot open repository")
+	}
+
+	n, err := repo.VerifyChain()
+	if err != nil {
+		fmt.Printf("First inconsistent round: %d\n", n+1)
+		return errors.Wrap(err, "accumulator chain is broken")
+	}
+
+	fmt.Printf("All %d rounds are valid\n", n)
+	return nil
+}
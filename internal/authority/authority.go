@@ -0,0 +1,163 @@
+// Package authority stores data of k-of-n threshold authorities: their
+// shares of msk and shares of recipients' keys derived by them, as well as
+// msk of a single authority adding recipients after keygen
+package authority
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+// Authority holding share of msk which can be used to derive
+// share of recipient's key
+type Authority struct {
+	Share data.MSKShare
+}
+
+// Saves authority's msk share at `{dir}/authority_{a}.json`
+//
+// It's an error if this file already exist
+func (a *Authority) SaveAuthority(dir string) error {
+	filepath := path.Join(dir, fmt.Sprintf("authority_%d.json", a.Share.Authority))
+	return errors.Wrap(createJSON(dir, filepath, a), "save msk share")
+}
+
+// Loads authority's msk share from `{dir}/authority_{a}.json`
+func LoadAuthority(dir string, a int) (*Authority, error) {
+	var authority Authority
+	err := readJSON(path.Join(dir, fmt.Sprintf("authority_%d.json", a)), &authority)
+	if err != nil {
+		return nil, err
+	}
+	return &authority, nil
+}
+
+// Saves master secret key at `{dir}/msk.json`, it's kept to derive keys of
+// recipients added after keygen
+//
+// It's an error if this file already exist
+func SaveMSK(dir string, msk data.MSK) error {
+	return errors.Wrap(createJSON(dir, path.Join(dir, "msk.json"), msk), "save msk")
+}
+
+// Replaces master secret key saved at `{dir}/msk.json` by msk of a new key
+// epoch
+//
+// File is replaced atomically, so msk isn't lost if writing fails.
+func ReplaceMSK(dir string, msk data.MSK) error {
+	err := os.MkdirAll(dir, 0770)
+	if err != nil {
+		return errors.Wrap(err, "create dir")
+	}
+	file, err := ioutil.TempFile(dir, "msk_*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "create temp file")
+	}
+	err = json.NewEncoder(file).Encode(msk)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path.Join(dir, "msk.json"))
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return errors.Wrap(err, "replace msk")
+	}
+	return nil
+}
+
+// Deletes master secret key saved at `{dir}/msk.json`, it's not an error if
+// there's no such file
+func DeleteMSK(dir string) error {
+	err := os.Remove(path.Join(dir, "msk.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "delete msk")
+	}
+	return nil
+}
+
+// Loads master secret key from `{dir}/msk.json`
+func LoadMSK(dir string) (data.MSK, error) {
+	var msk data.MSK
+	err := readJSON(path.Join(dir, "msk.json"), &msk)
+	return msk, err
+}
+
+// Saves share of recipient's key at `{dir}/party_{j}/authority_{a}.json`
+//
+// It's an error if this file already exist
+func SaveKeyShare(dir string, share data.DerivedKeyShare) error {
+	partyDir := path.Join(dir, fmt.Sprintf("party_%d", share.I+1))
+	filepath := path.Join(partyDir, fmt.Sprintf("authority_%d.json", share.Authority))
+	return errors.Wrap(createJSON(partyDir, filepath, share), "save key share")
+}
+
+// Loads all shares of j-th recipient's key saved at `{dir}/party_{j}`
+func LoadKeyShares(dir string, j int) ([]data.DerivedKeyShare, error) {
+	partyDir := path.Join(dir, fmt.Sprintf("party_%d", j))
+	files, err := ioutil.ReadDir(partyDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "list key shares")
+	}
+
+	var shares []data.DerivedKeyShare
+	for _, file := range files {
+		if file.IsDir() || !strings.HasPrefix(file.Name(), "authority_") || path.Ext(file.Name()) != ".json" {
+			continue
+		}
+		var share data.DerivedKeyShare
+		if err := readJSON(path.Join(partyDir, file.Name()), &share); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, nil
+}
+
+func createJSON(dir, filepath string, v interface{}) error {
+	err := os.MkdirAll(dir, 0770)
+	if err != nil {
+		return errors.Wrap(err, "create dir")
+	}
+	file, err := os.OpenFile(filepath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return errors.Wrap(err, "create file")
+	}
+
+	err = json.NewEncoder(file).Encode(v)
+	if err != nil {
+		_ = file.Close()
+		return errors.Wrap(err, "write/encode file")
+	}
+
+	if err = file.Close(); err != nil {
+		return errors.Wrap(err, "close file")
+	}
+
+	return nil
+}
+
+func readJSON(filepath string, v interface{}) error {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return errors.Wrap(err, "open file")
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	err = json.NewDecoder(file).Decode(v)
+	if err != nil {
+		return errors.Wrapf(err, "decode/read %s", path.Base(filepath))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package authority
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+func TestMarshalling(t *testing.T) {
+	// Create fake authority and key shares for test
+	authority := Authority{Share: data.MSKShare{
+		Scheme:    "ddh",
+		Authority: 2,
+		Threshold: 2,
+		Vectors:   []gofe.Vector{{big.NewInt(1), big.NewInt(2)}},
+	}}
+	shares := []data.DerivedKeyShare{
+		{Authority: 1, Threshold: 2, I: 1, Key: gofe.Vector{big.NewInt(3)}},
+		{Authority: 2, Threshold: 2, I: 1, Key: gofe.Vector{big.NewInt(4)}},
+	}
+
+	msk := data.MSK{Scheme: "ddh", Key: []byte(`{"x":1}`)}
+
+	// Create temp dir
+	dir, err := ioutil.TempDir("", "authorities")
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	// Tests
+	t.Run("Save", func(t *testing.T) {
+		err := authority.SaveAuthority(dir)
+		assert.NoError(t, err, "save authority")
+		err = authority.SaveAuthority(dir)
+		assert.Error(t, err, "overwrote authority")
+
+		for _, share := range shares {
+			err := SaveKeyShare(dir, share)
+			assert.NoError(t, err, "save key share")
+		}
+
+		err = SaveMSK(dir, msk)
+		assert.NoError(t, err, "save msk")
+		err = SaveMSK(dir, msk)
+		assert.Error(t, err, "overwrote msk")
+	})
+
+	t.Run("Load", func(t *testing.T) {
+		authority2, err := LoadAuthority(dir, 2)
+		assert.NoError(t, err, "load authority")
+		assert.Equal(t, &authority, authority2)
+
+		shares2, err := LoadKeyShares(dir, 2)
+		assert.NoError(t, err, "load key shares")
+		assert.Equal(t, shares, shares2)
+
+		_, err = LoadKeyShares(dir, 1)
+		assert.Error(t, err, "loaded shares of party without shares")
+
+		msk2, err := LoadMSK(dir)
+		assert.NoError(t, err, "load msk")
+		assert.Equal(t, msk, msk2)
+
+		replaced := data.MSK{Scheme: "ddh", Key: []byte(`{"x":2}`)}
+		assert.NoError(t, ReplaceMSK(dir, replaced), "replace msk")
+		msk2, err = LoadMSK(dir)
+		assert.NoError(t, err, "load msk")
+		assert.Equal(t, replaced, msk2)
+
+		assert.NoError(t, DeleteMSK(dir), "delete msk")
+		_, err = LoadMSK(dir)
+		assert.Error(t, err, "loaded deleted msk")
+		assert.NoError(t, DeleteMSK(dir), "delete missing msk")
+	})
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/pkg/errors"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/memo"
+)
+
+// Client of multi-client scheme holding ClientKey which is used
+// to encrypt its slot of a signal
+type Client struct {
+	Secret data.ClientKey
+	// Key which senders seal value of client's slot to
+	Memo *memo.Key `json:",omitempty"`
+}
+
+// Saves client secret key at `{dir}/client_{i}.json`
+//
+// It's an error if this file already exist
+func (c *Client) SaveClient(dir string) error {
+	err := os.MkdirAll(dir, 0770)
+	if err != nil {
+		return errors.Wrap(err, "create dir")
+	}
+	filepath := path.Join(dir, fmt.Sprintf("client_%d.json", c.Secret.I+1))
+	file, err := os.OpenFile(filepath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return errors.Wrap(err, "create file")
+	}
+
+	err = json.NewEncoder(file).Encode(c)
+	if err != nil {
+		_ = file.Close()
+		return errors.Wrap(err, "write/encode client secret key")
+	}
+
+	if err = file.Close(); err != nil {
+		return errors.Wrap(err, "close file")
+	}
+
+	return nil
+}
+
+// Loads client secret key from `{dir}/client_{i}.json`
+func LoadClient(dir string, i int) (*Client, error) {
+	filepath := path.Join(dir, fmt.Sprintf("client_%d.json", i))
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, errors.Wrap(err, "open file")
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var client Client
+	err = json.NewDecoder(file).Decode(&client)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode/read file")
+	}
+
+	return &client, nil
+}
+
+// Loads secret keys of clients 1..n
+func LoadClients(dir string, n int) ([]data.ClientKey, error) {
+	keys := make([]data.ClientKey, n)
+	for i := range keys {
+		client, err := LoadClient(dir, i+1)
+		if err != nil {
+			return nil, errors.Wrapf(err, "load client %d", i+1)
+		}
+		keys[i] = client.Secret
+	}
+	return keys, nil
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+func TestMarshalling(t *testing.T) {
+	// Create fake clients for test
+	clients := []Client{
+		{Secret: data.ClientKey{I: 0, Key: json.RawMessage(`{"SecKey":1}`)}},
+		{Secret: data.ClientKey{I: 1, Key: json.RawMessage(`{"SecKey":2}`)}},
+	}
+
+	// Create temp dir
+	dir, err := ioutil.TempDir("", "clients_secrets")
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	// Tests
+	t.Run("Save", func(t *testing.T) {
+		for _, client := range clients {
+			err := client.SaveClient(dir)
+			assert.NoError(t, err, "save client")
+		}
+		err := clients[0].SaveClient(dir)
+		assert.Error(t, err, "overwrote client")
+	})
+
+	t.Run("Load", func(t *testing.T) {
+		client2, err := LoadClient(dir, 2)
+		assert.NoError(t, err, "load client")
+		assert.Equal(t, &clients[1], client2)
+
+		keys, err := LoadClients(dir, 2)
+		assert.NoError(t, err, "load clients")
+		assert.Equal(t, []data.ClientKey{clients[0].Secret, clients[1].Secret}, keys)
+
+		_, err = LoadClients(dir, 3)
+		assert.Error(t, err, "loaded missing client")
+	})
+}
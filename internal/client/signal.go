@@ -0,0 +1,166 @@
+package client
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/pkg/errors"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/dkg"
+	gofe2 "github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/memo"
+)
+
+// Signal of multi-client scheme is encrypted over a Transport without
+// anyone holding keys of other clients:
+//  1. Request: sender seals value of every slot to memo key of its client
+//     and publishes them along with fresh label.
+//  2. EncryptSlot: every client opens its own value, encrypts it under the
+//     label and publishes encrypted slot.
+//  3. Combine: anyone assembles ciphertext once every slot is published.
+//
+// Every client learns only value of its own slot, and sealed values are of
+// the same size, so they don't tell whose slot is non-zero.
+
+// Message published by PublishKey
+type Key struct {
+	I      int
+	Public []byte
+}
+
+// Message published by RequestSignal
+type Request struct {
+	Label string
+	// Values[j] is value of j-th slot sealed to memo key of j-th client
+	Values [][]byte
+	// Memo sealed to the recipient, it's published along with the signal
+	Memo []byte
+}
+
+// Message published by EncryptSlot
+type Slot struct {
+	I    int
+	Slot []byte
+}
+
+func keyMessage(i int) string {
+	return fmt.Sprintf("client_key_%d", i+1)
+}
+
+func requestMessage(n int) string {
+	return fmt.Sprintf("request_%d", n)
+}
+
+func slotMessage(n, i int) string {
+	return fmt.Sprintf("slot_%d_%d", n, i+1)
+}
+
+// Publishes public part of client's memo key, so senders can seal values
+// of its slot to it
+func (c *Client) PublishKey(transport dkg.Transport) error {
+	if c.Memo == nil {
+		return errors.Errorf("client %d has no memo key", c.Secret.I+1)
+	}
+	err := transport.Publish(keyMessage(c.Secret.I), Key{I: c.Secret.I, Public: c.Memo.Public})
+	return errors.Wrap(err, "publish client key")
+}
+
+// Publishes request to encrypt vector as n-th signal, randomness is drawn
+// from given source
+func RequestSignal(random io.Reader, transport dkg.Transport, mpk data.MPK, n int, vector gofe.Vector, sealedMemo []byte) error {
+	if len(vector) != mpk.L {
+		return errors.New("plaintext has wrong length")
+	}
+	if err := transport.Retrieve(requestMessage(n), &Request{}); err == nil {
+		return errors.Errorf("signal %d is already requested, clients must encrypt it first", n)
+	}
+	nonce := make([]byte, 16)
+	if _, err := io.ReadFull(random, nonce); err != nil {
+		return errors.Wrap(err, "generate label")
+	}
+
+	values := make([][]byte, mpk.L)
+	for i, x := range vector {
+		var key Key
+		if err := transport.Retrieve(keyMessage(i), &key); err != nil {
+			return errors.Wrapf(err, "retrieve key of client %d", i+1)
+		}
+		if key.I != i {
+			return errors.Errorf("expected key of client %d, got %d", i+1, key.I+1)
+		}
+		sealed, err := memo.Seal(random, key.Public, n, []byte(x.String()))
+		if err != nil {
+			return errors.Wrapf(err, "seal value of slot %d", i+1)
+		}
+		values[i] = sealed
+	}
+
+	request := Request{Label: hex.EncodeToString(nonce), Values: values, Memo: sealedMemo}
+	return errors.Wrap(transport.Publish(requestMessage(n), request), "publish request")
+}
+
+// Encrypts client's slot of n-th signal and publishes it
+func (c *Client) EncryptSlot(transport dkg.Transport, mpk data.MPK, n int) error {
+	i := c.Secret.I
+	if c.Memo == nil {
+		return errors.Errorf("client %d has no memo key", i+1)
+	}
+	request, err := retrieveRequest(transport, mpk, n)
+	if err != nil {
+		return err
+	}
+
+	opened, err := c.Memo.Open(n, request.Values[i])
+	if err != nil {
+		return errors.Wrapf(err, "open value of slot %d", i+1)
+	}
+	x, ok := new(big.Int).SetString(string(opened), 10)
+	if !ok {
+		return errors.Errorf("malformed value of slot %d", i+1)
+	}
+	slot, err := gofe2.EncryptSlot(mpk, c.Secret, x, request.Label)
+	if err != nil {
+		return errors.Wrapf(err, "encrypt slot %d", i+1)
+	}
+	return errors.Wrap(transport.Publish(slotMessage(n, i), Slot{I: i, Slot: slot}), "publish slot")
+}
+
+// Assembles n-th signal from slots of all the clients, returns its
+// ciphertext along with memo of the request
+//
+// Returns error wrapping dkg.ErrNotPublished if some client hasn't
+// published its slot yet.
+func Combine(transport dkg.Transport, mpk data.MPK, n int) (data.Ciphertext, []byte, error) {
+	request, err := retrieveRequest(transport, mpk, n)
+	if err != nil {
+		return data.Ciphertext{}, nil, err
+	}
+	slots := make([][]byte, mpk.L)
+	for i := range slots {
+		var slot Slot
+		if err := transport.Retrieve(slotMessage(n, i), &slot); err != nil {
+			return data.Ciphertext{}, nil, errors.Wrapf(err, "retrieve slot %d", i+1)
+		}
+		if slot.I != i {
+			return data.Ciphertext{}, nil, errors.Errorf("expected slot %d, got %d", i+1, slot.I+1)
+		}
+		slots[i] = slot.Slot
+	}
+	ciphertext, err := gofe2.CombineSlots(mpk, request.Label, slots)
+	return ciphertext, request.Memo, err
+}
+
+func retrieveRequest(transport dkg.Transport, mpk data.MPK, n int) (*Request, error) {
+	var request Request
+	if err := transport.Retrieve(requestMessage(n), &request); err != nil {
+		return nil, errors.Wrapf(err, "retrieve request of signal %d", n)
+	}
+	if request.Label == "" || len(request.Values) != mpk.L {
+		return nil, errors.Errorf("malformed request of signal %d", n)
+	}
+	return &request, nil
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/dkg"
+	gofe2 "github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/memo"
+)
+
+func TestSignal(t *testing.T) {
+	scheme, err := gofe2.LookupScheme(gofe2.DMCFESchemeID)
+	assert.NoError(t, err)
+	mpk, keys, sk, err := gofe2.GenerateClientKeysWith(scheme.(gofe2.MultiClientScheme), 3)
+	assert.NoError(t, err, "keygen failed")
+
+	transport := dkg.NewMemoryTransport()
+	clients := make([]*Client, len(keys))
+	for i, key := range keys {
+		memoKey, err := memo.GenerateKey(rand.Reader)
+		if err != nil {
+			panic(err)
+		}
+		clients[i] = &Client{Secret: key, Memo: memoKey}
+		assert.NoError(t, clients[i].PublishKey(transport), "publish key")
+	}
+
+	// x = [0 1 0]
+	x := gofe.NewConstantVector(3, big.NewInt(0))
+	x[1] = big.NewInt(1)
+	assert.NoError(t, RequestSignal(rand.Reader, transport, mpk, 1, x, []byte("memo")), "request")
+	assert.Error(t, RequestSignal(rand.Reader, transport, mpk, 1, x, nil), "requested the same signal twice")
+
+	// Client can't encrypt slot of another client
+	stolen := &Client{Secret: clients[1].Secret, Memo: clients[0].Memo}
+	assert.Error(t, stolen.EncryptSlot(transport, mpk, 1), "encrypted slot sealed to another client")
+
+	for _, c := range clients[:2] {
+		assert.NoError(t, c.EncryptSlot(transport, mpk, 1), "encrypt slot")
+	}
+	_, _, err = Combine(transport, mpk, 1)
+	assert.True(t, errors.Is(err, dkg.ErrNotPublished), "combined without slot of the last client")
+	assert.NoError(t, clients[2].EncryptSlot(transport, mpk, 1), "encrypt slot")
+	assert.Error(t, clients[2].EncryptSlot(transport, mpk, 1), "encrypted slot twice")
+	assert.Error(t, clients[0].EncryptSlot(transport, mpk, 2), "encrypted slot of signal never requested")
+
+	ciphertext, sealedMemo, err := Combine(transport, mpk, 1)
+	assert.NoError(t, err, "combine")
+	assert.Equal(t, []byte("memo"), sealedMemo)
+	for i, expected := range x {
+		v, err := gofe2.Decrypt(mpk, sk[i], &ciphertext)
+		assert.NoError(t, err, "decrypt using sk", i)
+		assert.Equal(t, expected, v)
+	}
+}
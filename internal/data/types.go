@@ -1,7 +1,10 @@
 package data
 
 import (
-	"github.com/fentec-project/gofe/innerprod/simple"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"math/big"
 
 	"github.com/pkg/errors"
@@ -9,35 +12,248 @@ import (
 	gofe "github.com/fentec-project/gofe/data"
 )
 
+// Master public key of inner-product FE scheme
+//
+// Content of Key is defined by the scheme identified by Scheme, so
+// it can be interpreted only by corresponding scheme implementation.
 type MPK struct {
-	DDH    *simple.DDH
-	Vector gofe.Vector
+	// Identifier of the scheme which produced the key (e.g. "ddh")
+	Scheme string
+	// Length of plaintext vectors, i.e. total amount of recipients
+	L int
+	// Scheme-specific public parameters and master public key
+	Key json.RawMessage
+	// Security profile the scheme was configured with, it's absent if
+	// keys were generated with scheme defaults
+	Profile *Profile `json:",omitempty"`
+	// Max amount a single signal may carry, 0 if signals don't carry
+	// amounts. Every round proves its amount is in range [1; MaxAmount].
+	MaxAmount int64 `json:",omitempty"`
+}
+
+// Returns hex-encoded SHA-256 of scheme, L, key, max signals and max amount
+// of mpk
+//
+// Max signals of recorded profile places boundaries of accumulator epochs, so
+// it's fingerprinted along with the key. The rest of profile only describes
+// security and doesn't affect the fingerprint. Max signals and max amount are
+// omitted if they're 0, so fingerprints of mpks without them stay the same.
+func (m *MPK) Fingerprint() string {
+	var maxSignals int64
+	if m.Profile != nil {
+		maxSignals = m.Profile.MaxSignals
+	}
+	// Marshalling compacts Key, so formatting of stored mpk doesn't matter
+	encoded, err := json.Marshal(struct {
+		Scheme     string
+		L          int
+		Key        json.RawMessage
+		MaxSignals int64 `json:",omitempty"`
+		MaxAmount  int64 `json:",omitempty"`
+	}{m.Scheme, m.L, m.Key, maxSignals, m.MaxAmount})
+	if err != nil {
+		// Key isn't valid JSON, it's identified by its raw bytes then
+		encoded = append([]byte(m.Scheme+"\x00"), m.Key...)
+	}
+	hash := sha256.Sum256(encoded)
+	return hex.EncodeToString(hash[:])
 }
 
+// Security provided by master keys
+type Profile struct {
+	// Name of predefined profile, it's empty for custom parameters
+	Name string
+	// Bit length of modulus actually used by the scheme
+	ModulusBits int
+	// Estimated security level, 0 if it's not estimated
+	SecurityBits int
+	// Amount of signals every recipient is able to receive
+	MaxSignals int64
+}
+
+// Master secret key of inner-product FE scheme
+//
+// Like MPK, Key content is scheme-specific.
+type MSK struct {
+	Scheme string
+	Key    json.RawMessage
+}
+
+// Ciphertext of inner-product FE scheme
+//
+// Schemes working in Z_n groups use Vector, whereas elliptic curve schemes
+// use Points holding serialized curve points.
 type Ciphertext struct {
-	Vector gofe.Vector
+	Vector gofe.Vector `json:",omitempty"`
+	Points [][]byte    `json:",omitempty"`
 }
 
-// Performs ciphertext *= anotherCiphertext
+// Performs ciphertext *= anotherCiphertext (mod modulus)
+//
+// Reducing the product keeps accumulated ciphertext at constant size no
+// matter how many ciphertexts were multiplied into it.
 //
 // Might result in error, e.g. if adding ciphertext of different length.
 // In this case, ciphertext is not modified.
-func (c *Ciphertext) Mul(another *Ciphertext) error {
+func (c *Ciphertext) Mul(another *Ciphertext, modulus *big.Int) error {
 	v1 := ([]*big.Int)(c.Vector)
 	v2 := ([]*big.Int)(another.Vector)
 
 	if len(v1) != len(v2) {
 		return errors.New("given ciphertexts have different lengths")
 	}
+	if modulus == nil || modulus.Sign() <= 0 {
+		return errors.New("modulus must be positive")
+	}
 
 	for i, x := range another.Vector {
-		c.Vector[i] = new(big.Int).Mul(c.Vector[i], x)
+		product := new(big.Int).Mul(c.Vector[i], x)
+		c.Vector[i] = product.Mod(product, modulus)
 	}
 
 	return nil
 }
 
+// Returns deep copy of the ciphertext
+func (c *Ciphertext) Copy() *Ciphertext {
+	copied := &Ciphertext{}
+	if c.Vector != nil {
+		copied.Vector = make(gofe.Vector, len(c.Vector))
+		for i, x := range c.Vector {
+			if x != nil {
+				copied.Vector[i] = new(big.Int).Set(x)
+			}
+		}
+	}
+	if c.Points != nil {
+		copied.Points = make([][]byte, len(c.Points))
+		for i, point := range c.Points {
+			copied.Points[i] = append([]byte(nil), point...)
+		}
+	}
+	return copied
+}
+
+// Checks whether ciphertexts consist of the same elements
+func (c *Ciphertext) Equal(another *Ciphertext) bool {
+	if len(c.Vector) != len(another.Vector) || len(c.Points) != len(another.Points) {
+		return false
+	}
+	for i, x := range c.Vector {
+		y := another.Vector[i]
+		if x == nil || y == nil {
+			if x != y {
+				return false
+			}
+			continue
+		}
+		if x.Cmp(y) != 0 {
+			return false
+		}
+	}
+	for i, point := range c.Points {
+		if !bytes.Equal(point, another.Points[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 type RecipientSecretKey struct {
-	I          int
-	DerivedKey *big.Int
+	I int
+	// Scheme-specific key derived for vector y = e_I
+	DerivedKey json.RawMessage
+}
+
+// Secret key of a client of multi-client scheme
+//
+// Every client encrypts its own slot of plaintext vector and contributes
+// its share to every derived key.
+type ClientKey struct {
+	I int
+	// Scheme-specific secret key of I-th client
+	Key json.RawMessage
+}
+
+// Share of master secret key held by one of k-of-n authorities
+type MSKShare struct {
+	Scheme string
+	// Index of authority (1-indexed), it's x coordinate of Shamir's shares
+	Authority int
+	// Amount of authorities required to derive a key
+	Threshold int
+	// Shares of every coordinate of every msk vector
+	Vectors []gofe.Vector
+}
+
+// Share of recipient's derived key produced by one of k-of-n authorities
+type DerivedKeyShare struct {
+	Authority int
+	Threshold int
+	// Index of recipient (0-indexed)
+	I   int
+	Key gofe.Vector
+}
+
+// Round published in repository
+type Round struct {
+	// Accumulated ciphertext E_t
+	Ciphertext
+	// Fresh ciphertext accumulated into E_{t-1} to obtain E_t
+	Delta *Ciphertext `json:",omitempty"`
+	// Proof that Delta encrypts one-hot vector, it's present if scheme is
+	// able to produce it
+	Proof json.RawMessage `json:",omitempty"`
+	// Memo sealed to recipient of the signal, see package memo
+	Memo []byte `json:",omitempty"`
+}
+
+// Assignment of slots of plaintext vector to recipients
+//
+// Keys may be issued only for some of the slots, the rest are reserved for
+// recipients added later.
+type Membership struct {
+	// Slots (0-indexed) which recipients' keys were issued for
+	Assigned []int
+	// Assigned slots which were retired, they're never signalled again
+	Revoked []int `json:",omitempty"`
+}
+
+// Checks whether key of i-th slot (0-indexed) was issued
+func (m *Membership) IsAssigned(i int) bool {
+	for _, j := range m.Assigned {
+		if i == j {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks whether i-th slot (0-indexed) was revoked
+func (m *Membership) IsRevoked(i int) bool {
+	for _, j := range m.Revoked {
+		if i == j {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the lowest slot (0-indexed) among `slots` which isn't assigned, so
+// revoked slots are never reused
+func (m *Membership) NextFree(slots int) (int, error) {
+	for i := 0; i < slots; i++ {
+		if !m.IsAssigned(i) {
+			return i, nil
+		}
+	}
+	return 0, errors.New("all slots are assigned")
+}
+
+// Range of rounds encrypted under the same mpk
+//
+// Key epoch lasts from FirstRound until the next key epoch begins.
+type KeyEpoch struct {
+	FirstRound int
+	MPK        MPK
 }
@@ -0,0 +1,138 @@
+// Package dkg implements key generation without trusted party
+//
+// Ceremony consists of three steps:
+//  1. Init: anyone publishes public parameters of the scheme. They don't
+//     involve any secrets.
+//  2. Contribute: every recipient generates its own part of master key,
+//     keeps derived secret key and publishes public part of master key
+//     together with proof of knowledge of the secret.
+//  3. Finalize: anyone verifies published parts and assembles master public key.
+//
+// No one ever holds the whole master secret key, each recipient knows
+// only its own part.
+package dkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
+)
+
+// Message published by Init
+type Params struct {
+	Scheme string
+	L      int
+	Params json.RawMessage
+}
+
+// Message published by Contribute
+type Contribution struct {
+	I    int
+	Part json.RawMessage
+}
+
+const paramsMessage = "params"
+
+func contributionMessage(i int) string {
+	return fmt.Sprintf("contribution_%d", i+1)
+}
+
+// Publishes public parameters of the scheme for `parties` recipients,
+// randomness is drawn from given source
+func Init(random io.Reader, transport Transport, scheme gofe.DistributedScheme, parties int) error {
+	params, err := scheme.GenerateParams(random, parties)
+	if err != nil {
+		return errors.Wrap(err, "generate params")
+	}
+
+	err = transport.Publish(paramsMessage, Params{Scheme: scheme.ID(), L: parties, Params: params})
+	return errors.Wrap(err, "publish params")
+}
+
+// Generates part of master key for i-th recipient (0-indexed) and publishes
+// its public part
+//
+// Returned secret key must be kept by the recipient.
+func Contribute(random io.Reader, transport Transport, i int) (data.RecipientSecretKey, error) {
+	params, scheme, err := retrieveParams(transport)
+	if err != nil {
+		return data.RecipientSecretKey{}, err
+	}
+	if i < 0 || i >= params.L {
+		return data.RecipientSecretKey{}, errors.Errorf("expected party in range [1; %d]", params.L)
+	}
+
+	part, sk, err := scheme.GenerateKeyPart(random, params.Params, i)
+	if err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "generate key part")
+	}
+
+	err = transport.Publish(contributionMessage(i), Contribution{I: i, Part: part})
+	if err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "publish contribution")
+	}
+	return sk, nil
+}
+
+// Verifies contributions of all recipients and assembles mpk
+func Finalize(transport Transport) (data.MPK, error) {
+	params, scheme, err := retrieveParams(transport)
+	if err != nil {
+		return data.MPK{}, err
+	}
+
+	parts := make([]json.RawMessage, params.L)
+	for i := range parts {
+		var contribution Contribution
+		err := transport.Retrieve(contributionMessage(i), &contribution)
+		if err == ErrNotPublished {
+			return data.MPK{}, errors.Errorf("party %d hasn't contributed yet", i+1)
+		} else if err != nil {
+			return data.MPK{}, errors.Wrapf(err, "retrieve contribution of party %d", i+1)
+		}
+		if contribution.I != i {
+			return data.MPK{}, errors.Errorf("contribution of party %d claims to be of party %d", i+1, contribution.I+1)
+		}
+		parts[i] = contribution.Part
+	}
+
+	mpk, err := scheme.CombineKeyParts(params.Params, parts)
+	if err != nil {
+		return data.MPK{}, errors.Wrap(err, "combine key parts")
+	}
+	return mpk, nil
+}
+
+func retrieveParams(transport Transport) (Params, gofe.DistributedScheme, error) {
+	var params Params
+	err := transport.Retrieve(paramsMessage, &params)
+	if err == ErrNotPublished {
+		return Params{}, nil, errors.New("ceremony is not initialized")
+	} else if err != nil {
+		return Params{}, nil, errors.Wrap(err, "retrieve params")
+	}
+
+	scheme, err := LookupScheme(params.Scheme)
+	if err != nil {
+		return Params{}, nil, err
+	}
+	return params, scheme, nil
+}
+
+// Looks up registered scheme which supports distributed key generation
+func LookupScheme(id string) (gofe.DistributedScheme, error) {
+	scheme, err := gofe.LookupScheme(id)
+	if err != nil {
+		return nil, err
+	}
+	distributed, ok := scheme.(gofe.DistributedScheme)
+	if !ok {
+		return nil, errors.Errorf("scheme %q doesn't support distributed key generation", id)
+	}
+	return distributed, nil
+}
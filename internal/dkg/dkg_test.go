@@ -0,0 +1,145 @@
+package dkg
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync"
+	"testing"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+	gofe2 "github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
+)
+
+// Runs the whole ceremony, every party contributes concurrently
+func runCeremony(t *testing.T, transport Transport, schemeID string, parties int) (data.MPK, []data.RecipientSecretKey) {
+	scheme, err := LookupScheme(schemeID)
+	assert.NoError(t, err)
+	err = Init(rand.Reader, transport, scheme, parties)
+	assert.NoError(t, err, "init")
+
+	sk := make([]data.RecipientSecretKey, parties)
+	errs := make([]error, parties)
+	var wg sync.WaitGroup
+	for i := 0; i < parties; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sk[i], errs[i] = Contribute(rand.Reader, transport, i)
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		assert.NoError(t, err, "contribute party", i+1)
+	}
+
+	mpk, err := Finalize(transport)
+	assert.NoError(t, err, "finalize")
+	return mpk, sk
+}
+
+func TestCeremony(t *testing.T) {
+	for _, id := range []string{gofe2.DDHSchemeID, gofe2.ECDDHSchemeID} {
+		t.Run(id, func(t *testing.T) {
+			mpk, sk := runCeremony(t, NewMemoryTransport(), id, 3)
+			assert.Equal(t, id, mpk.Scheme)
+			assert.Equal(t, 3, mpk.L)
+
+			// x = [0 1 0]
+			x := gofe.NewConstantVector(3, big.NewInt(0))
+			x[1] = big.NewInt(1)
+			ciphertext, err := gofe2.Encrypt(mpk, x)
+			assert.NoError(t, err, "encrypt")
+
+			for j := 0; j < 3; j++ {
+				v, err := gofe2.Decrypt(mpk, sk[j], &ciphertext)
+				assert.NoError(t, err, "decrypt using sk", j)
+				assert.Equal(t, x[j], v, "wrong decryption using sk", j)
+			}
+		})
+	}
+}
+
+func TestCeremonyOverFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dkg")
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	transport, err := NewFileTransport(dir)
+	assert.NoError(t, err)
+	mpk, sk := runCeremony(t, transport, gofe2.ECDDHSchemeID, 2)
+	assert.Len(t, sk, 2)
+
+	t.Run("Contributions can't be overwritten", func(t *testing.T) {
+		_, err := Contribute(rand.Reader, transport, 0)
+		assert.Error(t, err)
+		mpk2, err := Finalize(transport)
+		assert.NoError(t, err)
+		assert.Equal(t, mpk, mpk2)
+	})
+}
+
+func TestFinalizeRejectsInvalidContributions(t *testing.T) {
+	scheme, err := LookupScheme(gofe2.ECDDHSchemeID)
+	assert.NoError(t, err)
+
+	t.Run("Missing contribution", func(t *testing.T) {
+		transport := NewMemoryTransport()
+		assert.NoError(t, Init(rand.Reader, transport, scheme, 2))
+		_, err := Contribute(rand.Reader, transport, 0)
+		assert.NoError(t, err)
+		_, err = Finalize(transport)
+		assert.Error(t, err)
+	})
+
+	t.Run("Copied contribution", func(t *testing.T) {
+		transport := NewMemoryTransport()
+		assert.NoError(t, Init(rand.Reader, transport, scheme, 2))
+		_, err := Contribute(rand.Reader, transport, 0)
+		assert.NoError(t, err)
+
+		// Party 2 replays proof of party 1, which is bound to party index
+		var contribution Contribution
+		assert.NoError(t, transport.Retrieve(contributionMessage(0), &contribution))
+		contribution.I = 1
+		assert.NoError(t, transport.Publish(contributionMessage(1), contribution))
+
+		_, err = Finalize(transport)
+		assert.Error(t, err)
+	})
+
+	t.Run("Forged proof", func(t *testing.T) {
+		transport := NewMemoryTransport()
+		assert.NoError(t, Init(rand.Reader, transport, scheme, 2))
+		_, err := Contribute(rand.Reader, transport, 0)
+		assert.NoError(t, err)
+
+		var params Params
+		assert.NoError(t, transport.Retrieve(paramsMessage, &params))
+		part, _, err := scheme.GenerateKeyPart(rand.Reader, params.Params, 1)
+		assert.NoError(t, err)
+		var forged map[string]interface{}
+		assert.NoError(t, json.Unmarshal(part, &forged))
+		forged["Response"] = 12345
+		part, err = json.Marshal(forged)
+		assert.NoError(t, err)
+		assert.NoError(t, transport.Publish(contributionMessage(1), Contribution{I: 1, Part: part}))
+
+		_, err = Finalize(transport)
+		assert.Error(t, err)
+	})
+}
+
+func TestSchemeWithoutDKGSupport(t *testing.T) {
+	_, err := LookupScheme(gofe2.PaillierSchemeID)
+	assert.Error(t, err)
+}
@@ -0,0 +1,117 @@
+package dkg
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotPublished is returned by Transport if requested message wasn't
+// published yet
+var ErrNotPublished = errors.New("message is not published")
+
+// Transport delivers ceremony messages between parties
+//
+// It behaves like a bulletin board: every published message is visible
+// to all the parties, and it can't be overwritten.
+type Transport interface {
+	// Publishes message under given name
+	//
+	// It's an error if message with this name already published.
+	Publish(name string, msg interface{}) error
+	// Retrieves message with given name and decodes it into msg
+	//
+	// Returns ErrNotPublished if there's no such message.
+	Retrieve(name string, msg interface{}) error
+}
+
+// Transport storing every message at `{dir}/{name}.json`
+type FileTransport struct {
+	dir string
+}
+
+func NewFileTransport(dir string) (*FileTransport, error) {
+	err := os.MkdirAll(dir, 0777)
+	if err != nil {
+		return nil, errors.Wrap(err, "create dir")
+	}
+	return &FileTransport{dir: dir}, nil
+}
+
+func (t *FileTransport) Publish(name string, msg interface{}) error {
+	filename := path.Join(t.dir, name+".json")
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666)
+	if err != nil {
+		return errors.Wrapf(err, "create file for %s", name)
+	}
+
+	err = json.NewEncoder(file).Encode(msg)
+	if err != nil {
+		_ = file.Close()
+		return errors.Wrap(err, "encode/write to file")
+	}
+	if err = file.Close(); err != nil {
+		return errors.Wrap(err, "close file")
+	}
+
+	return nil
+}
+
+func (t *FileTransport) Retrieve(name string, msg interface{}) error {
+	filename := path.Join(t.dir, name+".json")
+	file, err := os.Open(filename)
+	if err != nil && os.IsNotExist(err) {
+		return ErrNotPublished
+	} else if err != nil {
+		return errors.Wrapf(err, "open %s", name)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	err = json.NewDecoder(file).Decode(msg)
+	if err != nil {
+		return errors.Wrapf(err, "decode/read %s", name)
+	}
+	return nil
+}
+
+// In-process Transport, safe for concurrent use
+//
+// Messages are stored JSON-encoded, so parties never share memory.
+type MemoryTransport struct {
+	mu       sync.Mutex
+	messages map[string][]byte
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{messages: map[string][]byte{}}
+}
+
+func (t *MemoryTransport) Publish(name string, msg interface{}) error {
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "encode message")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.messages[name]; ok {
+		return errors.Errorf("message %s is already published", name)
+	}
+	t.messages[name] = encoded
+	return nil
+}
+
+func (t *MemoryTransport) Retrieve(name string, msg interface{}) error {
+	t.mu.Lock()
+	encoded, ok := t.messages[name]
+	t.mu.Unlock()
+	if !ok {
+		return ErrNotPublished
+	}
+	return errors.Wrapf(json.Unmarshal(encoded, msg), "decode %s", name)
+}
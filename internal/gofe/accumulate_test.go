@@ -0,0 +1,131 @@
+package gofe
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+// Simulates send-signal run for many rounds: round 1 signals party 0,
+// every other round carries a zero signal
+//
+// Zero signals are encrypted once and reused, accumulation is what's being
+// measured, not encryption. Rounds are accumulated by this package only, they
+// never go through rounds.Repository (see TestStoredRoundsHaveConstantSize
+// in package rounds for that).
+type roundsAccumulator struct {
+	mpk    data.MPK
+	sk     []data.RecipientSecretKey
+	acc    data.Ciphertext
+	zeros  []data.Ciphertext
+	rounds int
+}
+
+func newRoundsAccumulator(t testing.TB, scheme Scheme) *roundsAccumulator {
+	mpk, sk, encrypt := setupScheme(t, scheme, 2)
+
+	x := gofe.NewConstantVector(2, big.NewInt(0))
+	zeros := make([]data.Ciphertext, 8)
+	for i := range zeros {
+		ciphertext, err := encrypt(x)
+		assert.NoError(t, err, "encrypt zero signal")
+		zeros[i] = ciphertext
+	}
+
+	x[0] = big.NewInt(1)
+	acc, err := encrypt(x)
+	assert.NoError(t, err, "encrypt signal")
+	return &roundsAccumulator{mpk: mpk, sk: sk, acc: acc, zeros: zeros, rounds: 1}
+}
+
+// Accumulates rounds until there are `rounds` of them
+func (r *roundsAccumulator) accumulateUntil(t testing.TB, rounds int) {
+	for ; r.rounds < rounds; r.rounds++ {
+		delta := r.zeros[r.rounds%len(r.zeros)]
+		err := Accumulate(r.mpk, &r.acc, &delta)
+		if err != nil {
+			assert.NoError(t, err, "accumulate round", r.rounds+1)
+			return
+		}
+	}
+}
+
+func (r *roundsAccumulator) roundSize(t testing.TB) int {
+	encoded, err := json.Marshal(r.acc)
+	assert.NoError(t, err, "encode ciphertext")
+	return len(encoded)
+}
+
+// LWE decryption noise grows with every accumulated ciphertext, so amount of
+// rounds it tolerates is bounded by design
+func toleratesUnboundedRounds(scheme Scheme) bool {
+	return scheme.ID() != LWESchemeID
+}
+
+// Checks that ciphertext accumulated by the library over 10000 rounds has the
+// same size as a fresh one and still decrypts correctly
+func TestAccumulatedCiphertextHasConstantSize(t *testing.T) {
+	forEachScheme(t, func(t *testing.T, scheme Scheme) {
+		if !toleratesUnboundedRounds(scheme) {
+			t.Skip("amount of rounds is bounded by the scheme")
+		}
+
+		r := newRoundsAccumulator(t, scheme)
+		firstRoundSize := r.roundSize(t)
+		r.accumulateUntil(t, 10000)
+
+		// Elements of different rounds might differ in a few leading zeros
+		assert.LessOrEqual(t, r.roundSize(t), firstRoundSize*11/10, "round 10000 is larger than round 1")
+
+		v, err := Decrypt(r.mpk, r.sk[0], &r.acc)
+		assert.NoError(t, err, "decrypt round 10000")
+		assert.Equal(t, big.NewInt(1), v, "wrong decryption of round 10000")
+	})
+}
+
+func TestCiphertextMulReducesModulo(t *testing.T) {
+	c1 := data.Ciphertext{Vector: gofe.Vector{big.NewInt(5), big.NewInt(6)}}
+	c2 := data.Ciphertext{Vector: gofe.Vector{big.NewInt(3), big.NewInt(4)}}
+	assert.NoError(t, c1.Mul(&c2, big.NewInt(7)))
+	assert.Equal(t, gofe.Vector{big.NewInt(1), big.NewInt(3)}, c1.Vector)
+
+	c3 := data.Ciphertext{Vector: gofe.Vector{big.NewInt(1)}}
+	assert.Error(t, c1.Mul(&c3, big.NewInt(7)), "multiplied ciphertexts of different lengths")
+	assert.Error(t, c1.Mul(&c2, nil), "multiplied without modulus")
+}
+
+// Shows that round size and decryption time don't depend on amount of rounds
+// accumulated by the library, repository isn't involved
+//
+// Run it with `go test -run XXX -bench AccumulatedRounds ./internal/gofe`
+func BenchmarkAccumulatedRounds(b *testing.B) {
+	for _, id := range SchemeIDs() {
+		scheme, err := LookupScheme(id)
+		if err != nil {
+			panic(err)
+		}
+		if !toleratesUnboundedRounds(scheme) {
+			continue
+		}
+
+		r := newRoundsAccumulator(b, scheme)
+		for _, rounds := range []int{1, 100, 10000} {
+			r.accumulateUntil(b, rounds)
+			b.Run(fmt.Sprintf("%s/rounds=%d", id, rounds), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					_, err := Decrypt(r.mpk, r.sk[0], &r.acc)
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(r.roundSize(b)), "bytes/round")
+			})
+		}
+	}
+}
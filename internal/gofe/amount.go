@@ -0,0 +1,413 @@
+package gofe
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/big"
+
+	"github.com/fentec-project/bn256"
+	"github.com/pkg/errors"
+)
+
+// Proof that ciphertext c_0 = g^r, c_i = h_i^r * g^x_i encrypts a * e_j for
+// hidden recipient j and hidden amount a in range [1; bound]
+//
+// Sender publishes one-hot ciphertext u of e_j along with its one-hot proof
+// and Pedersen commitment A = g^a * f^rho to the amount, where f is a
+// generator nobody knows discrete logarithm of. Then it proves that
+// c = u^a * Enc(0; s) for a committed by A, and that a - 1 and bound - a
+// are both in range [0; 2^k), so a is in range [1; bound].
+type amountProof struct {
+	OneHot      [][]byte
+	OneHotProof json.RawMessage
+	Commitment  []byte
+	Link        linkProof
+	// Range proofs of A / g and g^bound / A
+	Lower, Upper rangeProof
+}
+
+// Proof of knowledge of a, s and rho such that c_0 = u_0^a * g^s,
+// c_i = u_i^a * h_i^s and A = g^a * f^rho
+type linkProof struct {
+	// Commitments to c_0, ..., c_l and to A
+	T         [][]byte
+	A, S, Rho *big.Int
+}
+
+// Proof that X = g^v * f^sigma commits to v in range [0; 2^k)
+//
+// Bits[k] commits to k-th bit of v, they multiply up to X as
+// X = prod Bits[k]^(2^k).
+type rangeProof struct {
+	Bits   [][]byte
+	Proofs []bitCommitmentProof
+}
+
+// OR-proof of knowledge of log_f X or log_f (X / g), i.e. that X commits
+// to 0 or 1
+type bitCommitmentProof struct {
+	A    [2][]byte
+	C, Z [2]*big.Int
+}
+
+const (
+	amountLinkDomain      = "pps/amount/link"
+	amountRangeDomain     = "pps/amount/range/"
+	amountGeneratorDomain = "pps/amount/generator"
+)
+
+// Encrypts a * e_index as c = u^a * Enc(0; s), where u is one-hot encryption
+// of e_index, and proves that 1 <= a <= bound
+func encryptAmount(random io.Reader, group proofGroup, h []interface{}, index int, amount, bound int64, context []byte) ([]interface{}, json.RawMessage, error) {
+	if bound < 1 {
+		return nil, nil, errors.New("bound of amount must be positive")
+	}
+	if amount < 1 || amount > bound {
+		return nil, nil, errors.Errorf("expected amount in range [1; %d], got %d", bound, amount)
+	}
+	q := group.order()
+	f, err := pedersenGenerator(group, context)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u, oneHotProof, err := encryptOneHot(random, group, h, index, context)
+	if err != nil {
+		return nil, nil, err
+	}
+	a := big.NewInt(amount)
+	s, err := sampleScalar(random, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	ciphertext := make([]interface{}, len(h)+1)
+	ciphertext[0] = group.mul(group.exp(u[0], a), group.baseExp(s))
+	for i := range h {
+		ciphertext[i+1] = group.mul(group.exp(u[i+1], a), group.exp(h[i], s))
+	}
+
+	rho, err := sampleScalar(random, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	commitment := group.mul(group.baseExp(a), group.exp(f, rho))
+	ctx := amountContext(group, context, bound, h, ciphertext, u, commitment)
+
+	proof := amountProof{
+		OneHot:      make([][]byte, len(u)),
+		OneHotProof: oneHotProof,
+		Commitment:  group.marshal(commitment),
+	}
+	for i, e := range u {
+		proof.OneHot[i] = group.marshal(e)
+	}
+	proof.Link, err = proveLink(random, group, f, h, u, a, s, rho, ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	k := amountBits(bound)
+	proof.Lower, err = proveRange(random, group, f, big.NewInt(amount-1), rho, k, ctx, "lower")
+	if err != nil {
+		return nil, nil, err
+	}
+	negRho := new(big.Int).Sub(q, rho)
+	proof.Upper, err = proveRange(random, group, f, big.NewInt(bound-amount), negRho.Mod(negRho, q), k, ctx, "upper")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proofJSON, err := json.Marshal(proof)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "encode proof")
+	}
+	return ciphertext, proofJSON, nil
+}
+
+// Verifies that ciphertext (c_0, c_1, ..., c_l) encrypts a * e_j for some j
+// and 1 <= a <= bound
+//
+// Elements of ciphertext must already be checked to belong to the group.
+func verifyAmount(group proofGroup, h []interface{}, ciphertext []interface{}, bound int64, proofJSON json.RawMessage, context []byte) error {
+	if bound < 1 {
+		return errors.New("bound of amount must be positive")
+	}
+	var proof amountProof
+	if err := json.Unmarshal(proofJSON, &proof); err != nil {
+		return errors.Wrap(err, "decode proof")
+	}
+	if len(ciphertext) != len(h)+1 || len(proof.OneHot) != len(h)+1 {
+		return errors.New("proof doesn't match ciphertext length")
+	}
+	f, err := pedersenGenerator(group, context)
+	if err != nil {
+		return err
+	}
+
+	u := make([]interface{}, len(proof.OneHot))
+	for i, bytes := range proof.OneHot {
+		u[i], err = group.unmarshal(bytes)
+		if err != nil {
+			return errors.Wrapf(err, "one-hot ciphertext element %d is malformed", i)
+		}
+	}
+	if err := verifyOneHot(group, h, u, proof.OneHotProof, context); err != nil {
+		return errors.Wrap(err, "recipient isn't unique")
+	}
+	commitment, err := group.unmarshal(proof.Commitment)
+	if err != nil {
+		return errors.Wrap(err, "commitment to amount is malformed")
+	}
+	ctx := amountContext(group, context, bound, h, ciphertext, u, commitment)
+
+	if err := verifyLink(group, f, h, u, ciphertext, commitment, proof.Link, ctx); err != nil {
+		return err
+	}
+	k := amountBits(bound)
+	g := group.baseExp(big.NewInt(1))
+	if err := verifyRange(group, f, group.div(commitment, g), proof.Lower, k, ctx, "lower"); err != nil {
+		return errors.Wrap(err, "amount is below 1")
+	}
+	upper := group.div(group.baseExp(big.NewInt(bound)), commitment)
+	if err := verifyRange(group, f, upper, proof.Upper, k, ctx, "upper"); err != nil {
+		return errors.Wrapf(err, "amount is above %d", bound)
+	}
+	return nil
+}
+
+func proveLink(random io.Reader, group proofGroup, f interface{}, h, u []interface{}, a, s, rho *big.Int, ctx *big.Int) (linkProof, error) {
+	q := group.order()
+	var nonces [3]*big.Int
+	for i := range nonces {
+		nonce, err := sampleScalar(random, q)
+		if err != nil {
+			return linkProof{}, err
+		}
+		nonces[i] = nonce
+	}
+	alpha, beta, gamma := nonces[0], nonces[1], nonces[2]
+
+	t := linkStatement(group, f, h, u, alpha, beta, gamma)
+	proof := linkProof{T: make([][]byte, len(t))}
+	for i, e := range t {
+		proof.T[i] = group.marshal(e)
+	}
+	c := linkChallenge(q, ctx, proof.T)
+
+	respond := func(nonce, secret *big.Int) *big.Int {
+		z := new(big.Int).Mul(c, secret)
+		z.Add(z, nonce)
+		return z.Mod(z, q)
+	}
+	proof.A, proof.S, proof.Rho = respond(alpha, a), respond(beta, s), respond(gamma, rho)
+	return proof, nil
+}
+
+func verifyLink(group proofGroup, f interface{}, h, u, ciphertext []interface{}, commitment interface{}, proof linkProof, ctx *big.Int) error {
+	if proof.A == nil || proof.S == nil || proof.Rho == nil || len(proof.T) != len(ciphertext)+1 {
+		return errors.New("link proof is malformed")
+	}
+	q := group.order()
+	c := linkChallenge(q, ctx, proof.T)
+
+	// Statement evaluated at responses must equal T * (c_0, ..., c_l, A)^c
+	lhs := linkStatement(group, f, h, u, proof.A, proof.S, proof.Rho)
+	values := append(append([]interface{}{}, ciphertext...), commitment)
+	for i, bytes := range proof.T {
+		t, err := group.unmarshal(bytes)
+		if err != nil {
+			return errors.Wrap(err, "link proof is malformed")
+		}
+		rhs := group.mul(t, group.exp(values[i], c))
+		if string(group.marshal(lhs[i])) != string(group.marshal(rhs)) {
+			return errors.New("ciphertext doesn't encrypt committed amount")
+		}
+	}
+	return nil
+}
+
+// Returns (u_0^a * g^s, u_1^a * h_1^s, ..., u_l^a * h_l^s, g^a * f^rho)
+func linkStatement(group proofGroup, f interface{}, h, u []interface{}, a, s, rho *big.Int) []interface{} {
+	statement := make([]interface{}, len(u)+1)
+	statement[0] = group.mul(group.exp(u[0], a), group.baseExp(s))
+	for i := range h {
+		statement[i+1] = group.mul(group.exp(u[i+1], a), group.exp(h[i], s))
+	}
+	statement[len(u)] = group.mul(group.baseExp(a), group.exp(f, rho))
+	return statement
+}
+
+func linkChallenge(q *big.Int, ctx *big.Int, t [][]byte) *big.Int {
+	values := []*big.Int{ctx}
+	for _, bytes := range t {
+		values = append(values, bytesToInt(bytes))
+	}
+	return challenge(q, amountLinkDomain, values...)
+}
+
+// Proves that X = g^v * f^sigma commits to v in range [0; 2^k)
+func proveRange(random io.Reader, group proofGroup, f interface{}, v, sigma *big.Int, k int, ctx *big.Int, label string) (rangeProof, error) {
+	q := group.order()
+	if v.Sign() < 0 || v.BitLen() > k {
+		return rangeProof{}, errors.Errorf("value doesn't fit into %d bits", k)
+	}
+
+	// Randomness of bits sums up to sigma: sigma_{k-1} is chosen so that
+	// sum 2^i * sigma_i = sigma
+	sigmas := make([]*big.Int, k)
+	rest := new(big.Int).Set(sigma)
+	for i := 0; i < k-1; i++ {
+		s, err := sampleScalar(random, q)
+		if err != nil {
+			return rangeProof{}, err
+		}
+		sigmas[i] = s
+		rest.Sub(rest, new(big.Int).Lsh(s, uint(i)))
+	}
+	scale := new(big.Int).ModInverse(new(big.Int).Lsh(big.NewInt(1), uint(k-1)), q)
+	sigmas[k-1] = rest.Mul(rest, scale)
+	sigmas[k-1].Mod(sigmas[k-1], q)
+
+	g := group.baseExp(big.NewInt(1))
+	proof := rangeProof{Bits: make([][]byte, k), Proofs: make([]bitCommitmentProof, k)}
+	for i := 0; i < k; i++ {
+		b := int(v.Bit(i))
+		bit := group.exp(f, sigmas[i])
+		if b == 1 {
+			bit = group.mul(bit, g)
+		}
+		proof.Bits[i] = group.marshal(bit)
+
+		// Simulate branch 1-b: A = f^z / Y^c
+		var p bitCommitmentProof
+		fake := 1 - b
+		c, err := sampleScalar(random, q)
+		if err != nil {
+			return rangeProof{}, err
+		}
+		z, err := sampleScalar(random, q)
+		if err != nil {
+			return rangeProof{}, err
+		}
+		y := bitBranchValue(group, bit, g, fake)
+		p.A[fake] = group.marshal(group.div(group.exp(f, z), group.exp(y, c)))
+		p.C[fake], p.Z[fake] = c, z
+
+		// Real branch b
+		w, err := sampleScalar(random, q)
+		if err != nil {
+			return rangeProof{}, err
+		}
+		p.A[b] = group.marshal(group.exp(f, w))
+		total := rangeBitChallenge(q, ctx, label, i, proof.Bits[i], p.A)
+		p.C[b] = new(big.Int).Sub(total, c)
+		p.C[b].Mod(p.C[b], q)
+		p.Z[b] = new(big.Int).Mul(p.C[b], sigmas[i])
+		p.Z[b].Add(p.Z[b], w)
+		p.Z[b].Mod(p.Z[b], q)
+
+		proof.Proofs[i] = p
+	}
+	return proof, nil
+}
+
+// Verifies that x commits to value in range [0; 2^k)
+func verifyRange(group proofGroup, f interface{}, x interface{}, proof rangeProof, k int, ctx *big.Int, label string) error {
+	if len(proof.Bits) != k || len(proof.Proofs) != k {
+		return errors.Errorf("expected range proof of %d bits", k)
+	}
+	q := group.order()
+	g := group.baseExp(big.NewInt(1))
+
+	bits := make([]interface{}, k)
+	for i, bytes := range proof.Bits {
+		bit, err := group.unmarshal(bytes)
+		if err != nil {
+			return errors.Wrapf(err, "commitment to bit %d is malformed", i)
+		}
+		bits[i] = bit
+
+		p := proof.Proofs[i]
+		if p.C[0] == nil || p.C[1] == nil || p.Z[0] == nil || p.Z[1] == nil {
+			return errors.Errorf("proof of bit %d is malformed", i)
+		}
+		total := rangeBitChallenge(q, ctx, label, i, bytes, p.A)
+		sum := new(big.Int).Add(p.C[0], p.C[1])
+		if sum.Mod(sum, q).Cmp(total) != 0 {
+			return errors.Errorf("proof of bit %d has wrong challenge", i)
+		}
+		for b := 0; b < 2; b++ {
+			a, err := group.unmarshal(p.A[b])
+			if err != nil {
+				return errors.Wrapf(err, "proof of bit %d is malformed", i)
+			}
+			y := bitBranchValue(group, bit, g, b)
+			lhs := group.marshal(group.exp(f, p.Z[b]))
+			rhs := group.marshal(group.mul(a, group.exp(y, p.C[b])))
+			if string(lhs) != string(rhs) {
+				return errors.Errorf("bit %d isn't 0 or 1", i)
+			}
+		}
+	}
+
+	// Horner's scheme: prod bits_i^(2^i)
+	product := bits[k-1]
+	for i := k - 2; i >= 0; i-- {
+		product = group.mul(group.exp(product, big.NewInt(2)), bits[i])
+	}
+	if string(group.marshal(product)) != string(group.marshal(x)) {
+		return errors.New("bits don't make up committed value")
+	}
+	return nil
+}
+
+func rangeBitChallenge(q *big.Int, ctx *big.Int, label string, i int, bit []byte, a [2][]byte) *big.Int {
+	return challenge(q, amountRangeDomain+label, ctx, big.NewInt(int64(i)), bytesToInt(bit), bytesToInt(a[0]), bytesToInt(a[1]))
+}
+
+// Returns amount of bits k such that bound - 1 < 2^k
+func amountBits(bound int64) int {
+	k := big.NewInt(bound - 1).BitLen()
+	if k == 0 {
+		return 1
+	}
+	return k
+}
+
+// Hashes context and bound together with mpk vector, ciphertext, one-hot
+// ciphertext and commitment, so proof can't be moved to another ciphertext
+func amountContext(group proofGroup, context []byte, bound int64, h, ciphertext, u []interface{}, commitment interface{}) *big.Int {
+	var encodedBound [8]byte
+	binary.BigEndian.PutUint64(encodedBound[:], uint64(bound))
+	elements := append(append(append([]interface{}{}, ciphertext...), u...), commitment)
+	return oneHotContext(group, append(append([]byte{}, context...), encodedBound[:]...), h, elements)
+}
+
+// Returns generator f of the group derived by hashing context, so nobody
+// knows its discrete logarithm to the base g
+func pedersenGenerator(group proofGroup, context []byte) (interface{}, error) {
+	switch group := group.(type) {
+	case zpGroup:
+		// Squares modulo safe prime p form the subgroup of order q
+		stream := NewSeededReader(context, amountGeneratorDomain)
+		buf := make([]byte, (group.p.BitLen()+7)/8+8)
+		for {
+			if _, err := io.ReadFull(stream, buf); err != nil {
+				return nil, errors.Wrap(err, "hash to group")
+			}
+			x := new(big.Int).SetBytes(buf)
+			x.Mod(x, group.p)
+			x.Mul(x, x).Mod(x, group.p)
+			if x.Cmp(big.NewInt(1)) > 0 {
+				return x, nil
+			}
+		}
+	case g1Group:
+		f, err := bn256.HashG1(amountGeneratorDomain + " " + hex.EncodeToString(context))
+		return f, errors.Wrap(err, "hash to group")
+	default:
+		return nil, errors.New("group doesn't support commitments to amounts")
+	}
+}
@@ -0,0 +1,143 @@
+package gofe
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+func TestAmountProofs(t *testing.T) {
+	forEachScheme(t, func(t *testing.T, scheme Scheme) {
+		if _, ok := scheme.(AmountScheme); !ok {
+			t.Skip("scheme can't carry amounts")
+		}
+		mpk, sk, err := GenerateMasterKeysWith(scheme, 3)
+		assert.NoError(t, err, "keygen failed")
+		mpk.MaxAmount = 100
+		assert.NoError(t, ValidateMPK(mpk), "validate mpk allowing amounts")
+
+		// Party 1 receives 1 and 100, party 2 receives 37
+		var previous *data.Ciphertext
+		var chain []*data.Round
+		for _, signal := range []struct {
+			i      int
+			amount int64
+		}{{0, 1}, {1, 37}, {0, 100}} {
+			delta, proof, err := EncryptAmountFrom(rand.Reader, mpk, signal.i, signal.amount)
+			assert.NoError(t, err, "encrypt amount", signal.amount)
+			ciphertext := delta.Copy()
+			if previous != nil {
+				ciphertext = previous.Copy()
+				assert.NoError(t, Accumulate(mpk, ciphertext, &delta), "accumulate")
+			}
+			round := &data.Round{Ciphertext: *ciphertext, Delta: &delta, Proof: proof}
+			assert.NoError(t, VerifyRound(mpk, previous, round), "verify round carrying", signal.amount)
+			chain = append(chain, round)
+			previous = &round.Ciphertext
+		}
+
+		for j, expected := range []int64{101, 37, 0} {
+			v, err := Decrypt(mpk, sk[j], previous)
+			assert.NoError(t, err, "decrypt using sk", j)
+			assert.Equal(t, big.NewInt(expected), v, "wrong total amount using sk", j)
+		}
+
+		t.Run("Out of range amounts are refused", func(t *testing.T) {
+			for _, amount := range []int64{0, -1, 101} {
+				_, _, err := EncryptAmountFrom(rand.Reader, mpk, 0, amount)
+				assert.Error(t, err, "amount %d encrypted", amount)
+			}
+		})
+
+		t.Run("Signals default to amount 1", func(t *testing.T) {
+			delta, proof, err := EncryptSignal(mpk, 2)
+			assert.NoError(t, err, "encrypt signal")
+			assert.NoError(t, VerifyRound(mpk, nil, &data.Round{Ciphertext: delta, Delta: &delta, Proof: proof}))
+			v, err := Decrypt(mpk, sk[2], &delta)
+			assert.NoError(t, err)
+			assert.Equal(t, big.NewInt(1), v)
+		})
+
+		t.Run("One-hot proof isn't accepted as amount proof", func(t *testing.T) {
+			withoutAmounts := mpk
+			withoutAmounts.MaxAmount = 0
+			delta, proof, err := EncryptSignal(withoutAmounts, 0)
+			assert.NoError(t, err, "encrypt signal")
+			err = VerifyRound(mpk, nil, &data.Round{Ciphertext: delta, Delta: &delta, Proof: proof})
+			assert.Error(t, err)
+		})
+
+		t.Run("Proof of another round", func(t *testing.T) {
+			round := *chain[1]
+			round.Proof = chain[2].Proof
+			assert.Error(t, VerifyRound(mpk, &chain[0].Ciphertext, &round))
+		})
+
+		t.Run("Missing proof", func(t *testing.T) {
+			round := *chain[0]
+			round.Proof = nil
+			assert.Error(t, VerifyRound(mpk, nil, &round))
+		})
+	})
+}
+
+func TestAmountProofsRejectAmountsAboveBound(t *testing.T) {
+	mpk, _, err := GenerateMasterKeysWith(DefaultScheme, 3)
+	assert.NoError(t, err, "keygen failed")
+	zp, zpH, zpContext, err := ddhProofGroup(mpk)
+	assert.NoError(t, err)
+
+	ecMPK, _, err := GenerateMasterKeysWith(ECDDH{Bound: big.NewInt(1024)}, 3)
+	assert.NoError(t, err, "keygen failed")
+	_, points, err := decodeECDDHMPK(ecMPK)
+	assert.NoError(t, err)
+
+	groups := map[string]struct {
+		group   proofGroup
+		h       []interface{}
+		context []byte
+	}{
+		"zp": {zp, zpH, zpContext},
+		"g1": {g1Group{}, g1Elements(points), []byte(ECDDHSchemeID)},
+	}
+	for name, g := range groups {
+		t.Run(name, func(t *testing.T) {
+			// Both bounds need 7 bits, so proofs differ only in the bound
+			ciphertext, proof, err := encryptAmount(rand.Reader, g.group, g.h, 1, 101, 101, g.context)
+			assert.NoError(t, err, "encrypt amount")
+			assert.NoError(t, verifyAmount(g.group, g.h, ciphertext, 101, proof, g.context))
+			assert.Error(t, verifyAmount(g.group, g.h, ciphertext, 100, proof, g.context), "amount above bound accepted")
+
+			_, _, err = encryptAmount(rand.Reader, g.group, g.h, 1, 0, 101, g.context)
+			assert.Error(t, err, "amount 0 encrypted")
+		})
+	}
+}
+
+func TestEpochLengthWithAmounts(t *testing.T) {
+	mpk, _, err := GenerateMasterKeysWith(DefaultScheme, 2)
+	assert.NoError(t, err, "keygen failed")
+
+	capacity, err := DefaultScheme.(BoundedScheme).Capacity(mpk)
+	assert.NoError(t, err)
+
+	mpk.MaxAmount = 100
+	length, err := EpochLength(mpk)
+	assert.NoError(t, err)
+	assert.Equal(t, int(capacity.Int64()/100), length, "capacity isn't split by max amount")
+
+	mpk.MaxAmount = capacity.Int64() + 1
+	_, err = EpochLength(mpk)
+	assert.Error(t, err, "max amount above capacity accepted")
+	assert.Error(t, ValidateMPK(mpk), "mpk without capacity for amounts accepted")
+
+	mpk.MaxAmount = -1
+	assert.Error(t, ValidateMPK(mpk), "negative max amount accepted")
+
+	paillier := data.MPK{Scheme: PaillierSchemeID, L: 2, MaxAmount: 10}
+	assert.Error(t, ValidateMPK(paillier), "scheme which can't carry amounts accepted")
+}
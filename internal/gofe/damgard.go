@@ -0,0 +1,362 @@
+package gofe
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"math/big"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/innerprod/fullysec"
+	"github.com/pkg/errors"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+const DamgardSchemeID = "damgard"
+
+// Damgard is a Scheme based on fullysec.Damgard (fully secure scheme under
+// DDH assumption)
+type Damgard struct {
+	// Bit length of modulus P
+	ModulusLength int
+	// Bound of plaintext coordinates
+	Bound *big.Int
+}
+
+type damgardMPK struct {
+	Params *fullysec.DamgardParams
+	Vector gofe.Vector
+}
+
+func init() {
+	RegisterScheme(Damgard{ModulusLength: 512, Bound: big.NewInt(1024)})
+}
+
+func (Damgard) ID() string {
+	return DamgardSchemeID
+}
+
+func (s Damgard) Setup(parties int) (data.MPK, data.MSK, error) {
+	damgard, err := fullysec.NewDamgard(parties, s.ModulusLength, s.Bound)
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "scheme could not be properly configured")
+	}
+
+	msk, mpk, err := damgard.GenerateMasterKeys()
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "generate master key")
+	}
+
+	mpkJSON, err := json.Marshal(damgardMPK{Params: damgard.Params, Vector: mpk})
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "encode mpk")
+	}
+	mskJSON, err := json.Marshal(msk)
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "encode msk")
+	}
+
+	return data.MPK{Scheme: DamgardSchemeID, L: parties, Key: mpkJSON},
+		data.MSK{Scheme: DamgardSchemeID, Key: mskJSON},
+		nil
+}
+
+var _ SeedableScheme = Damgard{}
+
+func (s Damgard) SetupFrom(random io.Reader, parties int) (data.MPK, data.MSK, error) {
+	ddhParams, err := generateDDHParams(random, parties, s.ModulusLength, s.Bound)
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "scheme could not be properly configured")
+	}
+	params := &fullysec.DamgardParams{L: parties, Bound: s.Bound, G: ddhParams.G, P: ddhParams.P, Q: ddhParams.Q}
+
+	// Second generator is sampled the same way fullysec.NewDamgard does
+	one := big.NewInt(1)
+	pMinus1 := new(big.Int).Sub(params.P, one)
+	for {
+		r, err := sampleRange(random, big.NewInt(2), params.Q)
+		if err != nil {
+			return data.MPK{}, data.MSK{}, errors.Wrap(err, "sample second generator")
+		}
+		h := new(big.Int).Exp(params.G, r, params.P)
+		hInv := new(big.Int).ModInverse(h, params.P)
+		if new(big.Int).Mod(pMinus1, h).Sign() == 0 || new(big.Int).Mod(pMinus1, hInv).Sign() == 0 {
+			continue
+		}
+		params.H = h
+		break
+	}
+
+	msk := &fullysec.DamgardSecKey{S: make(gofe.Vector, parties), T: make(gofe.Vector, parties)}
+	mpk := make(gofe.Vector, parties)
+	for i := range mpk {
+		if msk.S[i], err = sampleRange(random, big.NewInt(2), params.Q); err != nil {
+			return data.MPK{}, data.MSK{}, errors.Wrap(err, "generate master key")
+		}
+		if msk.T[i], err = sampleRange(random, big.NewInt(2), params.Q); err != nil {
+			return data.MPK{}, data.MSK{}, errors.Wrap(err, "generate master key")
+		}
+		gs := new(big.Int).Exp(params.G, msk.S[i], params.P)
+		ht := new(big.Int).Exp(params.H, msk.T[i], params.P)
+		mpk[i] = gs.Mul(gs, ht).Mod(gs, params.P)
+	}
+
+	mpkJSON, err := json.Marshal(damgardMPK{Params: params, Vector: mpk})
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "encode mpk")
+	}
+	mskJSON, err := json.Marshal(msk)
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "encode msk")
+	}
+
+	return data.MPK{Scheme: DamgardSchemeID, L: parties, Key: mpkJSON},
+		data.MSK{Scheme: DamgardSchemeID, Key: mskJSON},
+		nil
+}
+
+func (Damgard) DeriveKey(mpk data.MPK, msk data.MSK, i int) (data.RecipientSecretKey, error) {
+	damgard, _, err := decodeDamgardMPK(mpk)
+	if err != nil {
+		return data.RecipientSecretKey{}, err
+	}
+	if msk.Scheme != DamgardSchemeID {
+		return data.RecipientSecretKey{}, errors.Errorf("expected %s msk, got %s", DamgardSchemeID, msk.Scheme)
+	}
+	var key fullysec.DamgardSecKey
+	if err := json.Unmarshal(msk.Key, &key); err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "decode msk")
+	}
+
+	sk, err := damgard.DeriveKey(&key, unitVector(mpk.L, i))
+	if err != nil {
+		return data.RecipientSecretKey{}, err
+	}
+	skJSON, err := json.Marshal(sk)
+	if err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "encode derived key")
+	}
+	return data.RecipientSecretKey{I: i, DerivedKey: skJSON}, nil
+}
+
+func (s Damgard) Encrypt(mpk data.MPK, x gofe.Vector) (data.Ciphertext, error) {
+	return s.EncryptFrom(rand.Reader, mpk, x)
+}
+
+// Encrypts x as ct_0 = g^r, ct_1 = h^r, ct_i = h_i^r * g^x_i, the same way
+// fullysec.Damgard does
+func (Damgard) EncryptFrom(random io.Reader, mpk data.MPK, x gofe.Vector) (data.Ciphertext, error) {
+	damgard, vector, err := decodeDamgardMPK(mpk)
+	if err != nil {
+		return data.Ciphertext{}, err
+	}
+	params := damgard.Params
+	if len(x) != mpk.L {
+		return data.Ciphertext{}, errors.New("plaintext has wrong length")
+	}
+	if err := x.CheckBound(params.Bound); err != nil {
+		return data.Ciphertext{}, err
+	}
+	r, err := sampleRange(random, big.NewInt(2), params.Q)
+	if err != nil {
+		return data.Ciphertext{}, errors.Wrap(err, "sample randomness")
+	}
+
+	ciphertext := make(gofe.Vector, len(x)+2)
+	ciphertext[0] = new(big.Int).Exp(params.G, r, params.P)
+	ciphertext[1] = new(big.Int).Exp(params.H, r, params.P)
+	for i, xi := range x {
+		hr := new(big.Int).Exp(vector[i], r, params.P)
+		gx := new(big.Int).Exp(params.G, xi, params.P)
+		ciphertext[i+2] = hr.Mul(hr, gx).Mod(hr, params.P)
+	}
+	return data.Ciphertext{Vector: ciphertext}, nil
+}
+
+// Multiplies ciphertexts element-wise modulo P
+func (Damgard) Accumulate(mpk data.MPK, acc *data.Ciphertext, delta *data.Ciphertext) error {
+	damgard, _, err := decodeDamgardMPK(mpk)
+	if err != nil {
+		return err
+	}
+	return acc.Mul(delta, damgard.Params.P)
+}
+
+func (s Damgard) Decrypt(mpk data.MPK, sk data.RecipientSecretKey, ciphertext *data.Ciphertext) (*big.Int, error) {
+	return decryptDlog(s, mpk, sk, ciphertext, nil)
+}
+
+func decodeDamgardMPK(mpk data.MPK) (*fullysec.Damgard, gofe.Vector, error) {
+	if mpk.Scheme != DamgardSchemeID {
+		return nil, nil, errors.Errorf("expected %s mpk, got %s", DamgardSchemeID, mpk.Scheme)
+	}
+	var key damgardMPK
+	if err := json.Unmarshal(mpk.Key, &key); err != nil {
+		return nil, nil, errors.Wrap(err, "decode mpk")
+	}
+	if key.Params == nil || key.Params.L != mpk.L || len(key.Vector) != mpk.L {
+		return nil, nil, errors.New("malformed mpk")
+	}
+	return fullysec.NewDamgardFromParams(key.Params), key.Vector, nil
+}
+
+var _ ValidatingScheme = Damgard{}
+
+func (Damgard) ValidateMPK(mpk data.MPK) error {
+	damgard, h, err := decodeDamgardMPK(mpk)
+	if err != nil {
+		return err
+	}
+	params := damgard.Params
+	if err := validateSchnorrGroup(params.P, params.Q, params.G); err != nil {
+		return err
+	}
+	if err := validateSubgroupElement(params.H, params.P, params.Q); err != nil {
+		return errors.Wrap(err, "second generator")
+	}
+	if err := validateBound(params.Bound, params.L, params.Q); err != nil {
+		return err
+	}
+	for i, x := range h {
+		if err := validateSubgroupElement(x, params.P, params.Q); err != nil {
+			return errors.Wrapf(err, "element %d", i)
+		}
+	}
+	return nil
+}
+
+// Ciphertext consists of g^r, h^r and L masked slots
+func (Damgard) ValidateCiphertext(mpk data.MPK, ciphertext *data.Ciphertext) error {
+	damgard, _, err := decodeDamgardMPK(mpk)
+	if err != nil {
+		return err
+	}
+	if len(ciphertext.Points) != 0 {
+		return errors.New("expected vector, got points")
+	}
+	return validateSubgroupVector(ciphertext.Vector, mpk.L+2, damgard.Params.P, damgard.Params.Q)
+}
+
+var _ VerifiableKeyScheme = Damgard{}
+
+// Key of i-th recipient is (s_i, t_i), so it must satisfy g^s_i * h^t_i = h_i
+func (Damgard) VerifyKey(mpk data.MPK, sk data.RecipientSecretKey) error {
+	damgard, h, err := decodeDamgardMPK(mpk)
+	if err != nil {
+		return err
+	}
+	var key fullysec.DamgardDerivedKey
+	if err := json.Unmarshal(sk.DerivedKey, &key); err != nil {
+		return errors.Wrap(err, "decode derived key")
+	}
+	if key.Key1 == nil || key.Key2 == nil {
+		return errors.New("malformed derived key")
+	}
+
+	params := damgard.Params
+	group := zpGroup{p: params.P, q: params.Q, g: params.G}
+	expected := group.mul(group.exp(params.G, key.Key1), group.exp(params.H, key.Key2))
+	if expected.(*big.Int).Cmp(h[sk.I]) != 0 {
+		return errors.Errorf("key doesn't match element %d of mpk", sk.I)
+	}
+	return nil
+}
+
+var _ ThresholdScheme = Damgard{}
+
+func (Damgard) MasterKeyVectors(mpk data.MPK, msk data.MSK) ([]gofe.Vector, *big.Int, error) {
+	damgard, _, err := decodeDamgardMPK(mpk)
+	if err != nil {
+		return nil, nil, err
+	}
+	if msk.Scheme != DamgardSchemeID {
+		return nil, nil, errors.Errorf("expected %s msk, got %s", DamgardSchemeID, msk.Scheme)
+	}
+	var key fullysec.DamgardSecKey
+	if err := json.Unmarshal(msk.Key, &key); err != nil {
+		return nil, nil, errors.Wrap(err, "decode msk")
+	}
+	return []gofe.Vector{key.S, key.T}, damgard.Params.Q, nil
+}
+
+func (Damgard) MasterKeyModulus(mpk data.MPK) (*big.Int, error) {
+	damgard, _, err := decodeDamgardMPK(mpk)
+	if err != nil {
+		return nil, err
+	}
+	return damgard.Params.Q, nil
+}
+
+// For y = e_i, derived key is (<s, y>, <t, y>) = (s_i, t_i)
+func (Damgard) EncodeDerivedKey(_ data.MPK, i int, key gofe.Vector) (data.RecipientSecretKey, error) {
+	if len(key) != 2 {
+		return data.RecipientSecretKey{}, errors.New("expected key of 2 elements")
+	}
+	skJSON, err := json.Marshal(fullysec.DamgardDerivedKey{Key1: key[0], Key2: key[1]})
+	if err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "encode derived key")
+	}
+	return data.RecipientSecretKey{I: i, DerivedKey: skJSON}, nil
+}
+
+var _ DlogScheme = Damgard{}
+
+func (Damgard) DlogParams(mpk data.MPK) (DlogParams, error) {
+	damgard, _, err := decodeDamgardMPK(mpk)
+	if err != nil {
+		return DlogParams{}, err
+	}
+	return zpDlogParams(damgard.Params.P, damgard.Params.Q, damgard.Params.G, damgard.Params.Bound, mpk.L), nil
+}
+
+// Computes g^v = ct_{i+2} / (ct_0^key1 * ct_1^key2)
+func (Damgard) DecryptElement(mpk data.MPK, sk data.RecipientSecretKey, ciphertext *data.Ciphertext) ([]byte, error) {
+	damgard, _, err := decodeDamgardMPK(mpk)
+	if err != nil {
+		return nil, err
+	}
+	var key fullysec.DamgardDerivedKey
+	if err := json.Unmarshal(sk.DerivedKey, &key); err != nil {
+		return nil, errors.Wrap(err, "decode derived key")
+	}
+	if key.Key1 == nil || key.Key2 == nil {
+		return nil, errors.New("malformed derived key")
+	}
+	if len(ciphertext.Vector) != mpk.L+2 || sk.I < 0 || sk.I >= mpk.L {
+		return nil, errors.New("malformed ciphertext")
+	}
+	if ciphertext.Vector[0] == nil || ciphertext.Vector[1] == nil {
+		return nil, errors.New("malformed ciphertext")
+	}
+
+	group := zpGroup{p: damgard.Params.P, q: damgard.Params.Q, g: damgard.Params.G}
+	mask := group.mul(group.exp(ciphertext.Vector[0], key.Key1), group.exp(ciphertext.Vector[1], key.Key2))
+	gv, err := zpDiv(group, ciphertext.Vector[sk.I+2], mask.(*big.Int))
+	if err != nil {
+		return nil, err
+	}
+	return group.marshal(gv), nil
+}
+
+var _ ConfigurableScheme = Damgard{}
+
+func (Damgard) Configure(profile Profile) (Scheme, data.Profile, error) {
+	scheme := Damgard{ModulusLength: profile.ModulusBits, Bound: innerProductBound(profile.MaxSignals)}
+	return scheme, data.Profile{
+		ModulusBits:  profile.ModulusBits,
+		SecurityBits: modulusSecurityBits(profile.ModulusBits),
+		MaxSignals:   profile.MaxSignals,
+	}, nil
+}
+
+var _ BoundedScheme = Damgard{}
+
+// Capacity is the bound of discrete logarithm solved by Decrypt
+func (s Damgard) Capacity(mpk data.MPK) (*big.Int, error) {
+	params, err := s.DlogParams(mpk)
+	if err != nil {
+		return nil, err
+	}
+	return params.Bound, nil
+}
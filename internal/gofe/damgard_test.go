@@ -0,0 +1,63 @@
+package gofe
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/innerprod/fullysec"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+func TestDamgardRefusesMalformedInput(t *testing.T) {
+	scheme, err := LookupScheme(DamgardSchemeID)
+	assert.NoError(t, err)
+	mpk, sk, err := GenerateMasterKeysWith(scheme, 3)
+	assert.NoError(t, err, "keygen failed")
+	damgard, _, err := decodeDamgardMPK(mpk)
+	if err != nil {
+		panic(err)
+	}
+	params := damgard.Params
+
+	_, err = Encrypt(mpk, unitVector(2, 0))
+	assert.Error(t, err, "encrypted plaintext of wrong length")
+	beyondBound := gofe.NewConstantVector(3, big.NewInt(0))
+	beyondBound[1] = new(big.Int).Add(params.Bound, big.NewInt(1))
+	_, err = Encrypt(mpk, beyondBound)
+	assert.Error(t, err, "encrypted plaintext beyond bound")
+
+	ciphertext, err := Encrypt(mpk, unitVector(3, 1))
+	assert.NoError(t, err, "encrypt")
+	for name, element := range map[string]*big.Int{
+		// Negated element is out of subgroup of quadratic residues
+		"outside subgroup": new(big.Int).Sub(params.P, ciphertext.Vector[1]),
+		"not invertible":   big.NewInt(0),
+		"out of range":     new(big.Int).Add(ciphertext.Vector[1], params.P),
+	} {
+		tampered := ciphertext.Copy()
+		tampered.Vector[1] = element
+		assert.Error(t, ValidateCiphertext(mpk, tampered), "element %s accepted", name)
+	}
+
+	var key fullysec.DamgardDerivedKey
+	if err := json.Unmarshal(sk[0].DerivedKey, &key); err != nil {
+		panic(err)
+	}
+	for name, tamper := range map[string]func(key *fullysec.DamgardDerivedKey){
+		"first part is changed":  func(key *fullysec.DamgardDerivedKey) { key.Key1 = new(big.Int).Add(key.Key1, big.NewInt(1)) },
+		"second part is changed": func(key *fullysec.DamgardDerivedKey) { key.Key2 = new(big.Int).Add(key.Key2, big.NewInt(1)) },
+		"second part is missing": func(key *fullysec.DamgardDerivedKey) { key.Key2 = nil },
+	} {
+		tampered := key
+		tamper(&tampered)
+		encoded, err := json.Marshal(tampered)
+		if err != nil {
+			panic(err)
+		}
+		assert.Error(t, VerifyKey(mpk, data.RecipientSecretKey{I: 0, DerivedKey: encoded}, 0), "key whose %s accepted", name)
+	}
+}
@@ -0,0 +1,588 @@
+package gofe
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"math/big"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/innerprod/simple"
+	"github.com/pkg/errors"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+const DDHSchemeID = "ddh"
+
+// DDH is a Scheme based on simple.DDH (selectively secure scheme under
+// DDH assumption)
+type DDH struct {
+	// Bit length of modulus P
+	ModulusLength int
+	// Bound of plaintext coordinates
+	Bound *big.Int
+}
+
+type ddhMPK struct {
+	Params *simple.DDHParams
+	Vector gofe.Vector
+}
+
+type ddhMSK struct {
+	Vector gofe.Vector
+}
+
+var _ DistributedScheme = DDH{}
+
+func init() {
+	RegisterScheme(DefaultScheme)
+}
+
+func (DDH) ID() string {
+	return DDHSchemeID
+}
+
+func (s DDH) Setup(parties int) (data.MPK, data.MSK, error) {
+	ddh, err := simple.NewDDH(parties, s.ModulusLength, s.Bound)
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "scheme could not be properly configured")
+	}
+	return setupDDH(ddh)
+}
+
+var _ SeedableScheme = DDH{}
+
+func (s DDH) SetupFrom(random io.Reader, parties int) (data.MPK, data.MSK, error) {
+	params, err := generateDDHParams(random, parties, s.ModulusLength, s.Bound)
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "scheme could not be properly configured")
+	}
+	return setupDDHFrom(random, simple.NewDDHFromParams(params))
+}
+
+// Generates parameters the same way simple.NewDDH does, but draws all the
+// randomness from given source
+func generateDDHParams(random io.Reader, parties, modulusLength int, bound *big.Int) (*simple.DDHParams, error) {
+	p, q, err := generateSafePrime(random, modulusLength)
+	if err != nil {
+		return nil, err
+	}
+	// Inner product of vectors bounded by `bound` must be below q
+	if new(big.Int).Mul(big.NewInt(int64(2*parties)), new(big.Int).Mul(bound, bound)).Cmp(q) > 0 {
+		return nil, errors.New("bound and l are too big for the group")
+	}
+
+	one, two := big.NewInt(1), big.NewInt(2)
+	pMinus1 := new(big.Int).Sub(p, one)
+	for {
+		g, err := sampleRange(random, big.NewInt(3), p)
+		if err != nil {
+			return nil, errors.Wrap(err, "sample generator")
+		}
+		// Square is a quadratic residue, i.e. it generates subgroup of order q
+		g.Exp(g, two, p)
+		gInv := new(big.Int).ModInverse(g, p)
+		// The same checks against known attacks as keygen of gofe does
+		if new(big.Int).Mod(pMinus1, g).Sign() == 0 || new(big.Int).Mod(pMinus1, gInv).Sign() == 0 {
+			continue
+		}
+		return &simple.DDHParams{L: parties, Bound: bound, G: g, P: p, Q: q}, nil
+	}
+}
+
+func setupDDH(ddh *simple.DDH) (data.MPK, data.MSK, error) {
+	return setupDDHFrom(rand.Reader, ddh)
+}
+
+func setupDDHFrom(random io.Reader, ddh *simple.DDH) (data.MPK, data.MSK, error) {
+	params := ddh.Params
+	msk := make(gofe.Vector, params.L)
+	mpk := make(gofe.Vector, params.L)
+	for i := range msk {
+		s, err := sampleRange(random, big.NewInt(2), params.Q)
+		if err != nil {
+			return data.MPK{}, data.MSK{}, errors.Wrap(err, "generate master key")
+		}
+		msk[i] = s
+		mpk[i] = new(big.Int).Exp(params.G, s, params.P)
+	}
+
+	mpkJSON, err := json.Marshal(ddhMPK{Params: ddh.Params, Vector: mpk})
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "encode mpk")
+	}
+	mskJSON, err := json.Marshal(ddhMSK{Vector: msk})
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "encode msk")
+	}
+
+	return data.MPK{Scheme: DDHSchemeID, L: ddh.Params.L, Key: mpkJSON},
+		data.MSK{Scheme: DDHSchemeID, Key: mskJSON},
+		nil
+}
+
+func (DDH) DeriveKey(mpk data.MPK, msk data.MSK, i int) (data.RecipientSecretKey, error) {
+	ddh, _, err := decodeDDHMPK(mpk)
+	if err != nil {
+		return data.RecipientSecretKey{}, err
+	}
+	if msk.Scheme != DDHSchemeID {
+		return data.RecipientSecretKey{}, errors.Errorf("expected %s msk, got %s", DDHSchemeID, msk.Scheme)
+	}
+	var key ddhMSK
+	if err := json.Unmarshal(msk.Key, &key); err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "decode msk")
+	}
+
+	sk, err := ddh.DeriveKey(key.Vector, unitVector(mpk.L, i))
+	if err != nil {
+		return data.RecipientSecretKey{}, err
+	}
+	skJSON, err := json.Marshal(sk)
+	if err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "encode derived key")
+	}
+	return data.RecipientSecretKey{I: i, DerivedKey: skJSON}, nil
+}
+
+func (s DDH) Encrypt(mpk data.MPK, x gofe.Vector) (data.Ciphertext, error) {
+	return s.EncryptFrom(rand.Reader, mpk, x)
+}
+
+// Encrypts x as ct_0 = g^r, ct_i = h_i^r * g^x_i, the same way simple.DDH
+// does
+func (DDH) EncryptFrom(random io.Reader, mpk data.MPK, x gofe.Vector) (data.Ciphertext, error) {
+	ddh, vector, err := decodeDDHMPK(mpk)
+	if err != nil {
+		return data.Ciphertext{}, err
+	}
+	params := ddh.Params
+	if len(x) != mpk.L {
+		return data.Ciphertext{}, errors.New("plaintext has wrong length")
+	}
+	if err := x.CheckBound(params.Bound); err != nil {
+		return data.Ciphertext{}, err
+	}
+	r, err := sampleRange(random, big.NewInt(2), params.Q)
+	if err != nil {
+		return data.Ciphertext{}, errors.Wrap(err, "sample randomness")
+	}
+
+	ciphertext := make(gofe.Vector, len(x)+1)
+	ciphertext[0] = new(big.Int).Exp(params.G, r, params.P)
+	for i, xi := range x {
+		hr := new(big.Int).Exp(vector[i], r, params.P)
+		// Negative exponent is computed via modular inverse
+		gx := new(big.Int).Exp(params.G, xi, params.P)
+		ciphertext[i+1] = hr.Mul(hr, gx).Mod(hr, params.P)
+	}
+	return data.Ciphertext{Vector: ciphertext}, nil
+}
+
+// Multiplies ciphertexts element-wise modulo P
+func (DDH) Accumulate(mpk data.MPK, acc *data.Ciphertext, delta *data.Ciphertext) error {
+	ddh, _, err := decodeDDHMPK(mpk)
+	if err != nil {
+		return err
+	}
+	return acc.Mul(delta, ddh.Params.P)
+}
+
+func (s DDH) Decrypt(mpk data.MPK, sk data.RecipientSecretKey, ciphertext *data.Ciphertext) (*big.Int, error) {
+	return decryptDlog(s, mpk, sk, ciphertext, nil)
+}
+
+func decodeDDHMPK(mpk data.MPK) (*simple.DDH, gofe.Vector, error) {
+	if mpk.Scheme != DDHSchemeID {
+		return nil, nil, errors.Errorf("expected %s mpk, got %s", DDHSchemeID, mpk.Scheme)
+	}
+	var key ddhMPK
+	if err := json.Unmarshal(mpk.Key, &key); err != nil {
+		return nil, nil, errors.Wrap(err, "decode mpk")
+	}
+	if key.Params == nil || key.Params.L != mpk.L || len(key.Vector) != mpk.L {
+		return nil, nil, errors.New("malformed mpk")
+	}
+	return simple.NewDDHFromParams(key.Params), key.Vector, nil
+}
+
+var _ ValidatingScheme = DDH{}
+
+func (DDH) ValidateMPK(mpk data.MPK) error {
+	ddh, h, err := decodeDDHMPK(mpk)
+	if err != nil {
+		return err
+	}
+	params := ddh.Params
+	if err := validateSchnorrGroup(params.P, params.Q, params.G); err != nil {
+		return err
+	}
+	if err := validateBound(params.Bound, params.L, params.Q); err != nil {
+		return err
+	}
+	for i, x := range h {
+		if err := validateSubgroupElement(x, params.P, params.Q); err != nil {
+			return errors.Wrapf(err, "element %d", i)
+		}
+	}
+	return nil
+}
+
+func (DDH) ValidateCiphertext(mpk data.MPK, ciphertext *data.Ciphertext) error {
+	ddh, _, err := decodeDDHMPK(mpk)
+	if err != nil {
+		return err
+	}
+	if len(ciphertext.Points) != 0 {
+		return errors.New("expected vector, got points")
+	}
+	return validateSubgroupVector(ciphertext.Vector, mpk.L+1, ddh.Params.P, ddh.Params.Q)
+}
+
+var _ VerifiableKeyScheme = DDH{}
+
+// Key of i-th recipient is msk_i, so it must satisfy g^key = h_i
+func (DDH) VerifyKey(mpk data.MPK, sk data.RecipientSecretKey) error {
+	ddh, h, err := decodeDDHMPK(mpk)
+	if err != nil {
+		return err
+	}
+	var key big.Int
+	if err := json.Unmarshal(sk.DerivedKey, &key); err != nil {
+		return errors.Wrap(err, "decode derived key")
+	}
+	if new(big.Int).Exp(ddh.Params.G, &key, ddh.Params.P).Cmp(h[sk.I]) != 0 {
+		return errors.Errorf("key doesn't match element %d of mpk", sk.I)
+	}
+	return nil
+}
+
+// Public part of master key generated by a single recipient
+type ddhKeyPart struct {
+	// H = g^s where s is recipient's part of msk
+	H *big.Int
+	// Schnorr proof of knowledge of s
+	Commitment *big.Int
+	Response   *big.Int
+}
+
+const ddhKeyPartDomain = "pps/ddh/key-part"
+
+func (s DDH) GenerateParams(random io.Reader, parties int) (json.RawMessage, error) {
+	var params *simple.DDHParams
+	if isSystemRandom(random) {
+		ddh, err := simple.NewDDH(parties, s.ModulusLength, s.Bound)
+		if err != nil {
+			return nil, errors.Wrap(err, "scheme could not be properly configured")
+		}
+		params = ddh.Params
+	} else {
+		var err error
+		params, err = generateDDHParams(random, parties, s.ModulusLength, s.Bound)
+		if err != nil {
+			return nil, errors.Wrap(err, "scheme could not be properly configured")
+		}
+	}
+	return json.Marshal(params)
+}
+
+func (DDH) GenerateKeyPart(random io.Reader, params json.RawMessage, i int) (json.RawMessage, data.RecipientSecretKey, error) {
+	ddhParams, err := decodeDDHParams(params)
+	if err != nil {
+		return nil, data.RecipientSecretKey{}, err
+	}
+	if i < 0 || i >= ddhParams.L {
+		return nil, data.RecipientSecretKey{}, errors.Errorf("party index %d is out of range", i)
+	}
+
+	secret, err := sampleRange(random, big.NewInt(2), ddhParams.Q)
+	if err != nil {
+		return nil, data.RecipientSecretKey{}, errors.Wrap(err, "sample secret")
+	}
+	k, err := sampleRange(random, big.NewInt(2), ddhParams.Q)
+	if err != nil {
+		return nil, data.RecipientSecretKey{}, errors.Wrap(err, "sample nonce")
+	}
+
+	h := new(big.Int).Exp(ddhParams.G, secret, ddhParams.P)
+	commitment := new(big.Int).Exp(ddhParams.G, k, ddhParams.P)
+	c := challenge(ddhParams.Q, ddhKeyPartDomain, ddhParams.P, ddhParams.G, big.NewInt(int64(i)), h, commitment)
+	response := new(big.Int).Mul(c, secret)
+	response.Add(response, k)
+	response.Mod(response, ddhParams.Q)
+
+	part, err := json.Marshal(ddhKeyPart{H: h, Commitment: commitment, Response: response})
+	if err != nil {
+		return nil, data.RecipientSecretKey{}, errors.Wrap(err, "encode key part")
+	}
+	// For y = e_i, derived key is <msk, y> = s
+	sk, err := json.Marshal(secret)
+	if err != nil {
+		return nil, data.RecipientSecretKey{}, errors.Wrap(err, "encode derived key")
+	}
+	return part, data.RecipientSecretKey{I: i, DerivedKey: sk}, nil
+}
+
+func (DDH) CombineKeyParts(params json.RawMessage, parts []json.RawMessage) (data.MPK, error) {
+	ddhParams, err := decodeDDHParams(params)
+	if err != nil {
+		return data.MPK{}, err
+	}
+	if len(parts) != ddhParams.L {
+		return data.MPK{}, errors.Errorf("expected %d key parts, got %d", ddhParams.L, len(parts))
+	}
+
+	vector := make(gofe.Vector, len(parts))
+	for i, partJSON := range parts {
+		var part ddhKeyPart
+		if err := json.Unmarshal(partJSON, &part); err != nil {
+			return data.MPK{}, errors.Wrapf(err, "decode key part %d", i)
+		}
+		if part.H == nil || part.Commitment == nil || part.Response == nil {
+			return data.MPK{}, errors.Errorf("key part %d is malformed", i)
+		}
+		if err := validateSubgroupElement(part.H, ddhParams.P, ddhParams.Q); err != nil {
+			return data.MPK{}, errors.Wrapf(err, "key part %d", i)
+		}
+		if err := validateSubgroupElement(part.Commitment, ddhParams.P, ddhParams.Q); err != nil {
+			return data.MPK{}, errors.Wrapf(err, "commitment of key part %d", i)
+		}
+
+		// g^response == commitment * h^c
+		c := challenge(ddhParams.Q, ddhKeyPartDomain, ddhParams.P, ddhParams.G, big.NewInt(int64(i)), part.H, part.Commitment)
+		lhs := new(big.Int).Exp(ddhParams.G, part.Response, ddhParams.P)
+		rhs := new(big.Int).Exp(part.H, c, ddhParams.P)
+		rhs.Mul(rhs, part.Commitment)
+		rhs.Mod(rhs, ddhParams.P)
+		if lhs.Cmp(rhs) != 0 {
+			return data.MPK{}, errors.Errorf("key part %d has invalid proof of knowledge", i)
+		}
+		vector[i] = part.H
+	}
+
+	mpkJSON, err := json.Marshal(ddhMPK{Params: ddhParams, Vector: vector})
+	if err != nil {
+		return data.MPK{}, errors.Wrap(err, "encode mpk")
+	}
+	return data.MPK{Scheme: DDHSchemeID, L: ddhParams.L, Key: mpkJSON}, nil
+}
+
+func decodeDDHParams(params json.RawMessage) (*simple.DDHParams, error) {
+	var ddhParams simple.DDHParams
+	if err := json.Unmarshal(params, &ddhParams); err != nil {
+		return nil, errors.Wrap(err, "decode params")
+	}
+	if ddhParams.L <= 0 || ddhParams.G == nil || ddhParams.P == nil || ddhParams.Q == nil || ddhParams.Bound == nil {
+		return nil, errors.New("malformed params")
+	}
+	return &ddhParams, nil
+}
+
+var _ ThresholdScheme = DDH{}
+
+func (DDH) MasterKeyVectors(mpk data.MPK, msk data.MSK) ([]gofe.Vector, *big.Int, error) {
+	ddh, _, err := decodeDDHMPK(mpk)
+	if err != nil {
+		return nil, nil, err
+	}
+	if msk.Scheme != DDHSchemeID {
+		return nil, nil, errors.Errorf("expected %s msk, got %s", DDHSchemeID, msk.Scheme)
+	}
+	var key ddhMSK
+	if err := json.Unmarshal(msk.Key, &key); err != nil {
+		return nil, nil, errors.Wrap(err, "decode msk")
+	}
+	return []gofe.Vector{key.Vector}, ddh.Params.Q, nil
+}
+
+func (DDH) MasterKeyModulus(mpk data.MPK) (*big.Int, error) {
+	ddh, _, err := decodeDDHMPK(mpk)
+	if err != nil {
+		return nil, err
+	}
+	return ddh.Params.Q, nil
+}
+
+// For y = e_i, derived key is <msk, y> = msk_i
+func (DDH) EncodeDerivedKey(_ data.MPK, i int, key gofe.Vector) (data.RecipientSecretKey, error) {
+	if len(key) != 1 {
+		return data.RecipientSecretKey{}, errors.New("expected key of 1 element")
+	}
+	skJSON, err := json.Marshal(key[0])
+	if err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "encode derived key")
+	}
+	return data.RecipientSecretKey{I: i, DerivedKey: skJSON}, nil
+}
+
+var _ ProvableScheme = DDH{}
+
+func (DDH) EncryptOneHot(random io.Reader, mpk data.MPK, i int) (data.Ciphertext, json.RawMessage, error) {
+	group, h, context, err := ddhProofGroup(mpk)
+	if err != nil {
+		return data.Ciphertext{}, nil, err
+	}
+	elements, proof, err := encryptOneHot(random, group, h, i, context)
+	if err != nil {
+		return data.Ciphertext{}, nil, err
+	}
+
+	vector := make(gofe.Vector, len(elements))
+	for j, e := range elements {
+		vector[j] = e.(*big.Int)
+	}
+	return data.Ciphertext{Vector: vector}, proof, nil
+}
+
+func (DDH) VerifyOneHot(mpk data.MPK, ciphertext *data.Ciphertext, proof json.RawMessage) error {
+	group, h, context, err := ddhProofGroup(mpk)
+	if err != nil {
+		return err
+	}
+	elements, err := ddhCiphertextElements(group, mpk, ciphertext)
+	if err != nil {
+		return err
+	}
+	return verifyOneHot(group, h, elements, proof, context)
+}
+
+var _ AmountScheme = DDH{}
+
+func (DDH) EncryptAmount(random io.Reader, mpk data.MPK, i int, amount, bound int64) (data.Ciphertext, json.RawMessage, error) {
+	group, h, context, err := ddhProofGroup(mpk)
+	if err != nil {
+		return data.Ciphertext{}, nil, err
+	}
+	elements, proof, err := encryptAmount(random, group, h, i, amount, bound, context)
+	if err != nil {
+		return data.Ciphertext{}, nil, err
+	}
+
+	vector := make(gofe.Vector, len(elements))
+	for j, e := range elements {
+		vector[j] = e.(*big.Int)
+	}
+	return data.Ciphertext{Vector: vector}, proof, nil
+}
+
+func (DDH) VerifyAmount(mpk data.MPK, ciphertext *data.Ciphertext, bound int64, proof json.RawMessage) error {
+	group, h, context, err := ddhProofGroup(mpk)
+	if err != nil {
+		return err
+	}
+	elements, err := ddhCiphertextElements(group, mpk, ciphertext)
+	if err != nil {
+		return err
+	}
+	return verifyAmount(group, h, elements, bound, proof, context)
+}
+
+// Returns ciphertext as group elements, checking that they belong to the group
+func ddhCiphertextElements(group zpGroup, mpk data.MPK, ciphertext *data.Ciphertext) ([]interface{}, error) {
+	if len(ciphertext.Vector) != mpk.L+1 {
+		return nil, errors.New("ciphertext has wrong length")
+	}
+	elements := make([]interface{}, len(ciphertext.Vector))
+	for j, e := range ciphertext.Vector {
+		if err := validateInSubgroup(e, group.p, group.q); err != nil {
+			return nil, errors.Wrapf(err, "ciphertext element %d", j)
+		}
+		elements[j] = e
+	}
+	return elements, nil
+}
+
+// Returns group of the scheme, mpk vector as group elements and encoded
+// group parameters
+func ddhProofGroup(mpk data.MPK) (zpGroup, []interface{}, []byte, error) {
+	ddh, vector, err := decodeDDHMPK(mpk)
+	if err != nil {
+		return zpGroup{}, nil, nil, err
+	}
+	params := ddh.Params
+	group := zpGroup{p: params.P, q: params.Q, g: params.G}
+
+	h := make([]interface{}, len(vector))
+	for j, e := range vector {
+		h[j] = e
+	}
+	context := []byte(DDHSchemeID)
+	for _, x := range []*big.Int{params.P, params.Q, params.G} {
+		context = append(context, group.marshal(x)...)
+	}
+	return group, h, context, nil
+}
+
+var _ DlogScheme = DDH{}
+
+// Discrete logarithm is taken to the base G in Z_P*, inner product is bounded
+// by L * Bound^2
+func (DDH) DlogParams(mpk data.MPK) (DlogParams, error) {
+	ddh, _, err := decodeDDHMPK(mpk)
+	if err != nil {
+		return DlogParams{}, err
+	}
+	return zpDlogParams(ddh.Params.P, ddh.Params.Q, ddh.Params.G, ddh.Params.Bound, mpk.L), nil
+}
+
+// Computes g^v = ct_{i+1} / ct_0^sk
+func (DDH) DecryptElement(mpk data.MPK, sk data.RecipientSecretKey, ciphertext *data.Ciphertext) ([]byte, error) {
+	ddh, _, err := decodeDDHMPK(mpk)
+	if err != nil {
+		return nil, err
+	}
+	var key big.Int
+	if err := json.Unmarshal(sk.DerivedKey, &key); err != nil {
+		return nil, errors.Wrap(err, "decode derived key")
+	}
+	if len(ciphertext.Vector) != mpk.L+1 || sk.I < 0 || sk.I >= mpk.L {
+		return nil, errors.New("malformed ciphertext")
+	}
+
+	group := zpGroup{p: ddh.Params.P, q: ddh.Params.Q, g: ddh.Params.G}
+	mask := group.exp(ciphertext.Vector[0], &key)
+	gv, err := zpDiv(group, ciphertext.Vector[sk.I+1], mask.(*big.Int))
+	if err != nil {
+		return nil, err
+	}
+	return group.marshal(gv), nil
+}
+
+// Returns parameters of discrete logarithm to the base g in Z_p* of inner
+// product of l coordinates bounded by bound
+func zpDlogParams(p, q, g, bound *big.Int, l int) DlogParams {
+	innerProductBound := new(big.Int).Mul(bound, bound)
+	innerProductBound.Mul(innerProductBound, big.NewInt(int64(l)))
+	return DlogParams{Group: dlogGroupZp, Generator: g.Bytes(), Modulus: p, Order: q, Bound: innerProductBound}
+}
+
+// Computes a / b in Z_p*, it's an error if b isn't invertible
+func zpDiv(group zpGroup, a, b *big.Int) (*big.Int, error) {
+	if a == nil || b == nil || new(big.Int).ModInverse(b, group.p) == nil {
+		return nil, errors.New("malformed ciphertext")
+	}
+	return group.div(a, b).(*big.Int), nil
+}
+
+var _ ConfigurableScheme = DDH{}
+
+func (DDH) Configure(profile Profile) (Scheme, data.Profile, error) {
+	scheme := DDH{ModulusLength: profile.ModulusBits, Bound: innerProductBound(profile.MaxSignals)}
+	return scheme, data.Profile{
+		ModulusBits:  profile.ModulusBits,
+		SecurityBits: modulusSecurityBits(profile.ModulusBits),
+		MaxSignals:   profile.MaxSignals,
+	}, nil
+}
+
+var _ BoundedScheme = DDH{}
+
+// Capacity is the bound of discrete logarithm solved by Decrypt
+func (s DDH) Capacity(mpk data.MPK) (*big.Int, error) {
+	params, err := s.DlogParams(mpk)
+	if err != nil {
+		return nil, err
+	}
+	return params.Bound, nil
+}
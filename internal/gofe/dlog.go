@@ -0,0 +1,330 @@
+package gofe
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"path"
+
+	"github.com/fentec-project/bn256"
+	"github.com/pkg/errors"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+const (
+	dlogGroupZp = "zp"
+	dlogGroupG1 = "g1"
+	dlogGroupGT = "gt"
+
+	// Persisted table holds dlogTableFactor * sqrt(bound) baby steps, so
+	// every decryption takes that many times fewer giant steps
+	dlogTableFactor = 16
+	fingerprintSize = 8
+)
+
+// Parameters of discrete logarithm problem g^v = h where |v| <= Bound
+type DlogParams struct {
+	// Group which logarithm is taken in: "zp", "g1" or "gt"
+	Group string
+	// Generator g, encoded by the group
+	Generator []byte
+	// Modulus p of Z_p*, it's absent for elliptic curve groups
+	Modulus *big.Int `json:",omitempty"`
+	// Order of generator
+	Order *big.Int
+	Bound *big.Int
+}
+
+// Checks whether parameters define the same problem
+func (p DlogParams) Equal(another DlogParams) bool {
+	return p.Group == another.Group &&
+		bytes.Equal(p.Generator, another.Generator) &&
+		equalInts(p.Modulus, another.Modulus) &&
+		equalInts(p.Order, another.Order) &&
+		equalInts(p.Bound, another.Bound)
+}
+
+// Returns hex-encoded hash of parameters, it identifies tables built for them
+func (p DlogParams) Fingerprint() string {
+	encoded, _ := json.Marshal(p)
+	hash := sha256.Sum256(encoded)
+	return hex.EncodeToString(hash[:8])
+}
+
+func (p DlogParams) group() (proofGroup, error) {
+	switch p.Group {
+	case dlogGroupZp:
+		if p.Modulus == nil || p.Order == nil || len(p.Generator) == 0 {
+			return nil, errors.New("malformed discrete logarithm parameters")
+		}
+		return zpGroup{p: p.Modulus, q: p.Order, g: new(big.Int).SetBytes(p.Generator)}, nil
+	case dlogGroupG1:
+		return g1Group{}, nil
+	case dlogGroupGT:
+		return gtGroup{}, nil
+	default:
+		return nil, errors.Errorf("unknown group %q", p.Group)
+	}
+}
+
+// Table of precomputed discrete logarithms of g^j for j in [0; M)
+//
+// Solving discrete logarithm of h takes at most 2*Bound/M group operations
+// (giant steps). Table doesn't depend on any secret and can be reused by
+// every mpk having the same parameters.
+type DlogTable struct {
+	params DlogParams
+	group  proofGroup
+	m      int64
+	// Maps fingerprint of g^j to j, collisions are resolved by verifying
+	// found logarithm
+	steps map[uint64]int64
+}
+
+// Serialized DlogTable
+type dlogTableFile struct {
+	Params DlogParams
+	M      int64
+	// Concatenated fingerprints of g^j for j in [0; M)
+	Steps []byte
+}
+
+// Precomputes table of discrete logarithms large enough to be persisted and
+// reused for many decryptions
+func NewDlogTable(params DlogParams) (*DlogTable, error) {
+	return newDlogTable(params, dlogTableFactor)
+}
+
+// Precomputes table of factor * sqrt(bound) discrete logarithms
+func newDlogTable(params DlogParams, factor int64) (*DlogTable, error) {
+	if params.Bound == nil || params.Bound.Sign() < 0 || !params.Bound.IsInt64() {
+		return nil, errors.New("bound must be non-negative 64-bit integer")
+	}
+	group, err := params.group()
+	if err != nil {
+		return nil, err
+	}
+
+	size := params.Bound.Int64() + 1
+	m := factor * int64(math.Ceil(math.Sqrt(float64(size))))
+	if m > size {
+		m = size
+	}
+
+	table := &DlogTable{params: params, group: group, m: m, steps: make(map[uint64]int64, m)}
+	step, g := group.baseExp(big.NewInt(0)), group.baseExp(big.NewInt(1))
+	for j := int64(0); j < m; j++ {
+		fingerprint := elementFingerprint(group.marshal(step))
+		if _, ok := table.steps[fingerprint]; !ok {
+			table.steps[fingerprint] = j
+		}
+		step = group.mul(step, g)
+	}
+	return table, nil
+}
+
+// Returns parameters table is built for
+func (t *DlogTable) Params() DlogParams {
+	return t.params
+}
+
+// Finds v in [-bound; bound] such that g^v = h
+func (t *DlogTable) Solve(h []byte) (*big.Int, error) {
+	element, err := t.group.unmarshal(h)
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed element")
+	}
+	if v, ok := t.solve(element); ok {
+		return v, nil
+	}
+	inverse := t.group.div(t.group.baseExp(big.NewInt(0)), element)
+	if v, ok := t.solve(inverse); ok {
+		return v.Neg(v), nil
+	}
+	return nil, errors.New("failed to find discrete logarithm within bound")
+}
+
+// Finds v in [0; bound] such that g^v = h
+func (t *DlogTable) solve(h interface{}) (*big.Int, bool) {
+	bound := t.params.Bound.Int64()
+	target := t.group.marshal(h)
+
+	// Giant steps: h * g^(-i*m) for i in [0; bound/m]
+	giant := t.group.div(t.group.baseExp(big.NewInt(0)), t.group.baseExp(big.NewInt(t.m)))
+	current := h
+	for i := int64(0); i <= bound/t.m; i++ {
+		j, ok := t.steps[elementFingerprint(t.group.marshal(current))]
+		if ok && i*t.m+j <= bound {
+			v := big.NewInt(i*t.m + j)
+			if bytes.Equal(t.group.marshal(t.group.baseExp(v)), target) {
+				return v, true
+			}
+		}
+		current = t.group.mul(current, giant)
+	}
+	return nil, false
+}
+
+// Loads table built for given parameters from dir
+//
+// If there's no such table (or it's built for other parameters), it's built
+// and saved to dir.
+func LoadOrBuildDlogTable(dir string, params DlogParams) (*DlogTable, error) {
+	filename := path.Join(dir, "dlog_"+params.Fingerprint()+".json")
+	if table, err := loadDlogTable(filename, params); err == nil {
+		return table, nil
+	}
+
+	table, err := NewDlogTable(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := table.save(dir, filename); err != nil {
+		return nil, errors.Wrap(err, "save table")
+	}
+	return table, nil
+}
+
+func loadDlogTable(filename string, params DlogParams) (*DlogTable, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var content dlogTableFile
+	if err := json.NewDecoder(file).Decode(&content); err != nil {
+		return nil, errors.Wrap(err, "decode table")
+	}
+	if !content.Params.Equal(params) {
+		return nil, errors.New("table is built for other parameters")
+	}
+	if content.M <= 0 || int64(len(content.Steps)) != content.M*fingerprintSize {
+		return nil, errors.New("malformed table")
+	}
+	group, err := params.group()
+	if err != nil {
+		return nil, err
+	}
+
+	table := &DlogTable{params: params, group: group, m: content.M, steps: make(map[uint64]int64, content.M)}
+	for j := int64(0); j < content.M; j++ {
+		fingerprint := binary.BigEndian.Uint64(content.Steps[j*fingerprintSize:])
+		if _, ok := table.steps[fingerprint]; !ok {
+			table.steps[fingerprint] = j
+		}
+	}
+	return table, nil
+}
+
+// Writes table to temporary file and renames it, so concurrent readers never
+// observe partially written table
+func (t *DlogTable) save(dir, filename string) error {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return errors.Wrap(err, "create dir")
+	}
+
+	// Colliding baby steps are kept by the first j only, the rest get zero
+	// fingerprint which is as good as absent
+	steps := make([]byte, t.m*fingerprintSize)
+	for fingerprint, j := range t.steps {
+		binary.BigEndian.PutUint64(steps[j*fingerprintSize:], fingerprint)
+	}
+	file, err := ioutil.TempFile(dir, "dlog_*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "create temporary file")
+	}
+	err = json.NewEncoder(file).Encode(dlogTableFile{Params: t.params, M: t.m, Steps: steps})
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return errors.Wrap(err, "encode/write table")
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return errors.Wrap(err, "close file")
+	}
+	return errors.Wrap(os.Rename(file.Name(), filename), "rename table file")
+}
+
+func elementFingerprint(element []byte) uint64 {
+	hash := sha256.Sum256(element)
+	return binary.BigEndian.Uint64(hash[:fingerprintSize])
+}
+
+func equalInts(a, b *big.Int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmp(b) == 0
+}
+
+// Group GT of bn256 pairing generated by e(G1, G2)
+type gtGroup struct{}
+
+var gtGenerator = bn256.Pair(new(bn256.G1).ScalarBaseMult(big.NewInt(1)), new(bn256.G2).ScalarBaseMult(big.NewInt(1)))
+
+func (gtGroup) order() *big.Int {
+	return bn256.Order
+}
+
+func (gtGroup) baseExp(k *big.Int) interface{} {
+	return new(bn256.GT).ScalarMult(gtGenerator, k)
+}
+
+func (gtGroup) exp(a interface{}, k *big.Int) interface{} {
+	return new(bn256.GT).ScalarMult(a.(*bn256.GT), k)
+}
+
+func (gtGroup) mul(a, b interface{}) interface{} {
+	return new(bn256.GT).Add(a.(*bn256.GT), b.(*bn256.GT))
+}
+
+func (gtGroup) div(a, b interface{}) interface{} {
+	return new(bn256.GT).Add(a.(*bn256.GT), new(bn256.GT).Neg(b.(*bn256.GT)))
+}
+
+func (gtGroup) marshal(a interface{}) []byte {
+	return a.(*bn256.GT).Marshal()
+}
+
+// Decodes element of GT, membership is not checked
+func (gtGroup) unmarshal(bytes []byte) (interface{}, error) {
+	element := new(bn256.GT)
+	if _, err := element.Unmarshal(bytes); err != nil {
+		return nil, err
+	}
+	return element, nil
+}
+
+// Decrypts ciphertext using precomputed table, if table is nil a minimal one
+// is built for this decryption only
+func decryptDlog(scheme DlogScheme, mpk data.MPK, sk data.RecipientSecretKey, ciphertext *data.Ciphertext, table *DlogTable) (*big.Int, error) {
+	params, err := scheme.DlogParams(mpk)
+	if err != nil {
+		return nil, err
+	}
+	if table == nil {
+		table, err = newDlogTable(params, 1)
+		if err != nil {
+			return nil, err
+		}
+	} else if !table.params.Equal(params) {
+		return nil, errors.New("discrete logarithm table is built for another mpk")
+	}
+
+	gv, err := scheme.DecryptElement(mpk, sk, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return table.Solve(gv)
+}
@@ -0,0 +1,118 @@
+package gofe
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDlogTable(t *testing.T) {
+	forEachScheme(t, testDlogTable)
+}
+
+func testDlogTable(t *testing.T, scheme Scheme) {
+	dir, err := ioutil.TempDir("", "dlog_cache")
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	mpk, sk, encrypt := setupScheme(t, scheme, 3)
+	table, err := LoadDlogTable(dir, mpk)
+	assert.NoError(t, err, "load table")
+	if _, ok := scheme.(DlogScheme); !ok {
+		assert.Nil(t, table, "table for scheme without discrete logarithm")
+		return
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "dlog_*.json"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1, "table is not saved")
+
+	// x = [0 1000 7]
+	x := gofe.NewVector([]*big.Int{big.NewInt(0), big.NewInt(1000), big.NewInt(7)})
+	ciphertext, err := encrypt(x)
+	assert.NoError(t, err, "encrypt")
+
+	reloaded, err := LoadDlogTable(dir, mpk)
+	assert.NoError(t, err, "reload table")
+	for j := range x {
+		v, err := DecryptWithTable(mpk, sk[j], &ciphertext, table)
+		assert.NoError(t, err, "decrypt using table, sk", j)
+		assert.Equal(t, x[j], v, "wrong decryption using table, sk", j)
+
+		v, err = DecryptWithTable(mpk, sk[j], &ciphertext, reloaded)
+		assert.NoError(t, err, "decrypt using reloaded table, sk", j)
+		assert.Equal(t, x[j], v, "wrong decryption using reloaded table, sk", j)
+
+		v, err = DecryptValidated(mpk, sk[j], &ciphertext, table)
+		assert.NoError(t, err, "decrypt validated ciphertext, sk", j)
+		assert.Equal(t, x[j], v, "wrong decryption of validated ciphertext, sk", j)
+	}
+
+	t.Run("Negative logarithm", func(t *testing.T) {
+		group := table.group
+		h := group.div(group.baseExp(big.NewInt(0)), group.baseExp(big.NewInt(5)))
+		v, err := table.Solve(group.marshal(h))
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewInt(-5), v)
+	})
+
+	t.Run("Table of another mpk", func(t *testing.T) {
+		another, err := NewDlogTable(DlogParams{Group: dlogGroupG1, Order: big.NewInt(7), Bound: big.NewInt(10)})
+		assert.NoError(t, err)
+		_, err = DecryptWithTable(mpk, sk[0], &ciphertext, another)
+		assert.Error(t, err)
+	})
+
+	t.Run("Stale table is rebuilt", func(t *testing.T) {
+		params := table.Params()
+		params.Bound = new(big.Int).Add(params.Bound, big.NewInt(1))
+		// Table of other params put where the current one is expected
+		stale, err := NewDlogTable(params)
+		assert.NoError(t, err)
+		assert.NoError(t, stale.save(dir, files[0]))
+
+		rebuilt, err := LoadDlogTable(dir, mpk)
+		assert.NoError(t, err, "load table")
+		assert.True(t, rebuilt.Params().Equal(table.Params()), "stale table is loaded")
+	})
+}
+
+func BenchmarkDecrypt(b *testing.B) {
+	mpk, sk, encrypt := setupScheme(b, DefaultScheme, 3)
+	ciphertext, err := encrypt(unitVector(3, 1))
+	if err != nil {
+		b.Fatal(err)
+	}
+	params, err := DefaultScheme.(DlogScheme).DlogParams(mpk)
+	if err != nil {
+		b.Fatal(err)
+	}
+	table, err := NewDlogTable(params)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("without table", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := Decrypt(mpk, sk[1], &ciphertext); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("with table", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := DecryptWithTable(mpk, sk[1], &ciphertext, table); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,368 @@
+package gofe
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+
+	"github.com/fentec-project/bn256"
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/innerprod/fullysec"
+	"github.com/pkg/errors"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+const DMCFESchemeID = "dmcfe"
+
+// DMCFE is a MultiClientScheme based on fullysec.DMCFEClient (decentralized
+// multi-client scheme over bn256 pairing)
+//
+// Ciphertext consists of label points H_0(l), H_1(l) followed by encrypted
+// slots, all of them are points of G1. Label points are accumulated together
+// with the slots, that allows accumulating ciphertexts encrypted under
+// different labels.
+type DMCFE struct {
+	// Max value that accumulated ciphertext decrypts to
+	Bound *big.Int
+}
+
+type dmcfeMPK struct {
+	Bound *big.Int
+	// Public keys of the clients which they use to agree on masks of key shares
+	PubKeys [][]byte
+}
+
+type dmcfeClientKey struct {
+	SecKey *big.Int
+	S      gofe.Vector
+}
+
+type dmcfeMSK struct {
+	Clients []data.ClientKey
+}
+
+var _ MultiClientScheme = DMCFE{}
+
+func init() {
+	RegisterScheme(DMCFE{Bound: big.NewInt(1 << 20)})
+}
+
+func (DMCFE) ID() string {
+	return DMCFESchemeID
+}
+
+func (s DMCFE) SetupClients(parties int) (data.MPK, []data.ClientKey, error) {
+	clients := make([]data.ClientKey, parties)
+	pubKeys := make([][]byte, parties)
+	for i := 0; i < parties; i++ {
+		client, err := fullysec.NewDMCFEClient(i)
+		if err != nil {
+			return data.MPK{}, nil, errors.Wrapf(err, "generate key of client %d", i)
+		}
+		key, err := json.Marshal(dmcfeClientKey{SecKey: client.ClientSecKey, S: client.S})
+		if err != nil {
+			return data.MPK{}, nil, errors.Wrap(err, "encode client key")
+		}
+		clients[i] = data.ClientKey{I: i, Key: key}
+		pubKeys[i] = marshalPoint(client.ClientPubKey)
+	}
+
+	mpkJSON, err := json.Marshal(dmcfeMPK{Bound: s.Bound, PubKeys: pubKeys})
+	if err != nil {
+		return data.MPK{}, nil, errors.Wrap(err, "encode mpk")
+	}
+	return data.MPK{Scheme: DMCFESchemeID, L: parties, Key: mpkJSON}, clients, nil
+}
+
+// Generates keys of all the clients in one place, msk is just a list of them
+//
+// It's convenient for testing, but defeats the purpose of the scheme. Clients
+// keys are supposed to be kept by their owners.
+func (s DMCFE) Setup(parties int) (data.MPK, data.MSK, error) {
+	mpk, clients, err := s.SetupClients(parties)
+	if err != nil {
+		return data.MPK{}, data.MSK{}, err
+	}
+	mskJSON, err := json.Marshal(dmcfeMSK{Clients: clients})
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "encode msk")
+	}
+	return mpk, data.MSK{Scheme: DMCFESchemeID, Key: mskJSON}, nil
+}
+
+func (s DMCFE) DeriveKey(mpk data.MPK, msk data.MSK, i int) (data.RecipientSecretKey, error) {
+	if msk.Scheme != DMCFESchemeID {
+		return data.RecipientSecretKey{}, errors.Errorf("expected %s msk, got %s", DMCFESchemeID, msk.Scheme)
+	}
+	var key dmcfeMSK
+	if err := json.Unmarshal(msk.Key, &key); err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "decode msk")
+	}
+	return deriveKeyFromShares(s, mpk, key.Clients, i)
+}
+
+func (s DMCFE) DeriveKeyShare(mpk data.MPK, client data.ClientKey, i int) (json.RawMessage, error) {
+	_, pubKeys, err := decodeDMCFEMPK(mpk)
+	if err != nil {
+		return nil, err
+	}
+	if i < 0 || i >= mpk.L {
+		return nil, errors.Errorf("party index %d is out of range", i)
+	}
+	c, err := decodeDMCFEClient(client, pubKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.SetShare(pubKeys); err != nil {
+		return nil, errors.Wrap(err, "agree on key share mask")
+	}
+	share, err := c.DeriveKeyShare(unitVector(mpk.L, i))
+	if err != nil {
+		return nil, errors.Wrap(err, "derive key share")
+	}
+	return json.Marshal([][]byte{share[0].Marshal(), share[1].Marshal()})
+}
+
+// Sums up key shares, their masks cancel out
+func (DMCFE) CombineKeyShares(mpk data.MPK, i int, shares []json.RawMessage) (data.RecipientSecretKey, error) {
+	if _, _, err := decodeDMCFEMPK(mpk); err != nil {
+		return data.RecipientSecretKey{}, err
+	}
+	if i < 0 || i >= mpk.L {
+		return data.RecipientSecretKey{}, errors.Errorf("party index %d is out of range", i)
+	}
+	if len(shares) != mpk.L {
+		return data.RecipientSecretKey{}, errors.Errorf("expected %d key shares, got %d", mpk.L, len(shares))
+	}
+
+	key := [2]*bn256.G2{new(bn256.G2).ScalarBaseMult(big.NewInt(0)), new(bn256.G2).ScalarBaseMult(big.NewInt(0))}
+	for j, share := range shares {
+		points, err := decodeDMCFEKey(share)
+		if err != nil {
+			return data.RecipientSecretKey{}, errors.Wrapf(err, "key share of client %d", j)
+		}
+		for k := range key {
+			key[k] = new(bn256.G2).Add(key[k], points[k])
+		}
+	}
+
+	keyJSON, err := json.Marshal([][]byte{key[0].Marshal(), key[1].Marshal()})
+	if err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "encode derived key")
+	}
+	return data.RecipientSecretKey{I: i, DerivedKey: keyJSON}, nil
+}
+
+// Always fails: slots must be encrypted by their clients
+func (DMCFE) Encrypt(data.MPK, gofe.Vector) (data.Ciphertext, error) {
+	return data.Ciphertext{}, errors.Errorf("scheme %s requires keys of all the clients to encrypt", DMCFESchemeID)
+}
+
+func (DMCFE) EncryptSlot(mpk data.MPK, client data.ClientKey, x *big.Int, label string) ([]byte, error) {
+	bound, pubKeys, err := decodeDMCFEMPK(mpk)
+	if err != nil {
+		return nil, err
+	}
+	if x.Sign() < 0 || x.Cmp(bound) > 0 {
+		return nil, errors.New("plaintext is out of bound")
+	}
+	c, err := decodeDMCFEClient(client, pubKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	ct, err := c.Encrypt(x, label)
+	if err != nil {
+		return nil, errors.Wrap(err, "encrypt slot")
+	}
+	return marshalPoint(ct), nil
+}
+
+func (DMCFE) CombineSlots(mpk data.MPK, label string, slots [][]byte) (data.Ciphertext, error) {
+	if _, _, err := decodeDMCFEMPK(mpk); err != nil {
+		return data.Ciphertext{}, err
+	}
+	if len(slots) != mpk.L {
+		return data.Ciphertext{}, errors.Errorf("expected %d slots, got %d", mpk.L, len(slots))
+	}
+
+	points := make([][]byte, 0, mpk.L+2)
+	for k := 0; k < 2; k++ {
+		h, err := dmcfeLabelPoint(k, label)
+		if err != nil {
+			return data.Ciphertext{}, err
+		}
+		points = append(points, marshalPoint(h))
+	}
+	for i, slot := range slots {
+		if _, err := unmarshalPoint(slot); err != nil {
+			return data.Ciphertext{}, errors.Wrapf(err, "malformed slot %d", i)
+		}
+		points = append(points, slot)
+	}
+	return data.Ciphertext{Points: points}, nil
+}
+
+// Adds ciphertexts point-wise, including label points
+func (DMCFE) Accumulate(mpk data.MPK, acc *data.Ciphertext, delta *data.Ciphertext) error {
+	return accumulatePoints(acc, delta, mpk.L+2)
+}
+
+func (s DMCFE) Decrypt(mpk data.MPK, sk data.RecipientSecretKey, ciphertext *data.Ciphertext) (*big.Int, error) {
+	return decryptDlog(s, mpk, sk, ciphertext, nil)
+}
+
+// Computes e(G1, G2)^v = e(ct_i, G2) - e(H_0, key_0) - e(H_1, key_1)
+func (DMCFE) DecryptElement(mpk data.MPK, sk data.RecipientSecretKey, ciphertext *data.Ciphertext) ([]byte, error) {
+	if _, _, err := decodeDMCFEMPK(mpk); err != nil {
+		return nil, err
+	}
+	key, err := decodeDMCFEKey(sk.DerivedKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode derived key")
+	}
+	if len(ciphertext.Points) != mpk.L+2 || sk.I < 0 || sk.I >= mpk.L {
+		return nil, errors.New("malformed ciphertext")
+	}
+
+	points := make([]*bn256.G1, 3)
+	for k, j := range []int{0, 1, sk.I + 2} {
+		points[k], err = unmarshalPoint(ciphertext.Points[j])
+		if err != nil {
+			return nil, errors.Wrap(err, "malformed ciphertext")
+		}
+	}
+
+	mask := new(bn256.GT).Add(bn256.Pair(points[0], key[0]), bn256.Pair(points[1], key[1]))
+	gv := bn256.Pair(points[2], new(bn256.G2).ScalarBaseMult(big.NewInt(1)))
+	gv.Add(gv, new(bn256.GT).Neg(mask))
+	return gv.Marshal(), nil
+}
+
+// Discrete logarithm is taken to the base e(G1, G2) in GT
+func (DMCFE) DlogParams(mpk data.MPK) (DlogParams, error) {
+	bound, _, err := decodeDMCFEMPK(mpk)
+	if err != nil {
+		return DlogParams{}, err
+	}
+	return DlogParams{Group: dlogGroupGT, Generator: gtGenerator.Marshal(), Order: bn256.Order, Bound: bound}, nil
+}
+
+func decodeDMCFEMPK(mpk data.MPK) (*big.Int, []*bn256.G1, error) {
+	if mpk.Scheme != DMCFESchemeID {
+		return nil, nil, errors.Errorf("expected %s mpk, got %s", DMCFESchemeID, mpk.Scheme)
+	}
+	var key dmcfeMPK
+	if err := json.Unmarshal(mpk.Key, &key); err != nil {
+		return nil, nil, errors.Wrap(err, "decode mpk")
+	}
+	if key.Bound == nil || key.Bound.Sign() <= 0 || len(key.PubKeys) != mpk.L {
+		return nil, nil, errors.New("malformed mpk")
+	}
+
+	pubKeys := make([]*bn256.G1, len(key.PubKeys))
+	for i, bytes := range key.PubKeys {
+		point, err := unmarshalPoint(bytes)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "malformed public key of client %d", i)
+		}
+		pubKeys[i] = point
+	}
+	return key.Bound, pubKeys, nil
+}
+
+// Restores client and checks that its key matches public key from mpk
+func decodeDMCFEClient(client data.ClientKey, pubKeys []*bn256.G1) (*fullysec.DMCFEClient, error) {
+	if client.I < 0 || client.I >= len(pubKeys) {
+		return nil, errors.Errorf("client index %d is out of range", client.I)
+	}
+	var key dmcfeClientKey
+	if err := json.Unmarshal(client.Key, &key); err != nil {
+		return nil, errors.Wrap(err, "decode client key")
+	}
+	if key.SecKey == nil || len(key.S) != 2 {
+		return nil, errors.New("malformed client key")
+	}
+
+	pubKey := new(bn256.G1).ScalarBaseMult(key.SecKey)
+	if string(marshalPoint(pubKey)) != string(marshalPoint(pubKeys[client.I])) {
+		return nil, errors.Errorf("key of client %d doesn't match mpk", client.I)
+	}
+	return &fullysec.DMCFEClient{
+		Idx:          client.I,
+		ClientSecKey: key.SecKey,
+		ClientPubKey: pubKey,
+		S:            key.S,
+	}, nil
+}
+
+// Decodes pair of G2 points which both key shares and derived keys consist of
+func decodeDMCFEKey(key json.RawMessage) ([2]*bn256.G2, error) {
+	var encoded [][]byte
+	if err := json.Unmarshal(key, &encoded); err != nil {
+		return [2]*bn256.G2{}, err
+	}
+	if len(encoded) != 2 {
+		return [2]*bn256.G2{}, errors.New("expected 2 points")
+	}
+
+	var points [2]*bn256.G2
+	for k, bytes := range encoded {
+		points[k] = new(bn256.G2)
+		if _, err := points[k].Unmarshal(bytes); err != nil {
+			return [2]*bn256.G2{}, errors.Wrapf(err, "malformed point %d", k)
+		}
+	}
+	return points, nil
+}
+
+// Computes H_k(label) in the same way as fullysec.DMCFEClient does
+func dmcfeLabelPoint(k int, label string) (*bn256.G1, error) {
+	h, err := bn256.HashG1(strconv.Itoa(k) + " " + label)
+	return h, errors.Wrap(err, "hash label")
+}
+
+var _ ValidatingScheme = DMCFE{}
+
+func (DMCFE) ValidateMPK(mpk data.MPK) error {
+	_, pubKeys, err := decodeDMCFEMPK(mpk)
+	if err != nil {
+		return err
+	}
+	for i, point := range pubKeys {
+		if marshalPoint(point)[0] == pointInfinity {
+			return errors.Errorf("public key of client %d is point at infinity", i)
+		}
+	}
+	return nil
+}
+
+// Ciphertext consists of two points derived from its label followed by L
+// slots encrypted by clients
+func (DMCFE) ValidateCiphertext(mpk data.MPK, ciphertext *data.Ciphertext) error {
+	if _, _, err := decodeDMCFEMPK(mpk); err != nil {
+		return err
+	}
+	return validatePoints(ciphertext, mpk.L+2)
+}
+
+var _ ConfigurableScheme = DMCFE{}
+
+// Curve is fixed, so modulus bits don't affect parameters
+func (DMCFE) Configure(profile Profile) (Scheme, data.Profile, error) {
+	scheme := DMCFE{Bound: big.NewInt(profile.MaxSignals)}
+	return scheme, data.Profile{ModulusBits: 256, SecurityBits: bn256SecurityBits, MaxSignals: profile.MaxSignals}, nil
+}
+
+var _ BoundedScheme = DMCFE{}
+
+// Capacity is the bound of discrete logarithm solved by Decrypt
+func (s DMCFE) Capacity(mpk data.MPK) (*big.Int, error) {
+	params, err := s.DlogParams(mpk)
+	if err != nil {
+		return nil, err
+	}
+	return params.Bound, nil
+}
@@ -0,0 +1,45 @@
+package gofe
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+func TestDMCFERequiresClientKeys(t *testing.T) {
+	scheme := DMCFE{Bound: big.NewInt(16)}
+	mpk, clients, _, err := GenerateClientKeysWith(scheme, 2)
+	assert.NoError(t, err, "keygen failed")
+
+	x := gofe.NewConstantVector(2, big.NewInt(0))
+	_, err = scheme.Encrypt(mpk, x)
+	assert.Error(t, err, "encrypted without client keys")
+
+	// Clients must encrypt their own slots
+	_, err = EncryptWithClients(mpk, []data.ClientKey{clients[1], clients[0]}, x)
+	assert.Error(t, err, "encrypted using swapped clients")
+
+	// Key of client from another setup doesn't match mpk
+	_, otherClients, _, err := GenerateClientKeysWith(scheme, 2)
+	assert.NoError(t, err, "keygen failed")
+	_, err = EncryptWithClients(mpk, otherClients, x)
+	assert.Error(t, err, "encrypted using foreign clients")
+	_, err = scheme.DeriveKeyShare(mpk, otherClients[0], 0)
+	assert.Error(t, err, "derived key share using foreign client")
+}
+
+func TestDMCFEKeyNeedsAllShares(t *testing.T) {
+	scheme := DMCFE{Bound: big.NewInt(16)}
+	mpk, clients, err := scheme.SetupClients(3)
+	assert.NoError(t, err, "setup failed")
+
+	share, err := scheme.DeriveKeyShare(mpk, clients[0], 0)
+	assert.NoError(t, err, "derive key share")
+	_, err = scheme.CombineKeyShares(mpk, 0, []json.RawMessage{share})
+	assert.Error(t, err, "combined key out of single share")
+}
@@ -0,0 +1,611 @@
+package gofe
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"math/big"
+
+	"github.com/fentec-project/bn256"
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/pkg/errors"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+const ECDDHSchemeID = "ecddh"
+
+// ECDDH is a Scheme which follows construction of simple.DDH but works
+// in group G1 of bn256 elliptic curve
+//
+// Group elements are serialized in compressed form taking 33 bytes, which
+// makes rounds several times smaller than in Z_p schemes.
+type ECDDH struct {
+	// Max value that accumulated ciphertext decrypts to
+	Bound *big.Int
+}
+
+type ecddhMPK struct {
+	Bound  *big.Int
+	Vector [][]byte
+}
+
+type ecddhMSK struct {
+	Vector gofe.Vector
+}
+
+var _ DistributedScheme = ECDDH{}
+
+func init() {
+	RegisterScheme(ECDDH{Bound: big.NewInt(1 << 20)})
+}
+
+func (ECDDH) ID() string {
+	return ECDDHSchemeID
+}
+
+func (s ECDDH) Setup(parties int) (data.MPK, data.MSK, error) {
+	return s.SetupFrom(rand.Reader, parties)
+}
+
+var _ SeedableScheme = ECDDH{}
+
+func (s ECDDH) SetupFrom(random io.Reader, parties int) (data.MPK, data.MSK, error) {
+	msk := make(gofe.Vector, parties)
+	mpk := make([][]byte, parties)
+	for i := 0; i < parties; i++ {
+		k, h, err := bn256.RandomG1(random)
+		if err != nil {
+			return data.MPK{}, data.MSK{}, errors.Wrap(err, "generate master key")
+		}
+		msk[i] = k
+		mpk[i] = marshalPoint(h)
+	}
+
+	mpkJSON, err := json.Marshal(ecddhMPK{Bound: s.Bound, Vector: mpk})
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "encode mpk")
+	}
+	mskJSON, err := json.Marshal(ecddhMSK{Vector: msk})
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "encode msk")
+	}
+
+	return data.MPK{Scheme: ECDDHSchemeID, L: parties, Key: mpkJSON},
+		data.MSK{Scheme: ECDDHSchemeID, Key: mskJSON},
+		nil
+}
+
+func (ECDDH) DeriveKey(mpk data.MPK, msk data.MSK, i int) (data.RecipientSecretKey, error) {
+	if _, _, err := decodeECDDHMPK(mpk); err != nil {
+		return data.RecipientSecretKey{}, err
+	}
+	if msk.Scheme != ECDDHSchemeID {
+		return data.RecipientSecretKey{}, errors.Errorf("expected %s msk, got %s", ECDDHSchemeID, msk.Scheme)
+	}
+	var key ecddhMSK
+	if err := json.Unmarshal(msk.Key, &key); err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "decode msk")
+	}
+	if len(key.Vector) != mpk.L {
+		return data.RecipientSecretKey{}, errors.New("malformed msk")
+	}
+
+	// For y = e_i, <msk, y> = msk_i
+	skJSON, err := json.Marshal(key.Vector[i])
+	if err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "encode derived key")
+	}
+	return data.RecipientSecretKey{I: i, DerivedKey: skJSON}, nil
+}
+
+func (s ECDDH) Encrypt(mpk data.MPK, x gofe.Vector) (data.Ciphertext, error) {
+	return s.EncryptFrom(rand.Reader, mpk, x)
+}
+
+// Encrypts x as ct_0 = r*G, ct_i = r*H_i + x_i*G
+func (ECDDH) EncryptFrom(random io.Reader, mpk data.MPK, x gofe.Vector) (data.Ciphertext, error) {
+	bound, h, err := decodeECDDHMPK(mpk)
+	if err != nil {
+		return data.Ciphertext{}, err
+	}
+	if len(x) != mpk.L {
+		return data.Ciphertext{}, errors.New("plaintext has wrong length")
+	}
+	for _, xi := range x {
+		if xi.Sign() < 0 || xi.Cmp(bound) > 0 {
+			return data.Ciphertext{}, errors.New("plaintext is out of bound")
+		}
+	}
+
+	r, ct0, err := bn256.RandomG1(random)
+	if err != nil {
+		return data.Ciphertext{}, errors.Wrap(err, "sample randomness")
+	}
+
+	points := make([][]byte, len(x)+1)
+	points[0] = marshalPoint(ct0)
+	for i, xi := range x {
+		hr := new(bn256.G1).ScalarMult(h[i], r)
+		gx := new(bn256.G1).ScalarBaseMult(xi)
+		points[i+1] = marshalPoint(new(bn256.G1).Add(hr, gx))
+	}
+
+	return data.Ciphertext{Points: points}, nil
+}
+
+// Adds ciphertexts point-wise
+func (ECDDH) Accumulate(mpk data.MPK, acc *data.Ciphertext, delta *data.Ciphertext) error {
+	return accumulatePoints(acc, delta, mpk.L+1)
+}
+
+// Adds ciphertexts consisting of n points of G1 point-wise
+func accumulatePoints(acc *data.Ciphertext, delta *data.Ciphertext, n int) error {
+	if len(acc.Points) != n || len(delta.Points) != n {
+		return errors.New("given ciphertexts have wrong lengths")
+	}
+
+	sum := make([][]byte, len(acc.Points))
+	for i := range acc.Points {
+		a, err := unmarshalPoint(acc.Points[i])
+		if err != nil {
+			return errors.Wrapf(err, "malformed accumulator point %d", i)
+		}
+		b, err := unmarshalPoint(delta.Points[i])
+		if err != nil {
+			return errors.Wrapf(err, "malformed delta point %d", i)
+		}
+		sum[i] = marshalPoint(new(bn256.G1).Add(a, b))
+	}
+
+	acc.Points = sum
+	return nil
+}
+
+func (s ECDDH) Decrypt(mpk data.MPK, sk data.RecipientSecretKey, ciphertext *data.Ciphertext) (*big.Int, error) {
+	return decryptDlog(s, mpk, sk, ciphertext, nil)
+}
+
+// Computes v*G = ct_{i+1} - sk*ct_0
+func (ECDDH) DecryptElement(mpk data.MPK, sk data.RecipientSecretKey, ciphertext *data.Ciphertext) ([]byte, error) {
+	if _, _, err := decodeECDDHMPK(mpk); err != nil {
+		return nil, err
+	}
+	var key big.Int
+	if err := json.Unmarshal(sk.DerivedKey, &key); err != nil {
+		return nil, errors.Wrap(err, "decode derived key")
+	}
+	if len(ciphertext.Points) != mpk.L+1 || sk.I < 0 || sk.I >= mpk.L {
+		return nil, errors.New("malformed ciphertext")
+	}
+
+	ct0, err := unmarshalPoint(ciphertext.Points[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed ciphertext")
+	}
+	cti, err := unmarshalPoint(ciphertext.Points[sk.I+1])
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed ciphertext")
+	}
+
+	mask := new(bn256.G1).ScalarMult(ct0, &key)
+	gv := new(bn256.G1).Add(cti, new(bn256.G1).Neg(mask))
+	return marshalPoint(gv), nil
+}
+
+// Discrete logarithm is taken to the base G in G1
+func (ECDDH) DlogParams(mpk data.MPK) (DlogParams, error) {
+	bound, _, err := decodeECDDHMPK(mpk)
+	if err != nil {
+		return DlogParams{}, err
+	}
+	return DlogParams{
+		Group:     dlogGroupG1,
+		Generator: marshalPoint(new(bn256.G1).ScalarBaseMult(big.NewInt(1))),
+		Order:     bn256.Order,
+		Bound:     bound,
+	}, nil
+}
+
+func decodeECDDHMPK(mpk data.MPK) (*big.Int, []*bn256.G1, error) {
+	if mpk.Scheme != ECDDHSchemeID {
+		return nil, nil, errors.Errorf("expected %s mpk, got %s", ECDDHSchemeID, mpk.Scheme)
+	}
+	var key ecddhMPK
+	if err := json.Unmarshal(mpk.Key, &key); err != nil {
+		return nil, nil, errors.Wrap(err, "decode mpk")
+	}
+	if key.Bound == nil || key.Bound.Sign() <= 0 || len(key.Vector) != mpk.L {
+		return nil, nil, errors.New("malformed mpk")
+	}
+
+	h := make([]*bn256.G1, len(key.Vector))
+	for i, bytes := range key.Vector {
+		point, err := unmarshalPoint(bytes)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "malformed mpk point %d", i)
+		}
+		h[i] = point
+	}
+	return key.Bound, h, nil
+}
+
+var _ ValidatingScheme = ECDDH{}
+
+// Points are checked to be on curve when decoded, and G1 has prime order, so
+// every point but infinity generates the whole group
+func (ECDDH) ValidateMPK(mpk data.MPK) error {
+	bound, h, err := decodeECDDHMPK(mpk)
+	if err != nil {
+		return err
+	}
+	if err := validateBound(bound, mpk.L, bn256.Order); err != nil {
+		return err
+	}
+	for i, point := range h {
+		if marshalPoint(point)[0] == pointInfinity {
+			return errors.Errorf("element %d is point at infinity", i)
+		}
+	}
+	return nil
+}
+
+func (ECDDH) ValidateCiphertext(mpk data.MPK, ciphertext *data.Ciphertext) error {
+	if _, _, err := decodeECDDHMPK(mpk); err != nil {
+		return err
+	}
+	return validatePoints(ciphertext, mpk.L+1)
+}
+
+var _ VerifiableKeyScheme = ECDDH{}
+
+// Key of i-th recipient is msk_i, so it must satisfy key*G = H_i
+func (ECDDH) VerifyKey(mpk data.MPK, sk data.RecipientSecretKey) error {
+	_, h, err := decodeECDDHMPK(mpk)
+	if err != nil {
+		return err
+	}
+	var key big.Int
+	if err := json.Unmarshal(sk.DerivedKey, &key); err != nil {
+		return errors.Wrap(err, "decode derived key")
+	}
+	expected := new(bn256.G1).ScalarBaseMult(new(big.Int).Mod(&key, bn256.Order))
+	if !bytes.Equal(marshalPoint(expected), marshalPoint(h[sk.I])) {
+		return errors.Errorf("key doesn't match element %d of mpk", sk.I)
+	}
+	return nil
+}
+
+// Field modulus of bn256 curve (it's not exported by bn256 package)
+var bn256P, _ = new(big.Int).SetString("65000549695646603732796438742359905742825358107623003571877145026864184071783", 10)
+
+const (
+	pointInfinity = 0x00
+	pointEvenY    = 0x02
+	pointOddY     = 0x03
+	coordSize     = 32
+)
+
+// Serializes point of G1 in compressed form: prefix byte followed by
+// x coordinate, prefix is 0x02 or 0x03 depending on parity of y
+// coordinate. Point at infinity is encoded as 33 zero bytes.
+func marshalPoint(point *bn256.G1) []byte {
+	uncompressed := point.Marshal()
+	compressed := make([]byte, coordSize+1)
+
+	y := new(big.Int).SetBytes(uncompressed[coordSize:])
+	x := uncompressed[:coordSize]
+	if y.Sign() == 0 && new(big.Int).SetBytes(x).Sign() == 0 {
+		compressed[0] = pointInfinity
+		return compressed
+	}
+
+	compressed[0] = pointEvenY
+	if y.Bit(0) == 1 {
+		compressed[0] = pointOddY
+	}
+	copy(compressed[1:], x)
+	return compressed
+}
+
+// Deserializes point produced by marshalPoint
+//
+// Returns error if bytes don't represent a point of G1.
+func unmarshalPoint(bytes []byte) (*bn256.G1, error) {
+	if len(bytes) != coordSize+1 {
+		return nil, errors.New("wrong length of point")
+	}
+
+	uncompressed := make([]byte, 2*coordSize)
+	switch bytes[0] {
+	case pointInfinity:
+		for _, b := range bytes[1:] {
+			if b != 0 {
+				return nil, errors.New("malformed point at infinity")
+			}
+		}
+	case pointEvenY, pointOddY:
+		x := new(big.Int).SetBytes(bytes[1:])
+		if x.Cmp(bn256P) >= 0 {
+			return nil, errors.New("x coordinate is out of field")
+		}
+		// y^2 = x^3 + 3
+		rhs := new(big.Int).Exp(x, big.NewInt(3), bn256P)
+		rhs.Add(rhs, big.NewInt(3))
+		rhs.Mod(rhs, bn256P)
+		y := new(big.Int).ModSqrt(rhs, bn256P)
+		if y == nil {
+			return nil, errors.New("point is not on curve")
+		}
+		if y.Bit(0) != uint(bytes[0]&1) {
+			y.Sub(bn256P, y)
+		}
+		xBytes, yBytes := x.Bytes(), y.Bytes()
+		copy(uncompressed[coordSize-len(xBytes):coordSize], xBytes)
+		copy(uncompressed[2*coordSize-len(yBytes):], yBytes)
+	default:
+		return nil, errors.New("unknown point prefix")
+	}
+
+	point := new(bn256.G1)
+	if _, err := point.Unmarshal(uncompressed); err != nil {
+		return nil, err
+	}
+	return point, nil
+}
+
+type ecddhParams struct {
+	L     int
+	Bound *big.Int
+}
+
+// Public part of master key generated by a single recipient
+type ecddhKeyPart struct {
+	// H = s*G where s is recipient's part of msk
+	H []byte
+	// Schnorr proof of knowledge of s
+	Commitment []byte
+	Response   *big.Int
+}
+
+const ecddhKeyPartDomain = "pps/ecddh/key-part"
+
+func (s ECDDH) GenerateParams(_ io.Reader, parties int) (json.RawMessage, error) {
+	return json.Marshal(ecddhParams{L: parties, Bound: s.Bound})
+}
+
+func (ECDDH) GenerateKeyPart(random io.Reader, params json.RawMessage, i int) (json.RawMessage, data.RecipientSecretKey, error) {
+	ecParams, err := decodeECDDHParams(params)
+	if err != nil {
+		return nil, data.RecipientSecretKey{}, err
+	}
+	if i < 0 || i >= ecParams.L {
+		return nil, data.RecipientSecretKey{}, errors.Errorf("party index %d is out of range", i)
+	}
+
+	secret, h, err := bn256.RandomG1(random)
+	if err != nil {
+		return nil, data.RecipientSecretKey{}, errors.Wrap(err, "sample secret")
+	}
+	k, commitment, err := bn256.RandomG1(random)
+	if err != nil {
+		return nil, data.RecipientSecretKey{}, errors.Wrap(err, "sample nonce")
+	}
+
+	hBytes, commitmentBytes := marshalPoint(h), marshalPoint(commitment)
+	c := ecddhKeyPartChallenge(i, hBytes, commitmentBytes)
+	response := new(big.Int).Mul(c, secret)
+	response.Add(response, k)
+	response.Mod(response, bn256.Order)
+
+	part, err := json.Marshal(ecddhKeyPart{H: hBytes, Commitment: commitmentBytes, Response: response})
+	if err != nil {
+		return nil, data.RecipientSecretKey{}, errors.Wrap(err, "encode key part")
+	}
+	sk, err := json.Marshal(secret)
+	if err != nil {
+		return nil, data.RecipientSecretKey{}, errors.Wrap(err, "encode derived key")
+	}
+	return part, data.RecipientSecretKey{I: i, DerivedKey: sk}, nil
+}
+
+func (ECDDH) CombineKeyParts(params json.RawMessage, parts []json.RawMessage) (data.MPK, error) {
+	ecParams, err := decodeECDDHParams(params)
+	if err != nil {
+		return data.MPK{}, err
+	}
+	if len(parts) != ecParams.L {
+		return data.MPK{}, errors.Errorf("expected %d key parts, got %d", ecParams.L, len(parts))
+	}
+
+	vector := make([][]byte, len(parts))
+	for i, partJSON := range parts {
+		var part ecddhKeyPart
+		if err := json.Unmarshal(partJSON, &part); err != nil {
+			return data.MPK{}, errors.Wrapf(err, "decode key part %d", i)
+		}
+		if part.Response == nil {
+			return data.MPK{}, errors.Errorf("key part %d is malformed", i)
+		}
+		h, err := unmarshalPoint(part.H)
+		if err != nil {
+			return data.MPK{}, errors.Wrapf(err, "key part %d is malformed", i)
+		}
+		commitment, err := unmarshalPoint(part.Commitment)
+		if err != nil {
+			return data.MPK{}, errors.Wrapf(err, "key part %d is malformed", i)
+		}
+
+		// response*G == commitment + c*H
+		c := ecddhKeyPartChallenge(i, part.H, part.Commitment)
+		lhs := new(bn256.G1).ScalarBaseMult(part.Response)
+		rhs := new(bn256.G1).Add(commitment, new(bn256.G1).ScalarMult(h, c))
+		if string(lhs.Marshal()) != string(rhs.Marshal()) {
+			return data.MPK{}, errors.Errorf("key part %d has invalid proof of knowledge", i)
+		}
+		vector[i] = part.H
+	}
+
+	mpkJSON, err := json.Marshal(ecddhMPK{Bound: ecParams.Bound, Vector: vector})
+	if err != nil {
+		return data.MPK{}, errors.Wrap(err, "encode mpk")
+	}
+	return data.MPK{Scheme: ECDDHSchemeID, L: ecParams.L, Key: mpkJSON}, nil
+}
+
+func ecddhKeyPartChallenge(i int, h, commitment []byte) *big.Int {
+	return challenge(bn256.Order, ecddhKeyPartDomain,
+		big.NewInt(int64(i)), new(big.Int).SetBytes(h), new(big.Int).SetBytes(commitment))
+}
+
+func decodeECDDHParams(params json.RawMessage) (*ecddhParams, error) {
+	var ecParams ecddhParams
+	if err := json.Unmarshal(params, &ecParams); err != nil {
+		return nil, errors.Wrap(err, "decode params")
+	}
+	if ecParams.L <= 0 || ecParams.Bound == nil || ecParams.Bound.Sign() <= 0 {
+		return nil, errors.New("malformed params")
+	}
+	return &ecParams, nil
+}
+
+var _ ThresholdScheme = ECDDH{}
+
+func (ECDDH) MasterKeyVectors(mpk data.MPK, msk data.MSK) ([]gofe.Vector, *big.Int, error) {
+	if _, _, err := decodeECDDHMPK(mpk); err != nil {
+		return nil, nil, err
+	}
+	if msk.Scheme != ECDDHSchemeID {
+		return nil, nil, errors.Errorf("expected %s msk, got %s", ECDDHSchemeID, msk.Scheme)
+	}
+	var key ecddhMSK
+	if err := json.Unmarshal(msk.Key, &key); err != nil {
+		return nil, nil, errors.Wrap(err, "decode msk")
+	}
+	return []gofe.Vector{key.Vector}, bn256.Order, nil
+}
+
+func (ECDDH) MasterKeyModulus(mpk data.MPK) (*big.Int, error) {
+	if _, _, err := decodeECDDHMPK(mpk); err != nil {
+		return nil, err
+	}
+	return bn256.Order, nil
+}
+
+// For y = e_i, derived key is <msk, y> = msk_i
+func (ECDDH) EncodeDerivedKey(_ data.MPK, i int, key gofe.Vector) (data.RecipientSecretKey, error) {
+	if len(key) != 1 {
+		return data.RecipientSecretKey{}, errors.New("expected key of 1 element")
+	}
+	skJSON, err := json.Marshal(key[0])
+	if err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "encode derived key")
+	}
+	return data.RecipientSecretKey{I: i, DerivedKey: skJSON}, nil
+}
+
+var _ ProvableScheme = ECDDH{}
+
+func (ECDDH) EncryptOneHot(random io.Reader, mpk data.MPK, i int) (data.Ciphertext, json.RawMessage, error) {
+	_, h, err := decodeECDDHMPK(mpk)
+	if err != nil {
+		return data.Ciphertext{}, nil, err
+	}
+	elements, proof, err := encryptOneHot(random, g1Group{}, g1Elements(h), i, []byte(ECDDHSchemeID))
+	if err != nil {
+		return data.Ciphertext{}, nil, err
+	}
+
+	points := make([][]byte, len(elements))
+	for j, e := range elements {
+		points[j] = marshalPoint(e.(*bn256.G1))
+	}
+	return data.Ciphertext{Points: points}, proof, nil
+}
+
+func (ECDDH) VerifyOneHot(mpk data.MPK, ciphertext *data.Ciphertext, proof json.RawMessage) error {
+	_, h, err := decodeECDDHMPK(mpk)
+	if err != nil {
+		return err
+	}
+	elements, err := ecddhCiphertextElements(mpk, ciphertext)
+	if err != nil {
+		return err
+	}
+	return verifyOneHot(g1Group{}, g1Elements(h), elements, proof, []byte(ECDDHSchemeID))
+}
+
+var _ AmountScheme = ECDDH{}
+
+func (ECDDH) EncryptAmount(random io.Reader, mpk data.MPK, i int, amount, bound int64) (data.Ciphertext, json.RawMessage, error) {
+	_, h, err := decodeECDDHMPK(mpk)
+	if err != nil {
+		return data.Ciphertext{}, nil, err
+	}
+	elements, proof, err := encryptAmount(random, g1Group{}, g1Elements(h), i, amount, bound, []byte(ECDDHSchemeID))
+	if err != nil {
+		return data.Ciphertext{}, nil, err
+	}
+
+	points := make([][]byte, len(elements))
+	for j, e := range elements {
+		points[j] = marshalPoint(e.(*bn256.G1))
+	}
+	return data.Ciphertext{Points: points}, proof, nil
+}
+
+func (ECDDH) VerifyAmount(mpk data.MPK, ciphertext *data.Ciphertext, bound int64, proof json.RawMessage) error {
+	_, h, err := decodeECDDHMPK(mpk)
+	if err != nil {
+		return err
+	}
+	elements, err := ecddhCiphertextElements(mpk, ciphertext)
+	if err != nil {
+		return err
+	}
+	return verifyAmount(g1Group{}, g1Elements(h), elements, bound, proof, []byte(ECDDHSchemeID))
+}
+
+// Returns ciphertext as points of G1
+func ecddhCiphertextElements(mpk data.MPK, ciphertext *data.Ciphertext) ([]interface{}, error) {
+	if len(ciphertext.Points) != mpk.L+1 {
+		return nil, errors.New("ciphertext has wrong length")
+	}
+	elements := make([]interface{}, len(ciphertext.Points))
+	for j, bytes := range ciphertext.Points {
+		point, err := unmarshalPoint(bytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "malformed ciphertext point %d", j)
+		}
+		elements[j] = point
+	}
+	return elements, nil
+}
+
+func g1Elements(points []*bn256.G1) []interface{} {
+	elements := make([]interface{}, len(points))
+	for j, point := range points {
+		elements[j] = point
+	}
+	return elements
+}
+
+var _ ConfigurableScheme = ECDDH{}
+
+// Curve is fixed, so modulus bits don't affect parameters
+func (ECDDH) Configure(profile Profile) (Scheme, data.Profile, error) {
+	scheme := ECDDH{Bound: big.NewInt(profile.MaxSignals)}
+	return scheme, data.Profile{ModulusBits: 256, SecurityBits: bn256SecurityBits, MaxSignals: profile.MaxSignals}, nil
+}
+
+var _ BoundedScheme = ECDDH{}
+
+// Capacity is the bound of discrete logarithm solved by Decrypt
+func (s ECDDH) Capacity(mpk data.MPK) (*big.Int, error) {
+	params, err := s.DlogParams(mpk)
+	if err != nil {
+		return nil, err
+	}
+	return params.Bound, nil
+}
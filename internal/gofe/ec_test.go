@@ -0,0 +1,90 @@
+package gofe
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/fentec-project/bn256"
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPointMarshalling(t *testing.T) {
+	points := []*bn256.G1{
+		new(bn256.G1).ScalarBaseMult(big.NewInt(0)),
+		new(bn256.G1).ScalarBaseMult(big.NewInt(1)),
+		new(bn256.G1).ScalarBaseMult(new(big.Int).Sub(bn256.Order, big.NewInt(1))),
+	}
+	for i := 0; i < 10; i++ {
+		_, point, err := bn256.RandomG1(rand.Reader)
+		if err != nil {
+			panic(err)
+		}
+		points = append(points, point)
+	}
+
+	for _, point := range points {
+		bytes := marshalPoint(point)
+		assert.Len(t, bytes, 33)
+		point2, err := unmarshalPoint(bytes)
+		assert.NoError(t, err, "unmarshal point")
+		assert.Equal(t, point.Marshal(), point2.Marshal())
+	}
+
+	t.Run("Malformed points are rejected", func(t *testing.T) {
+		_, err := unmarshalPoint(make([]byte, 32))
+		assert.Error(t, err, "wrong length")
+		bytes := marshalPoint(points[3])
+		bytes[0] = 0x05
+		_, err = unmarshalPoint(bytes)
+		assert.Error(t, err, "wrong prefix")
+		bytes = make([]byte, 33)
+		bytes[0] = pointEvenY
+		copy(bytes[1:], bn256P.Bytes())
+		_, err = unmarshalPoint(bytes)
+		assert.Error(t, err, "x out of field")
+	})
+}
+
+// Reports size of a round (JSON-encoded accumulated ciphertext) produced by
+// Z_p and elliptic curve DDH schemes
+func BenchmarkRoundSize(b *testing.B) {
+	for _, id := range []string{DDHSchemeID, ECDDHSchemeID} {
+		for _, parties := range []int{5, 50} {
+			scheme, err := LookupScheme(id)
+			if err != nil {
+				panic(err)
+			}
+			mpk, _, err := GenerateMasterKeysWith(scheme, parties)
+			if err != nil {
+				panic(err)
+			}
+			x := gofe.NewConstantVector(parties, big.NewInt(0))
+			x[0] = big.NewInt(1)
+
+			b.Run(fmt.Sprintf("%s/parties=%d", id, parties), func(b *testing.B) {
+				acc, err := Encrypt(mpk, x)
+				if err != nil {
+					panic(err)
+				}
+				for i := 0; i < b.N; i++ {
+					delta, err := Encrypt(mpk, x)
+					if err != nil {
+						panic(err)
+					}
+					if err = Accumulate(mpk, &acc, &delta); err != nil {
+						panic(err)
+					}
+				}
+				round, err := json.Marshal(acc)
+				if err != nil {
+					panic(err)
+				}
+				b.ReportMetric(float64(len(round)), "bytes/round")
+			})
+		}
+	}
+}
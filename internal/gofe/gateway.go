@@ -1,6 +1,11 @@
 package gofe
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
 	"math/big"
 
 	gofe "github.com/fentec-project/gofe/data"
@@ -10,45 +15,498 @@ import (
 	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
 )
 
+// Generates master keys using DefaultScheme and derives key for every party
 func GenerateMasterKeys(parties int) (data.MPK, []data.RecipientSecretKey, error) {
-	ddh, err := simple.NewDDH(parties, 512, big.NewInt(1024))
+	return GenerateMasterKeysWith(DefaultScheme, parties)
+}
+
+// Generates master keys using given scheme and derives key for every party
+func GenerateMasterKeysWith(scheme Scheme, parties int) (data.MPK, []data.RecipientSecretKey, error) {
+	return GenerateMasterKeysFrom(rand.Reader, scheme, parties)
+}
+
+// Generates master keys using given scheme and randomness source, and derives
+// key for every party
+//
+// Scheme must implement SeedableScheme unless random is crypto/rand.Reader.
+func GenerateMasterKeysFrom(random io.Reader, scheme Scheme, parties int) (data.MPK, []data.RecipientSecretKey, error) {
+	mpk, msk, err := setup(random, scheme, parties)
 	if err != nil {
-		return data.MPK{}, nil, errors.Wrap(err, "scheme could not be properly configured")
+		return data.MPK{}, nil, errors.Wrap(err, "setup")
 	}
-	return GenerateMasterKeysDDH(ddh)
+	return deriveKeys(scheme, mpk, msk)
 }
 
-func GenerateMasterKeysDDH(ddh *simple.DDH) (data.MPK, []data.RecipientSecretKey, error) {
-	msk, mpk, err := ddh.GenerateMasterKeys()
+// Generates master keys using preconfigured simple.DDH instance and given
+// randomness source, and derives key for every party
+func GenerateMasterKeysDDH(random io.Reader, ddh *simple.DDH) (data.MPK, []data.RecipientSecretKey, error) {
+	mpk, msk, err := setupDDHFrom(random, ddh)
 	if err != nil {
-		return data.MPK{}, nil, errors.Wrap(err, "generate master key")
+		return data.MPK{}, nil, err
+	}
+	return deriveKeys(DDH{}, mpk, msk)
+}
+
+func setup(random io.Reader, scheme Scheme, parties int) (data.MPK, data.MSK, error) {
+	if isSystemRandom(random) {
+		return scheme.Setup(parties)
+	}
+	seedable, ok := scheme.(SeedableScheme)
+	if !ok {
+		return data.MPK{}, data.MSK{}, errNotSeedable(scheme)
 	}
+	return seedable.SetupFrom(random, parties)
+}
 
-	secretKeys := make([]data.RecipientSecretKey, 0)
-	for j := 0; j < ddh.Params.L; j++ {
-		y := gofe.NewConstantVector(ddh.Params.L, big.NewInt(0))
-		y[j] = big.NewInt(1)
+func encrypt(random io.Reader, scheme Scheme, mpk data.MPK, x gofe.Vector) (data.Ciphertext, error) {
+	if isSystemRandom(random) {
+		return scheme.Encrypt(mpk, x)
+	}
+	seedable, ok := scheme.(SeedableScheme)
+	if !ok {
+		return data.Ciphertext{}, errNotSeedable(scheme)
+	}
+	return seedable.EncryptFrom(random, mpk, x)
+}
+
+// Generates master keys for `slots` recipients using given scheme, but derives
+// keys only for the first `parties` of them
+//
+// The rest of slots are reserved for recipients added later, their keys are
+// derived from returned msk by DeriveKey.
+func GenerateReservedKeys(random io.Reader, scheme Scheme, parties, slots int) (data.MPK, data.MSK, []data.RecipientSecretKey, error) {
+	if parties < 1 || parties > slots {
+		return data.MPK{}, data.MSK{}, nil, errors.Errorf("expected parties in range [1; %d]", slots)
+	}
+	mpk, msk, err := setup(random, scheme, slots)
+	if err != nil {
+		return data.MPK{}, data.MSK{}, nil, errors.Wrap(err, "setup")
+	}
+	_, sk, err := deriveKeysUpTo(scheme, mpk, msk, parties)
+	if err != nil {
+		return data.MPK{}, data.MSK{}, nil, err
+	}
+	return mpk, msk, sk, nil
+}
+
+// Generates fresh master keys of the same scheme, length, security profile
+// and max amount as given mpk has
+//
+// Keys of recipients are to be derived from returned msk by DeriveKey.
+func RegenerateMasterKeys(random io.Reader, mpk data.MPK) (data.MPK, data.MSK, error) {
+	scheme, err := schemeOf(mpk)
+	if err != nil {
+		return data.MPK{}, data.MSK{}, err
+	}
+	var security *data.Profile
+	if mpk.Profile != nil {
+		var configured data.Profile
+		scheme, configured, _, err = ConfigureScheme(scheme, ProfileOf(*mpk.Profile))
+		if err != nil {
+			return data.MPK{}, data.MSK{}, errors.Wrap(err, "configure scheme")
+		}
+		security = &configured
+	}
+	regenerated, msk, err := setup(random, scheme, mpk.L)
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "setup")
+	}
+	regenerated.Profile = security
+	regenerated.MaxAmount = mpk.MaxAmount
+	return regenerated, msk, nil
+}
+
+// Derives key of i-th recipient (0-indexed) from msk
+func DeriveKey(mpk data.MPK, msk data.MSK, i int) (data.RecipientSecretKey, error) {
+	scheme, err := schemeOf(mpk)
+	if err != nil {
+		return data.RecipientSecretKey{}, err
+	}
+	if msk.Scheme != mpk.Scheme {
+		return data.RecipientSecretKey{}, errors.Errorf("msk of scheme %s doesn't match mpk of scheme %s", msk.Scheme, mpk.Scheme)
+	}
+	if i < 0 || i >= mpk.L {
+		return data.RecipientSecretKey{}, errors.Errorf("expected recipient in range [1; %d]", mpk.L)
+	}
+	return scheme.DeriveKey(mpk, msk, i)
+}
+
+// Checks that sk is key of i-th recipient (0-indexed) derived under mpk
+//
+// Check needs nothing but mpk, so recipient can run it without trusting
+// authority. Returns error if scheme doesn't implement VerifiableKeyScheme,
+// as keys of such scheme can't be checked.
+func VerifyKey(mpk data.MPK, sk data.RecipientSecretKey, i int) error {
+	scheme, err := schemeOf(mpk)
+	if err != nil {
+		return err
+	}
+	if i < 0 || i >= mpk.L {
+		return errors.Errorf("expected recipient in range [1; %d]", mpk.L)
+	}
+	if sk.I != i {
+		return errors.Errorf("key is issued for recipient %d, not %d", sk.I+1, i+1)
+	}
+	verifiable, ok := scheme.(VerifiableKeyScheme)
+	if !ok {
+		return errors.Errorf("scheme %s doesn't allow verifying derived keys", mpk.Scheme)
+	}
+	return verifiable.VerifyKey(mpk, sk)
+}
 
-		sk, err := ddh.DeriveKey(msk, y)
+func deriveKeys(scheme Scheme, mpk data.MPK, msk data.MSK) (data.MPK, []data.RecipientSecretKey, error) {
+	return deriveKeysUpTo(scheme, mpk, msk, mpk.L)
+}
+
+func deriveKeysUpTo(scheme Scheme, mpk data.MPK, msk data.MSK, parties int) (data.MPK, []data.RecipientSecretKey, error) {
+	secretKeys := make([]data.RecipientSecretKey, 0)
+	for j := 0; j < parties; j++ {
+		sk, err := scheme.DeriveKey(mpk, msk, j)
 		if err != nil {
 			return data.MPK{}, nil, errors.Wrapf(err, "generate sk for party %d", j+1)
 		}
-		secretKeys = append(secretKeys, data.RecipientSecretKey{I: j, DerivedKey: sk})
+		secretKeys = append(secretKeys, sk)
 	}
 
-	return data.MPK{DDH: ddh, Vector: mpk}, secretKeys, nil
+	return mpk, secretKeys, nil
 }
 
 func Encrypt(mpk data.MPK, vector gofe.Vector) (data.Ciphertext, error) {
-	ciphertext, err := mpk.DDH.Encrypt(gofe.NewVector(vector), mpk.Vector)
+	return EncryptFrom(rand.Reader, mpk, vector)
+}
+
+// Encrypts vector drawing randomness from given source
+//
+// Scheme must implement SeedableScheme unless random is crypto/rand.Reader.
+func EncryptFrom(random io.Reader, mpk data.MPK, vector gofe.Vector) (data.Ciphertext, error) {
+	scheme, err := schemeOf(mpk)
 	if err != nil {
 		return data.Ciphertext{}, err
 	}
-	return data.Ciphertext{Vector: ciphertext}, nil
+	return encrypt(random, scheme, mpk, vector)
+}
+
+// Encrypts signal to i-th recipient (0-indexed), i.e. unit vector e_i
+//
+// If scheme is able to prove that ciphertext is one-hot, the proof is
+// returned as well, otherwise it's nil.
+func EncryptSignal(mpk data.MPK, i int) (data.Ciphertext, json.RawMessage, error) {
+	return EncryptSignalFrom(rand.Reader, mpk, i)
+}
+
+// Same as EncryptSignal, but randomness of ciphertext and proof is drawn
+// from given source
+func EncryptSignalFrom(random io.Reader, mpk data.MPK, i int) (data.Ciphertext, json.RawMessage, error) {
+	scheme, err := schemeOf(mpk)
+	if err != nil {
+		return data.Ciphertext{}, nil, err
+	}
+	if i < 0 || i >= mpk.L {
+		return data.Ciphertext{}, nil, errors.Errorf("expected recipient in range [1; %d]", mpk.L)
+	}
+	if mpk.MaxAmount > 0 {
+		// Rounds of such mpk must carry amount proofs
+		return EncryptAmountFrom(random, mpk, i, 1)
+	}
+	if provable, ok := scheme.(ProvableScheme); ok {
+		return provable.EncryptOneHot(random, mpk, i)
+	}
+	ciphertext, err := encrypt(random, scheme, mpk, unitVector(mpk.L, i))
+	return ciphertext, nil, err
+}
+
+// Encrypts amount to i-th recipient (0-indexed), i.e. vector amount * e_i,
+// along with proof that amount is in range [1; mpk.MaxAmount]
+//
+// Mpk must allow amounts, i.e. its MaxAmount must be positive.
+func EncryptAmountFrom(random io.Reader, mpk data.MPK, i int, amount int64) (data.Ciphertext, json.RawMessage, error) {
+	scheme, err := schemeOf(mpk)
+	if err != nil {
+		return data.Ciphertext{}, nil, err
+	}
+	if i < 0 || i >= mpk.L {
+		return data.Ciphertext{}, nil, errors.Errorf("expected recipient in range [1; %d]", mpk.L)
+	}
+	if mpk.MaxAmount <= 0 {
+		return data.Ciphertext{}, nil, errors.New("mpk doesn't allow amounts")
+	}
+	if amount < 1 || amount > mpk.MaxAmount {
+		return data.Ciphertext{}, nil, errors.Errorf("expected amount in range [1; %d]", mpk.MaxAmount)
+	}
+	amounts, ok := scheme.(AmountScheme)
+	if !ok {
+		return data.Ciphertext{}, nil, errors.Errorf("scheme %s can't carry amounts", mpk.Scheme)
+	}
+	return amounts.EncryptAmount(random, mpk, i, amount, mpk.MaxAmount)
+}
+
+// Verifies that round is obtained by accumulating its delta ciphertext into
+// previous round (which is nil for the first round), and that delta encrypts
+// one-hot vector, or amount within [1; mpk.MaxAmount] if mpk allows amounts
+//
+// Schemes unable to prove that ciphertext is one-hot accept any delta
+// without proof. For others, proof is mandatory.
+func VerifyRound(mpk data.MPK, previous *data.Ciphertext, round *data.Round) error {
+	scheme, err := schemeOf(mpk)
+	if err != nil {
+		return err
+	}
+	if round.Delta == nil {
+		return errors.New("round doesn't carry delta ciphertext")
+	}
+	if err := ValidateCiphertext(mpk, round.Delta); err != nil {
+		return errors.Wrap(err, "delta")
+	}
+	if err := ValidateCiphertext(mpk, &round.Ciphertext); err != nil {
+		return err
+	}
+
+	expected := round.Delta.Copy()
+	if previous != nil {
+		expected = previous.Copy()
+		err = scheme.Accumulate(mpk, expected, round.Delta)
+		if err != nil {
+			return errors.Wrap(err, "accumulate delta into previous round")
+		}
+	}
+	if !expected.Equal(&round.Ciphertext) {
+		return errors.New("round is not equal to previous round accumulated with delta")
+	}
+
+	if mpk.MaxAmount > 0 {
+		amounts, ok := scheme.(AmountScheme)
+		if !ok {
+			return errors.Errorf("scheme %s can't carry amounts", mpk.Scheme)
+		}
+		if len(round.Proof) == 0 {
+			return errors.New("round doesn't carry amount proof")
+		}
+		return errors.Wrap(amounts.VerifyAmount(mpk, round.Delta, mpk.MaxAmount, round.Proof), "verify amount proof")
+	}
+	provable, ok := scheme.(ProvableScheme)
+	if !ok {
+		if len(round.Proof) != 0 {
+			return errors.Errorf("scheme %s doesn't support one-hot proofs", mpk.Scheme)
+		}
+		return nil
+	}
+	if len(round.Proof) == 0 {
+		return errors.New("round doesn't carry one-hot proof")
+	}
+	return errors.Wrap(provable.VerifyOneHot(mpk, round.Delta, round.Proof), "verify one-hot proof")
+}
+
+// Performs acc = acc + delta homomorphically, i.e. after that acc decrypts
+// to sum of plaintexts
+func Accumulate(mpk data.MPK, acc *data.Ciphertext, delta *data.Ciphertext) error {
+	scheme, err := schemeOf(mpk)
+	if err != nil {
+		return err
+	}
+	return scheme.Accumulate(mpk, acc, delta)
 }
 
 func Decrypt(mpk data.MPK, sk data.RecipientSecretKey, ciphertext *data.Ciphertext) (*big.Int, error) {
-	y := gofe.NewConstantVector(mpk.DDH.Params.L, big.NewInt(0))
-	y[sk.I] = big.NewInt(1)
-	return mpk.DDH.Decrypt(ciphertext.Vector, sk.DerivedKey, y)
+	scheme, err := schemeOf(mpk)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateCiphertext(mpk, ciphertext); err != nil {
+		return nil, err
+	}
+	return scheme.Decrypt(mpk, sk, ciphertext)
+}
+
+// Returns amount of rounds in accumulator epoch, 0 means that accumulator
+// never needs to be restarted
+//
+// Epoch is as long as scheme's capacity (or capacity recorded in mpk's profile
+// if it's smaller), so no recipient can receive more signals within an epoch
+// than it's able to decrypt. If signals carry amounts, capacity is divided by
+// max amount, as every round may add up to max amount to recipient's value.
+//
+// This holds only if every round carries a single signal, which VerifyRound
+// enforces for ProvableScheme only. Other schemes accept any delta, so a
+// malicious sender can overflow recipient's value within an epoch.
+func EpochLength(mpk data.MPK) (int, error) {
+	scheme, err := schemeOf(mpk)
+	if err != nil {
+		return 0, err
+	}
+	bounded, ok := scheme.(BoundedScheme)
+	if !ok {
+		return 0, nil
+	}
+	capacity, err := bounded.Capacity(mpk)
+	if err != nil {
+		return 0, err
+	}
+	if mpk.Profile != nil && mpk.Profile.MaxSignals > 0 && capacity.Cmp(big.NewInt(mpk.Profile.MaxSignals)) > 0 {
+		capacity = big.NewInt(mpk.Profile.MaxSignals)
+	}
+	if capacity.Sign() <= 0 {
+		return 0, errors.New("scheme has no capacity for signals")
+	}
+	if mpk.MaxAmount > 0 {
+		capacity = new(big.Int).Quo(capacity, big.NewInt(mpk.MaxAmount))
+		if capacity.Sign() == 0 {
+			return 0, errors.Errorf("scheme has no capacity for amounts up to %d", mpk.MaxAmount)
+		}
+	}
+	if capacity.Cmp(big.NewInt(math.MaxInt32)) > 0 {
+		// Such amount of rounds is never reached in practice
+		return 0, nil
+	}
+	return int(capacity.Int64()), nil
+}
+
+// Loads table of discrete logarithms for mpk from dir, building and saving it
+// on first use
+//
+// Table is identified by mpk's generator, modulus and bound, so a new mpk
+// never reuses a table built for another one. Returns nil table if scheme's
+// decryption doesn't involve discrete logarithm.
+func LoadDlogTable(dir string, mpk data.MPK) (*DlogTable, error) {
+	scheme, err := schemeOf(mpk)
+	if err != nil {
+		return nil, err
+	}
+	dlog, ok := scheme.(DlogScheme)
+	if !ok {
+		return nil, nil
+	}
+	params, err := dlog.DlogParams(mpk)
+	if err != nil {
+		return nil, err
+	}
+	return LoadOrBuildDlogTable(dir, params)
+}
+
+// Decrypts ciphertext using table of discrete logarithms loaded by
+// LoadDlogTable, nil table is the same as Decrypt
+func DecryptWithTable(mpk data.MPK, sk data.RecipientSecretKey, ciphertext *data.Ciphertext, table *DlogTable) (*big.Int, error) {
+	if err := ValidateCiphertext(mpk, ciphertext); err != nil {
+		return nil, err
+	}
+	return DecryptValidated(mpk, sk, ciphertext, table)
+}
+
+// The same as DecryptWithTable, but ciphertext isn't validated
+//
+// Ciphertext must already be checked by ValidateCiphertext, e.g. rounds
+// retrieved from rounds.Repository are.
+func DecryptValidated(mpk data.MPK, sk data.RecipientSecretKey, ciphertext *data.Ciphertext, table *DlogTable) (*big.Int, error) {
+	scheme, err := schemeOf(mpk)
+	if err != nil {
+		return nil, err
+	}
+	dlog, ok := scheme.(DlogScheme)
+	if !ok || table == nil {
+		return scheme.Decrypt(mpk, sk, ciphertext)
+	}
+	return decryptDlog(dlog, mpk, sk, ciphertext, table)
+}
+
+// Generates keys of every client of multi-client scheme and derives key for
+// every party out of key shares of all the clients
+func GenerateClientKeysWith(scheme MultiClientScheme, parties int) (data.MPK, []data.ClientKey, []data.RecipientSecretKey, error) {
+	mpk, clients, err := scheme.SetupClients(parties)
+	if err != nil {
+		return data.MPK{}, nil, nil, errors.Wrap(err, "setup clients")
+	}
+
+	secretKeys := make([]data.RecipientSecretKey, 0, parties)
+	for j := 0; j < parties; j++ {
+		sk, err := deriveKeyFromShares(scheme, mpk, clients, j)
+		if err != nil {
+			return data.MPK{}, nil, nil, errors.Wrapf(err, "generate sk for party %d", j+1)
+		}
+		secretKeys = append(secretKeys, sk)
+	}
+	return mpk, clients, secretKeys, nil
+}
+
+func deriveKeyFromShares(scheme MultiClientScheme, mpk data.MPK, clients []data.ClientKey, i int) (data.RecipientSecretKey, error) {
+	shares := make([]json.RawMessage, len(clients))
+	for j, client := range clients {
+		share, err := scheme.DeriveKeyShare(mpk, client, i)
+		if err != nil {
+			return data.RecipientSecretKey{}, errors.Wrapf(err, "derive key share by client %d", j+1)
+		}
+		shares[j] = share
+	}
+	return scheme.CombineKeyShares(mpk, i, shares)
+}
+
+// Encrypts vector using multi-client scheme: every client encrypts its own
+// slot under fresh random label
+//
+// clients[i] must be the key of i-th client.
+func EncryptWithClients(mpk data.MPK, clients []data.ClientKey, vector gofe.Vector) (data.Ciphertext, error) {
+	scheme, err := schemeOf(mpk)
+	if err != nil {
+		return data.Ciphertext{}, err
+	}
+	multiClient, ok := scheme.(MultiClientScheme)
+	if !ok {
+		return data.Ciphertext{}, errors.Errorf("scheme %s is not multi-client", mpk.Scheme)
+	}
+	if len(clients) != mpk.L || len(vector) != mpk.L {
+		return data.Ciphertext{}, errors.Errorf("expected %d clients and slots", mpk.L)
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return data.Ciphertext{}, errors.Wrap(err, "generate label")
+	}
+	label := hex.EncodeToString(nonce)
+
+	slots := make([][]byte, mpk.L)
+	for i, client := range clients {
+		if client.I != i {
+			return data.Ciphertext{}, errors.Errorf("expected key of client %d, got %d", i+1, client.I+1)
+		}
+		slots[i], err = multiClient.EncryptSlot(mpk, client, vector[i], label)
+		if err != nil {
+			return data.Ciphertext{}, errors.Wrapf(err, "encrypt slot %d", i+1)
+		}
+	}
+	return multiClient.CombineSlots(mpk, label, slots)
+}
+
+// Encrypts client's slot of plaintext using multi-client scheme, slots of
+// the same ciphertext are encrypted under the same label
+func EncryptSlot(mpk data.MPK, client data.ClientKey, x *big.Int, label string) ([]byte, error) {
+	multiClient, err := multiClientSchemeOf(mpk)
+	if err != nil {
+		return nil, err
+	}
+	if client.I < 0 || client.I >= mpk.L {
+		return nil, errors.Errorf("expected client in range [1; %d]", mpk.L)
+	}
+	return multiClient.EncryptSlot(mpk, client, x, label)
+}
+
+// Assembles ciphertext from slots encrypted by every client under given label
+func CombineSlots(mpk data.MPK, label string, slots [][]byte) (data.Ciphertext, error) {
+	multiClient, err := multiClientSchemeOf(mpk)
+	if err != nil {
+		return data.Ciphertext{}, err
+	}
+	if len(slots) != mpk.L {
+		return data.Ciphertext{}, errors.Errorf("expected %d slots, got %d", mpk.L, len(slots))
+	}
+	return multiClient.CombineSlots(mpk, label, slots)
+}
+
+func multiClientSchemeOf(mpk data.MPK) (MultiClientScheme, error) {
+	scheme, err := schemeOf(mpk)
+	if err != nil {
+		return nil, err
+	}
+	multiClient, ok := scheme.(MultiClientScheme)
+	if !ok {
+		return nil, errors.Errorf("scheme %s is not multi-client", mpk.Scheme)
+	}
+	return multiClient, nil
 }
@@ -1,40 +1,145 @@
 package gofe
 
 import (
+	"crypto/rand"
+	"encoding/json"
 	gofe "github.com/fentec-project/gofe/data"
 	"github.com/stretchr/testify/assert"
 	"math/big"
 	"testing"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
 )
 
-func TestGenerateMasterKeys(t *testing.T) {
-	f := func(n int) func(t *testing.T) {
-		return func(t *testing.T) {
-			mpk, sk, err := GenerateMasterKeys(n)
-			assert.NoError(t, err, "generate master keys, n", n)
-			assert.NotNil(t, mpk.DDH, "ddh is missing")
-			assert.Equal(t, mpk.DDH.Params.L, n, "wrong length of input vectors")
-			assert.NotNil(t, mpk.Vector, "mpk vector is missing")
-			assert.Len(t, sk, n, "wrong number of derived keys")
+// Schemes which generic tests run at toy parameters instead of registered
+// ones, as keygen and every encryption of registered LWE take seconds (see
+// lwe_test.go for tests at registered parameters)
+var testSchemes = map[string]Scheme{
+	LWESchemeID: LWE{N: 32, Bound: big.NewInt(lweMaxSignals)},
+}
+
+// Runs test against every registered scheme
+func forEachScheme(t *testing.T, test func(t *testing.T, scheme Scheme)) {
+	for _, id := range SchemeIDs() {
+		scheme, err := LookupScheme(id)
+		if err != nil {
+			panic(err)
 		}
+		if toy, ok := testSchemes[id]; ok {
+			scheme = toy
+		}
+		t.Run(id, func(t *testing.T) {
+			test(t, scheme)
+		})
 	}
-	t.Run("n=2", f(2))
-	t.Run("n=3", f(3))
-	t.Run("n=4", f(4))
-	t.Run("n=5", f(5))
 }
 
-func TestEncryptDecrypt(t *testing.T) {
-	mpk, sk, err := GenerateMasterKeys(5)
+// Generates keys for n parties, returned function encrypts under them
+//
+// Multi-client schemes can't encrypt using mpk alone, so their ciphertexts
+// are produced by all the clients.
+func setupScheme(t testing.TB, scheme Scheme, n int) (data.MPK, []data.RecipientSecretKey, func(gofe.Vector) (data.Ciphertext, error)) {
+	if multiClient, ok := scheme.(MultiClientScheme); ok {
+		mpk, clients, sk, err := GenerateClientKeysWith(multiClient, n)
+		assert.NoError(t, err, "keygen failed")
+		return mpk, sk, func(x gofe.Vector) (data.Ciphertext, error) {
+			return EncryptWithClients(mpk, clients, x)
+		}
+	}
+
+	mpk, sk, err := GenerateMasterKeysWith(scheme, n)
 	assert.NoError(t, err, "keygen failed")
+	return mpk, sk, func(x gofe.Vector) (data.Ciphertext, error) {
+		return Encrypt(mpk, x)
+	}
+}
+
+func TestGenerateMasterKeys(t *testing.T) {
+	mpk, sk, err := GenerateMasterKeys(2)
+	assert.NoError(t, err, "generate master keys")
+	assert.Equal(t, DefaultScheme.ID(), mpk.Scheme, "wrong scheme")
+	assert.Len(t, sk, 2, "wrong number of derived keys")
+
+	forEachScheme(t, func(t *testing.T, scheme Scheme) {
+		f := func(n int) func(t *testing.T) {
+			return func(t *testing.T) {
+				mpk, sk, err := GenerateMasterKeysWith(scheme, n)
+				assert.NoError(t, err, "generate master keys, n", n)
+				assert.Equal(t, scheme.ID(), mpk.Scheme, "wrong scheme")
+				assert.Equal(t, mpk.L, n, "wrong length of input vectors")
+				assert.NotEmpty(t, mpk.Key, "mpk key is missing")
+				assert.Len(t, sk, n, "wrong number of derived keys")
+			}
+		}
+		t.Run("n=2", f(2))
+		t.Run("n=3", f(3))
+		t.Run("n=4", f(4))
+		t.Run("n=5", f(5))
+	})
+}
+
+func TestGenerateReservedKeys(t *testing.T) {
+	forEachScheme(t, func(t *testing.T, scheme Scheme) {
+		if _, ok := scheme.(MultiClientScheme); ok {
+			t.Skip("multi-client scheme can't encrypt using mpk")
+		}
+		mpk, msk, sk, err := GenerateReservedKeys(rand.Reader, scheme, 2, 3)
+		assert.NoError(t, err, "generate reserved keys")
+		assert.Equal(t, 3, mpk.L, "wrong length of input vectors")
+		assert.Len(t, sk, 2, "wrong number of derived keys")
+
+		sk3, err := DeriveKey(mpk, msk, 2)
+		assert.NoError(t, err, "derive key of reserved slot")
+		ciphertext, err := Encrypt(mpk, unitVector(3, 2))
+		assert.NoError(t, err, "encrypt")
+		v, err := Decrypt(mpk, sk3, &ciphertext)
+		assert.NoError(t, err, "decrypt")
+		assert.Equal(t, big.NewInt(1), v)
+
+		_, err = DeriveKey(mpk, msk, 3)
+		assert.Error(t, err, "derived key of slot out of range")
+		_, _, _, err = GenerateReservedKeys(rand.Reader, scheme, 4, 3)
+		assert.Error(t, err, "more parties than slots")
+	})
+}
+
+func TestRegenerateMasterKeys(t *testing.T) {
+	scheme, profile, _, err := ConfigureScheme(DefaultScheme, Profile{ModulusBits: 512, MaxSignals: 100})
+	assert.NoError(t, err, "configure scheme")
+	mpk, _, err := GenerateMasterKeysWith(scheme, 3)
+	assert.NoError(t, err, "generate master keys")
+	mpk.Profile = &profile
+
+	regenerated, msk, err := RegenerateMasterKeys(rand.Reader, mpk)
+	assert.NoError(t, err, "regenerate master keys")
+	assert.Equal(t, mpk.Scheme, regenerated.Scheme)
+	assert.Equal(t, mpk.L, regenerated.L)
+	assert.Equal(t, mpk.Profile, regenerated.Profile, "profile isn't preserved")
+	assert.NotEqual(t, mpk.Key, regenerated.Key, "keys aren't regenerated")
+
+	sk, err := DeriveKey(regenerated, msk, 1)
+	assert.NoError(t, err, "derive key")
+	ciphertext, err := Encrypt(regenerated, unitVector(3, 1))
+	assert.NoError(t, err, "encrypt")
+	v, err := Decrypt(regenerated, sk, &ciphertext)
+	assert.NoError(t, err, "decrypt")
+	assert.Equal(t, big.NewInt(1), v)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	forEachScheme(t, testEncryptDecrypt)
+}
+
+func testEncryptDecrypt(t *testing.T, scheme Scheme) {
+	mpk, sk, encrypt := setupScheme(t, scheme, 5)
 
 	plaintext := gofe.NewConstantVector(5, big.NewInt(0))
 	plaintext[2] = big.NewInt(1)
 
-	ciphertext, err := Encrypt(mpk, plaintext)
+	ciphertext, err := encrypt(plaintext)
 	assert.NoError(t, err, "encrypt plaintext")
 
-	v, err := Decrypt(mpk, sk[2], ciphertext)
+	v, err := Decrypt(mpk, sk[2], &ciphertext)
 	assert.NoError(t, err, "decrypt using sk2")
 	assert.Equal(t, big.NewInt(1), v, "incorrectly decrypted v using sk2")
 
@@ -42,7 +147,7 @@ func TestEncryptDecrypt(t *testing.T) {
 		if j == 2 {
 			continue
 		}
-		v, err = Decrypt(mpk, sk[j], ciphertext)
+		v, err = Decrypt(mpk, sk[j], &ciphertext)
 		assert.NoError(t, err, "decrypt using sk", j)
 		assert.Equal(t, big.NewInt(0), v, "incorrectly decrypted v using sk", j)
 	}
@@ -50,8 +155,11 @@ func TestEncryptDecrypt(t *testing.T) {
 }
 
 func TestCiphertextIsMultiplicative(t *testing.T) {
-	mpk, sk, err := GenerateMasterKeys(2)
-	assert.NoError(t, err, "keygen failed")
+	forEachScheme(t, testCiphertextIsMultiplicative)
+}
+
+func testCiphertextIsMultiplicative(t *testing.T, scheme Scheme) {
+	mpk, sk, encrypt := setupScheme(t, scheme, 2)
 
 	// x1 = [1 0]
 	x1 := gofe.NewConstantVector(2, big.NewInt(0))
@@ -61,30 +169,33 @@ func TestCiphertextIsMultiplicative(t *testing.T) {
 	x2[1] = big.NewInt(1)
 
 	// e1 = Encrypt(mpk, x1)
-	e1, err := Encrypt(mpk, x1)
+	e1, err := encrypt(x1)
 	assert.NoError(t, err, "encrypt x1")
 
 	// e2 = e1 * Encrypt(mpk, x2)
-	e2, err := Encrypt(mpk, x2)
+	e2, err := encrypt(x2)
 	assert.NoError(t, err, "encrypt x1")
-	err = e2.Mul(&e1)
+	err = Accumulate(mpk, &e2, &e1)
 	assert.NoError(t, err, "e1*e2")
 
 	// check that Decrypt(mpk, sk0, e1) == 1
-	v1Sk0, err := Decrypt(mpk, sk[0], e1)
+	v1Sk0, err := Decrypt(mpk, sk[0], &e1)
 	assert.NoError(t, err, "decrypt e1 using sk0")
 	assert.Equal(t, big.NewInt(1), v1Sk0, "incorrectly decrypted e1 using sk0")
 
 	// check that Decrypt(mpk, sk0, e2) == 1
-	v2Sk0, err := Decrypt(mpk, sk[0], e2)
+	v2Sk0, err := Decrypt(mpk, sk[0], &e2)
 	assert.NoError(t, err, "decrypt e1*e2 using sk0")
 	assert.Equal(t, big.NewInt(1), v2Sk0, "incorrectly decrypted e1*e2 using sk0")
 }
 
 // Same as TestCiphertextIsMultiplicative but larger
 func TestCiphertextIsMultiplicative2(t *testing.T) {
-	mpk, sk, err := GenerateMasterKeys(5)
-	assert.NoError(t, err, "keygen failed")
+	forEachScheme(t, testCiphertextIsMultiplicative2)
+}
+
+func testCiphertextIsMultiplicative2(t *testing.T, scheme Scheme) {
+	mpk, sk, encrypt := setupScheme(t, scheme, 5)
 
 	plaintext1 := gofe.NewConstantVector(5, big.NewInt(0))
 	plaintext2 := gofe.NewConstantVector(5, big.NewInt(0))
@@ -94,20 +205,20 @@ func TestCiphertextIsMultiplicative2(t *testing.T) {
 	plaintext2[3] = big.NewInt(1)
 	plaintext3[2] = big.NewInt(1)
 
-	ciphertext1, err := Encrypt(mpk, plaintext1)
+	ciphertext1, err := encrypt(plaintext1)
 	assert.NoError(t, err, "encrypt plaintext1")
 
-	ciphertext2, err := Encrypt(mpk, plaintext2)
+	ciphertext2, err := encrypt(plaintext2)
 	assert.NoError(t, err, "encrypt plaintext2")
-	err = ciphertext2.Mul(&ciphertext1)
+	err = Accumulate(mpk, &ciphertext2, &ciphertext1)
 	assert.NoError(t, err, "ciphertext1*ciphertext2")
 
-	ciphertext3, err := Encrypt(mpk, plaintext3)
+	ciphertext3, err := encrypt(plaintext3)
 	assert.NoError(t, err, "encrypt plaintext3")
-	err = ciphertext3.Mul(&ciphertext2)
+	err = Accumulate(mpk, &ciphertext3, &ciphertext2)
 	assert.NoError(t, err, "ciphertext2*ciphertext3")
 
-	v1Sk2, err := Decrypt(mpk, sk[2], ciphertext1)
+	v1Sk2, err := Decrypt(mpk, sk[2], &ciphertext1)
 	assert.NoError(t, err, "decrypt ciphertext1 using sk2")
 	assert.Equal(t, big.NewInt(1), v1Sk2)
 
@@ -115,16 +226,16 @@ func TestCiphertextIsMultiplicative2(t *testing.T) {
 		if j == 2 {
 			continue
 		}
-		v1Skj, err := Decrypt(mpk, sk[j], ciphertext1)
+		v1Skj, err := Decrypt(mpk, sk[j], &ciphertext1)
 		assert.NoError(t, err, "decrypt ciphertext1 using skj", j)
 		assert.Equal(t, big.NewInt(0), v1Skj)
 	}
 
-	v2Sk2, err := Decrypt(mpk, sk[2], ciphertext2)
+	v2Sk2, err := Decrypt(mpk, sk[2], &ciphertext2)
 	assert.NoError(t, err, "decrypt ciphertext2 using sk2")
 	assert.Equal(t, big.NewInt(1), v2Sk2)
 
-	v2Sk3, err := Decrypt(mpk, sk[3], ciphertext2)
+	v2Sk3, err := Decrypt(mpk, sk[3], &ciphertext2)
 	assert.NoError(t, err, "decrypt ciphertext2 using sk3")
 	assert.Equal(t, big.NewInt(1), v2Sk3)
 
@@ -132,16 +243,16 @@ func TestCiphertextIsMultiplicative2(t *testing.T) {
 		if j == 2 || j == 3 {
 			continue
 		}
-		v2Skj, err := Decrypt(mpk, sk[j], ciphertext2)
+		v2Skj, err := Decrypt(mpk, sk[j], &ciphertext2)
 		assert.NoError(t, err, "decrypt ciphertext2 using skj", j)
 		assert.Equal(t, big.NewInt(0), v2Skj)
 	}
 
-	v3Sk2, err := Decrypt(mpk, sk[2], ciphertext3)
+	v3Sk2, err := Decrypt(mpk, sk[2], &ciphertext3)
 	assert.NoError(t, err, "decrypt ciphertext3 using sk2")
 	assert.Equal(t, big.NewInt(2), v3Sk2)
 
-	v3Sk3, err := Decrypt(mpk, sk[3], ciphertext3)
+	v3Sk3, err := Decrypt(mpk, sk[3], &ciphertext3)
 	assert.NoError(t, err, "decrypt ciphertext3 using sk3")
 	assert.Equal(t, big.NewInt(1), v3Sk3)
 
@@ -149,8 +260,81 @@ func TestCiphertextIsMultiplicative2(t *testing.T) {
 		if j == 2 || j == 3 {
 			continue
 		}
-		v3Skj, err := Decrypt(mpk, sk[j], ciphertext3)
+		v3Skj, err := Decrypt(mpk, sk[j], &ciphertext3)
 		assert.NoError(t, err, "decrypt ciphertext3 using skj", j)
 		assert.Equal(t, big.NewInt(0), v3Skj)
 	}
 }
+
+// Only provable schemes refuse a delta carrying more than one signal, others
+// accept it, so they can't guarantee that no value overflows within an epoch
+func TestVerifyRoundOverflow(t *testing.T) {
+	forEachScheme(t, func(t *testing.T, scheme Scheme) {
+		mpk, _, encrypt := setupScheme(t, scheme, 3)
+
+		// Single round counts as two signals to party 1
+		plaintext := gofe.NewConstantVector(3, big.NewInt(0))
+		plaintext[1] = big.NewInt(2)
+		delta, err := encrypt(plaintext)
+		assert.NoError(t, err, "encrypt delta")
+		err = VerifyRound(mpk, nil, &data.Round{Ciphertext: *delta.Copy(), Delta: &delta})
+		if _, ok := scheme.(ProvableScheme); ok {
+			assert.Error(t, err, "unproven delta is accepted")
+		} else {
+			assert.NoError(t, err, "unproven delta is refused")
+		}
+	})
+}
+
+func TestVerifyRoundChain(t *testing.T) {
+	forEachScheme(t, testVerifyRoundChain)
+}
+
+func testVerifyRoundChain(t *testing.T, scheme Scheme) {
+	mpk, _, encrypt := setupScheme(t, scheme, 3)
+	signal := func(i int) (data.Ciphertext, json.RawMessage) {
+		if _, ok := scheme.(ProvableScheme); ok {
+			delta, proof, err := EncryptSignal(mpk, i)
+			assert.NoError(t, err, "encrypt signal")
+			return delta, proof
+		}
+		delta, err := encrypt(unitVector(3, i))
+		assert.NoError(t, err, "encrypt signal")
+		return delta, nil
+	}
+
+	delta1, proof1 := signal(0)
+	round1 := &data.Round{Ciphertext: *delta1.Copy(), Delta: &delta1, Proof: proof1}
+	assert.NoError(t, VerifyRound(mpk, nil, round1), "verify round 1")
+
+	delta2, proof2 := signal(2)
+	ciphertext2 := round1.Ciphertext.Copy()
+	assert.NoError(t, Accumulate(mpk, ciphertext2, &delta2), "accumulate")
+	round2 := &data.Round{Ciphertext: *ciphertext2, Delta: &delta2, Proof: proof2}
+	assert.NoError(t, VerifyRound(mpk, &round1.Ciphertext, round2), "verify round 2")
+
+	t.Run("Missing delta", func(t *testing.T) {
+		err := VerifyRound(mpk, &round1.Ciphertext, &data.Round{Ciphertext: *ciphertext2, Proof: proof2})
+		assert.Error(t, err)
+	})
+
+	t.Run("Round doesn't extend previous one", func(t *testing.T) {
+		err := VerifyRound(mpk, &round2.Ciphertext, round2)
+		assert.Error(t, err)
+	})
+
+	t.Run("Chain is reset", func(t *testing.T) {
+		// Round 2 drops everything accumulated in round 1
+		err := VerifyRound(mpk, &round1.Ciphertext, &data.Round{Ciphertext: delta2, Delta: &delta2, Proof: proof2})
+		assert.Error(t, err)
+	})
+
+	t.Run("Published round is re-randomized", func(t *testing.T) {
+		zero, err := encrypt(gofe.NewConstantVector(3, big.NewInt(0)))
+		assert.NoError(t, err, "encrypt zero vector")
+		rerandomized := round2.Ciphertext.Copy()
+		assert.NoError(t, Accumulate(mpk, rerandomized, &zero), "accumulate zero vector")
+		err = VerifyRound(mpk, &round1.Ciphertext, &data.Round{Ciphertext: *rerandomized, Delta: &delta2, Proof: proof2})
+		assert.Error(t, err, "re-randomized round doesn't break the chain")
+	})
+}
@@ -0,0 +1,169 @@
+package gofe
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"math/big"
+
+	"github.com/fentec-project/gofe/sample"
+)
+
+// Discrete Gaussian sampler centered on 0 with sigma = l * sqrt(1/2ln(2))
+//
+// It's the same FACCT sampler as sample.NormalDoubleConstant of gofe (see
+// "FACCT: FAst, Compact, and Constant-Time Discrete Gaussian Sampler over
+// Integers" by R. K. Zhao, R. Steinfeld and A. Sakzad), but draws all the
+// randomness from given source, so keys of LWE and Paillier schemes can be
+// reproduced.
+type gaussianSampler struct {
+	random     io.Reader
+	l          *big.Int
+	twiceL     *big.Int
+	lSquareInv *big.Float
+}
+
+var _ sample.Sampler = &gaussianSampler{}
+
+func newGaussianSampler(random io.Reader, l *big.Int) *gaussianSampler {
+	lSquare := new(big.Float).SetInt(l)
+	lSquare.Mul(lSquare, lSquare)
+	return &gaussianSampler{
+		random:     random,
+		l:          new(big.Int).Set(l),
+		twiceL:     new(big.Int).Lsh(l, 1),
+		lSquareInv: new(big.Float).Quo(big.NewFloat(1), lSquare),
+	}
+}
+
+func (s *gaussianSampler) Sample() (*big.Int, error) {
+	res, check := new(big.Int), new(big.Int)
+	for {
+		// Sample of half-Gaussian with small sigma is scaled by l and
+		// shifted by uniform y, then accepted with probability
+		// 2^(-y(y + 2lx)/l^2)
+		x, err := sampleHalfGaussianCDT(s.random)
+		if err != nil {
+			return nil, err
+		}
+		y, err := sampleRange(s.random, big.NewInt(0), s.twiceL)
+		if err != nil {
+			return nil, err
+		}
+		sign := int64(1)
+		if y.Cmp(s.l) >= 0 {
+			sign = -1
+			y.Sub(y, s.l)
+		}
+
+		res.Mul(s.l, x)
+		check.Lsh(res, 1).Add(check, y).Mul(check, y)
+		res.Add(res, y)
+
+		accept, err := sampleBernoulli(s.random, check, s.lSquareInv)
+		if err != nil {
+			return nil, err
+		}
+		// One of two zeros is rejected, so that zero isn't sampled twice
+		// as often
+		if accept && !(sign == 1 && res.Sign() == 0) {
+			return res.Mul(res, big.NewInt(sign)), nil
+		}
+	}
+}
+
+// Cumulative distribution table of half-Gaussian with sigma =
+// sqrt(1/2ln(2)), the same as gofe uses
+var cdtTable = [][2]uint64{
+	{2200310400551559144, 3327841033070651387},
+	{7912151619254726620, 380075531178589176},
+	{5167367257772081627, 11604843442081400},
+	{5081592746475748971, 90134450315532},
+	{6522074513864805092, 175786317361},
+	{2579734681240182346, 85801740},
+	{8175784047440310133, 10472},
+	{2947787991558061753, 0},
+	{22489665999543, 0},
+}
+
+const cdtLowMask uint64 = 0x7fffffffffffffff
+
+// Samples non-negative x with probability proportional to 2^(-x^2) in
+// constant time
+func sampleHalfGaussianCDT(random io.Reader) (*big.Int, error) {
+	var bytes [16]byte
+	if _, err := io.ReadFull(random, bytes[:]); err != nil {
+		return nil, err
+	}
+	r1 := binary.LittleEndian.Uint64(bytes[:8]) & cdtLowMask
+	r2 := binary.LittleEndian.Uint64(bytes[8:]) & cdtLowMask
+
+	x := uint64(0)
+	for _, entry := range cdtTable {
+		x += (((r1 - entry[0]) & ((uint64(1) << 63) ^ ((r2 - entry[1]) | (entry[1] - r2)))) | (r2 - entry[1])) >> 63
+	}
+	return new(big.Int).SetUint64(x), nil
+}
+
+// Coefficients of polynomial approximating 2^z for z in [0; 1)
+var expCoef = []float64{
+	1.43291003789439094275872613876154915146798884961754e-7,
+	1.2303944375555413249736938854916878938183799618855e-6,
+	1.5359914219462011698283041005730353845137869939208e-5,
+	1.5396043210538638053991311593904356413986533880234e-4,
+	1.3333877552501097445841748978523355617653578519821e-3,
+	9.6181209331756452318717975913386908359825611114502e-3,
+	5.5504109841318247098307381293125217780470848083496e-2,
+	0.24022650687652774559310842050763312727212905883789,
+	0.69314718056193380668617010087473317980766296386719,
+	1,
+}
+
+const (
+	mantissaPrecision = uint64(52)
+	mantissaMask      = (uint64(1) << mantissaPrecision) - 1
+	bitLenForSample   = uint64(19)
+	maxExp            = uint64(1023)
+	cmpMask           = uint64(1) << 61
+)
+
+// Returns true with probability 2^(-t/l^2) in constant time
+func sampleBernoulli(random io.Reader, t *big.Int, lSquareInv *big.Float) (bool, error) {
+	aBig := new(big.Float).SetInt(t)
+	aBig.Mul(aBig, lSquareInv)
+	a, _ := aBig.Float64()
+	a = -a
+
+	negFloorA := -math.Floor(a)
+	z := a + negFloorA
+
+	powOfZ := expCoef[0]
+	for i := 1; i < len(expCoef); i++ {
+		powOfZ = powOfZ*z + expCoef[i]
+	}
+	powOfAMantissa := math.Float64bits(powOfZ) & mantissaMask
+	powOfAExponent := (math.Float64bits(powOfZ) >> mantissaPrecision) - uint64(negFloorA)
+
+	var bytes [16]byte
+	if _, err := io.ReadFull(random, bytes[:]); err != nil {
+		return false, err
+	}
+	r1 := binary.LittleEndian.Uint64(bytes[:8]) >> (64 - (mantissaPrecision + 1))
+	r2 := binary.LittleEndian.Uint64(bytes[8:]) >> (64 - bitLenForSample)
+
+	check1 := powOfAMantissa | (uint64(1) << mantissaPrecision)
+	check2 := uint64(1) << (bitLenForSample + powOfAExponent + 1 - maxExp)
+	return (cmpMask&(r1-check1)&(r2-check2)) > 0 || powOfZ == 1, nil
+}
+
+// Samples uniformly from [min; max) drawing randomness from given source
+type uniformSampler struct {
+	random   io.Reader
+	min, max *big.Int
+}
+
+var _ sample.Sampler = uniformSampler{}
+
+func (s uniformSampler) Sample() (*big.Int, error) {
+	return sampleRange(s.random, s.min, s.max)
+}
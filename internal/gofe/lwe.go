@@ -0,0 +1,570 @@
+package gofe
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"math/big"
+	"math/bits"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/innerprod/simple"
+	"github.com/fentec-project/gofe/sample"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+const LWESchemeID = "lwe"
+
+// LWE is a Scheme based on simple.LWE (selectively secure scheme under
+// LWE assumption)
+//
+// Ciphertexts are vectors over Z_Q, they're accumulated by modular addition.
+// Every accumulated encryption adds up its noise, and since all encryptions
+// share the same noise matrix of public key, noise grows linearly with amount
+// of signals. Scheme is configured to tolerate up to Bound accumulated
+// signals.
+//
+// gofe gives no security estimate of the scheme, so dimension is raised until
+// estimate of lweSecurityBits reaches SecurityBits.
+type LWE struct {
+	// Main security parameter (dimension of LWE secret), it's the least
+	// dimension tried if SecurityBits is set
+	N int
+	// Max value that accumulated ciphertext decrypts correctly
+	Bound *big.Int
+	// Estimated security level in bits the dimension must reach, 0 means
+	// that N is used as is
+	SecurityBits int
+}
+
+// Public parameters and public key of LWE scheme
+//
+// Public matrix A is not stored, instead it's expanded from Seed. It makes
+// mpk several times smaller.
+type lweMPK struct {
+	N      int
+	M      int
+	BoundX *big.Int
+	BoundY *big.Int
+	P      *big.Int
+	Q      *big.Int
+	SigmaQ *big.Float
+	LSigma *big.Int
+	Seed   []byte
+	PK     gofe.Matrix
+}
+
+type lweMSK struct {
+	SK gofe.Matrix
+}
+
+const (
+	lweSeedSize = 32
+	// Dimension is raised in steps of this size until it reaches security level
+	lweDimensionStep = 32
+	// Larger dimension makes matrix A of billions of elements
+	lweMaxDimension = 4096
+	// Larger bound inflates modulus, and so dimension needed for the same
+	// security level
+	lweMaxSignals = 1024
+)
+
+// Registered scheme reaches security level which the default profile gives
+// schemes over Z_p*
+func init() {
+	RegisterScheme(LWE{N: lweDimensionStep, Bound: big.NewInt(lweMaxSignals), SecurityBits: modulusSecurityBits(Profiles[0].ModulusBits)})
+}
+
+func (LWE) ID() string {
+	return LWESchemeID
+}
+
+func (s LWE) Setup(parties int) (data.MPK, data.MSK, error) {
+	return s.SetupFrom(rand.Reader, parties)
+}
+
+// Returns bounds of plaintext and derived keys coordinates
+func (s LWE) bounds() (boundX, boundY *big.Int) {
+	// Plaintext space must fit accumulated value, so fresh plaintext
+	// coordinates are bounded by the same bound (strictly less than boundX)
+	boundX = new(big.Int).Add(s.Bound, big.NewInt(1))
+	// Derived keys are unit vectors, so boundY = 2 would suffice. However, noise
+	// tolerance of the scheme is proportional to boundY, thus it's increased
+	// to tolerate accumulation of up to Bound signals.
+	boundY = new(big.Int).Div(s.Bound, big.NewInt(4))
+	if boundY.Cmp(big.NewInt(2)) < 0 {
+		boundY = big.NewInt(2)
+	}
+	return boundX, boundY
+}
+
+var _ SeedableScheme = LWE{}
+
+// Setup draws all the randomness from crypto/rand.Reader the same way
+func (s LWE) SetupFrom(random io.Reader, parties int) (data.MPK, data.MSK, error) {
+	params, err := s.generateParams(random, parties)
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "scheme could not be properly configured")
+	}
+
+	seed := make([]byte, lweSeedSize)
+	if _, err := io.ReadFull(random, seed); err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "generate seed")
+	}
+
+	sk, err := gofe.NewRandomMatrix(params.N, parties, uniformSampler{random: random, min: big.NewInt(0), max: params.Q})
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "generate secret key")
+	}
+	// PK = A * SK + E, the same as simple.LWE computes it
+	noise, err := gofe.NewRandomMatrix(params.M, parties, newGaussianSampler(random, params.LSigma))
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "generate public key")
+	}
+	columns := sk.Transpose()
+	pk := make(gofe.Matrix, params.M)
+	product := new(big.Int)
+	expandLWEMatrix(seed, params.M, params.N, params.Q, func(i int, row gofe.Vector) {
+		pk[i] = make(gofe.Vector, parties)
+		for j, column := range columns {
+			pk[i][j] = lweDot(row, column, product)
+			pk[i][j].Add(pk[i][j], noise[i][j]).Mod(pk[i][j], params.Q)
+		}
+	})
+	return encodeLWEKeys(params, seed, sk, pk)
+}
+
+// Generates parameters for given amount of parties, dimension is raised until
+// estimated security level reaches SecurityBits
+func (s LWE) generateParams(random io.Reader, parties int) (*simple.LWEParams, error) {
+	boundX, boundY := s.bounds()
+	for n := s.N; n <= lweMaxDimension; n += lweDimensionStep {
+		params, err := generateLWEParams(random, parties, boundX, boundY, n)
+		if err != nil {
+			return nil, err
+		}
+		if lweSecurityBits(params.N, params.Q, params.SigmaQ) >= s.SecurityBits {
+			return params, nil
+		}
+	}
+	return nil, errors.Errorf("no dimension up to %d reaches %d bits of security", lweMaxDimension, s.SecurityBits)
+}
+
+// Parameters of Homomorphic Encryption Standard (Albrecht et al., 2018)
+// giving 128 bits of classical security for uniform secret
+const (
+	lweReferenceN     = 2048
+	lweReferenceLogQ  = 54
+	lweReferenceSigma = 3.2
+	lweReferenceBits  = 128
+)
+
+// Estimates security level in bits of LWE instance of dimension n, modulus q
+// and noise of parameter sigmaQ
+//
+// Known attacks depend mostly on n / log2(q / sigmaQ), so the estimate scales
+// this ratio of the reference parameters linearly. It's a rough first-order
+// estimate, not a run of lattice estimator, and it ignores that the scheme
+// publishes M samples.
+func lweSecurityBits(n int, q *big.Int, sigmaQ *big.Float) int {
+	if n < 1 || q == nil || sigmaQ == nil || sigmaQ.Sign() <= 0 {
+		return 0
+	}
+	ratio := new(big.Float).Quo(new(big.Float).SetInt(q), sigmaQ)
+	mantissa := new(big.Float)
+	exp := ratio.MantExp(mantissa)
+	m, _ := mantissa.Float64()
+	logRatio := float64(exp) + math.Log2(m)
+	if logRatio <= 1 {
+		return 0
+	}
+	reference := float64(lweReferenceN) / (lweReferenceLogQ - math.Log2(lweReferenceSigma))
+	return int(lweReferenceBits * float64(n) / logRatio / reference)
+}
+
+// Generates parameters (except of matrix A) the same way simple.NewLWE does,
+// but draws all the randomness from given source
+func generateLWEParams(random io.Reader, parties int, boundX, boundY *big.Int, n int) (*simple.LWEParams, error) {
+	p, err := generatePrime(random, boundX.BitLen()+boundY.BitLen()+bits.Len(uint(parties))+2)
+	if err != nil {
+		return nil, err
+	}
+	pF := new(big.Float).SetInt(p)
+	boundXF := new(big.Float).SetInt(boundX)
+	boundYF := new(big.Float).SetInt(boundY)
+
+	val := new(big.Float).Mul(boundXF, big.NewFloat(math.Sqrt(float64(parties))))
+	val.Add(val, big.NewFloat(1))
+	x := new(big.Float).Mul(val, pF)
+	x.Mul(x, boundYF)
+	x.Mul(x, big.NewFloat(float64(8*n)*math.Sqrt(float64(n+parties+1))))
+	x.Mul(x, new(big.Float).Sqrt(x))
+	xI, _ := x.Int(nil)
+	nBitsQ := xI.BitLen() + 1
+	q, err := generatePrime(random, nBitsQ)
+	if err != nil {
+		return nil, err
+	}
+	m := (n+parties+1)*nBitsQ + 2*n + 1
+
+	sigma := new(big.Float)
+	sigma.SetPrec(uint(n))
+	sigma.Quo(big.NewFloat(1/(2*math.Sqrt(float64(2*parties*m*n)))), pF)
+	sigma.Quo(sigma, boundYF)
+	sigmaQ := new(big.Float).Mul(sigma, new(big.Float).SetInt(q))
+
+	lSigmaF := new(big.Float).Quo(sigmaQ, sample.SigmaCDT)
+	lSigma, _ := lSigmaF.Int(nil)
+	lSigma.Add(lSigma, big.NewInt(1))
+	lSigmaF.SetInt(lSigma)
+	sigmaQ.Mul(sample.SigmaCDT, lSigmaF)
+
+	val.Quo(sigmaQ, val)
+	if val.Cmp(big.NewFloat(2*math.Sqrt(float64(n)))) < 1 {
+		return nil, errors.New("sigmaQ is too small")
+	}
+
+	return &simple.LWEParams{
+		L:      parties,
+		N:      n,
+		M:      m,
+		BoundX: boundX,
+		BoundY: boundY,
+		P:      p,
+		Q:      q,
+		SigmaQ: sigmaQ,
+		LSigma: lSigma,
+	}, nil
+}
+
+func encodeLWEKeys(params *simple.LWEParams, seed []byte, sk, pk gofe.Matrix) (data.MPK, data.MSK, error) {
+	mpkJSON, err := json.Marshal(lweMPK{
+		N:      params.N,
+		M:      params.M,
+		BoundX: params.BoundX,
+		BoundY: params.BoundY,
+		P:      params.P,
+		Q:      params.Q,
+		SigmaQ: params.SigmaQ,
+		LSigma: params.LSigma,
+		Seed:   seed,
+		PK:     pk,
+	})
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "encode mpk")
+	}
+	mskJSON, err := json.Marshal(lweMSK{SK: sk})
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "encode msk")
+	}
+
+	return data.MPK{Scheme: LWESchemeID, L: params.L, Key: mpkJSON},
+		data.MSK{Scheme: LWESchemeID, Key: mskJSON},
+		nil
+}
+
+func (LWE) DeriveKey(mpk data.MPK, msk data.MSK, i int) (data.RecipientSecretKey, error) {
+	lwe, _, err := decodeLWEMPK(mpk)
+	if err != nil {
+		return data.RecipientSecretKey{}, err
+	}
+	if msk.Scheme != LWESchemeID {
+		return data.RecipientSecretKey{}, errors.Errorf("expected %s msk, got %s", LWESchemeID, msk.Scheme)
+	}
+	var key lweMSK
+	if err := json.Unmarshal(msk.Key, &key); err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "decode msk")
+	}
+
+	sk, err := lwe.DeriveKey(unitVector(mpk.L, i), key.SK)
+	if err != nil {
+		return data.RecipientSecretKey{}, err
+	}
+	skJSON, err := json.Marshal(sk)
+	if err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "encode derived key")
+	}
+	return data.RecipientSecretKey{I: i, DerivedKey: skJSON}, nil
+}
+
+func (s LWE) Encrypt(mpk data.MPK, x gofe.Vector) (data.Ciphertext, error) {
+	return s.EncryptFrom(rand.Reader, mpk, x)
+}
+
+// Encrypts x as A^T r || PK^T r + center(x) for random bit vector r, the same
+// way simple.LWE does, except that every element is reduced, so that every
+// element of well-formed ciphertext is in Z_q
+func (LWE) EncryptFrom(random io.Reader, mpk data.MPK, x gofe.Vector) (data.Ciphertext, error) {
+	lwe, key, err := decodeLWEMPK(mpk)
+	if err != nil {
+		return data.Ciphertext{}, err
+	}
+	params := lwe.Params
+	if len(x) != mpk.L {
+		return data.Ciphertext{}, errors.New("plaintext has wrong length")
+	}
+	if err := x.CheckBound(params.BoundX); err != nil {
+		return data.Ciphertext{}, err
+	}
+	r, err := gofe.NewRandomVector(params.M, uniformSampler{random: random, min: big.NewInt(0), max: big.NewInt(2)})
+	if err != nil {
+		return data.Ciphertext{}, errors.Wrap(err, "sample randomness")
+	}
+
+	// r is a bit vector, so A^T r and PK^T r are sums of rows picked by r
+	ct0 := gofe.NewConstantVector(params.N, big.NewInt(0))
+	ctLast := gofe.NewConstantVector(mpk.L, big.NewInt(0))
+	expandLWEMatrix(key.Seed, params.M, params.N, params.Q, func(i int, row gofe.Vector) {
+		if r[i].Sign() == 0 {
+			return
+		}
+		for k, a := range row {
+			ct0[k].Add(ct0[k], a)
+		}
+		for j, h := range key.PK[i] {
+			ctLast[j].Add(ctLast[j], h)
+		}
+	})
+	for i, xi := range x {
+		centered := new(big.Int).Mul(xi, params.Q)
+		centered.Div(centered, params.P)
+		ctLast[i].Add(ctLast[i], centered)
+	}
+	return data.Ciphertext{Vector: append(ct0, ctLast...).Mod(params.Q)}, nil
+}
+
+// Adds ciphertexts component-wise in Z_Q
+func (LWE) Accumulate(mpk data.MPK, acc *data.Ciphertext, delta *data.Ciphertext) error {
+	lwe, _, err := decodeLWEMPK(mpk)
+	if err != nil {
+		return err
+	}
+	if len(acc.Vector) != len(delta.Vector) {
+		return errors.New("given ciphertexts have different lengths")
+	}
+
+	acc.Vector = acc.Vector.Add(delta.Vector).Mod(lwe.Params.Q)
+	return nil
+}
+
+func (LWE) Decrypt(mpk data.MPK, sk data.RecipientSecretKey, ciphertext *data.Ciphertext) (*big.Int, error) {
+	lwe, _, err := decodeLWEMPK(mpk)
+	if err != nil {
+		return nil, err
+	}
+	var key gofe.Vector
+	if err := json.Unmarshal(sk.DerivedKey, &key); err != nil {
+		return nil, errors.Wrap(err, "decode derived key")
+	}
+	v, err := lwe.Decrypt(ciphertext.Vector, key, unitVector(mpk.L, sk.I))
+	if err != nil {
+		return nil, err
+	}
+	// simple.LWE returns zero having non-canonical internal representation,
+	// normalize it so results can be compared by value
+	if v.Sign() == 0 {
+		return big.NewInt(0), nil
+	}
+	return v, nil
+}
+
+// Decodes LWE mpk
+//
+// Public matrix A isn't expanded, see expandLWEMatrix. Dimension N is always
+// below M, which is the amount of rows of PK, so N is bounded by size of mpk.
+func decodeLWEMPK(mpk data.MPK) (*simple.LWE, *lweMPK, error) {
+	if mpk.Scheme != LWESchemeID {
+		return nil, nil, errors.Errorf("expected %s mpk, got %s", LWESchemeID, mpk.Scheme)
+	}
+	var key lweMPK
+	if err := json.Unmarshal(mpk.Key, &key); err != nil {
+		return nil, nil, errors.Wrap(err, "decode mpk")
+	}
+	if key.N < 1 || key.M <= key.N || key.Q == nil || key.Q.Sign() <= 0 || key.P == nil ||
+		key.BoundX == nil || key.BoundY == nil || len(key.Seed) != lweSeedSize || !key.PK.CheckDims(key.M, mpk.L) {
+		return nil, nil, errors.New("malformed mpk")
+	}
+
+	params := &simple.LWEParams{
+		L:      mpk.L,
+		N:      key.N,
+		M:      key.M,
+		BoundX: key.BoundX,
+		BoundY: key.BoundY,
+		P:      key.P,
+		Q:      key.Q,
+		SigmaQ: key.SigmaQ,
+		LSigma: key.LSigma,
+	}
+	return &simple.LWE{Params: params}, &key, nil
+}
+
+// Deterministically expands seed into rows*cols matrix A with elements
+// (statistically close to) uniformly distributed over Z_q, and passes its
+// rows to f one by one
+//
+// A has M*N elements, so it's never stored as a whole. Row passed to f is
+// reused for the next one, f must not retain it.
+func expandLWEMatrix(seed []byte, rows, cols int, q *big.Int, f func(i int, row gofe.Vector)) {
+	xof := sha3.NewShake128()
+	_, _ = xof.Write(seed)
+	var dims [16]byte
+	binary.BigEndian.PutUint64(dims[:8], uint64(rows))
+	binary.BigEndian.PutUint64(dims[8:], uint64(cols))
+	_, _ = xof.Write(dims[:])
+
+	// 64 extra bits make modular bias negligible
+	buf := make([]byte, (q.BitLen()+7)/8+8)
+	element, quotient := new(big.Int), new(big.Int)
+	row := gofe.NewConstantVector(cols, big.NewInt(0))
+	for i := 0; i < rows; i++ {
+		for j := range row {
+			_, _ = xof.Read(buf)
+			// Element is non-negative, so its remainder is the same as
+			// modulo, and QuoRem reuses memory of quotient
+			quotient.QuoRem(element.SetBytes(buf), q, row[j])
+		}
+		f(i, row)
+	}
+}
+
+// Returns inner product of x and y, product is used as temporary
+func lweDot(x, y gofe.Vector, product *big.Int) *big.Int {
+	sum := new(big.Int)
+	for i, xi := range x {
+		sum.Add(sum, product.Mul(xi, y[i]))
+	}
+	return sum
+}
+
+var _ ValidatingScheme = LWE{}
+
+func (LWE) ValidateMPK(mpk data.MPK) error {
+	lwe, key, err := decodeLWEMPK(mpk)
+	if err != nil {
+		return err
+	}
+	params := lwe.Params
+	if params.P.Sign() <= 0 {
+		return errors.New("moduli must be positive")
+	}
+	for _, row := range key.PK {
+		for _, x := range row {
+			if x == nil || x.Sign() < 0 || x.Cmp(params.Q) >= 0 {
+				return errors.New("element of public key is out of range [0; q-1]")
+			}
+		}
+	}
+	return nil
+}
+
+// Ciphertext consists of N elements masking randomness and L masked slots,
+// all of them in Z_q
+func (LWE) ValidateCiphertext(mpk data.MPK, ciphertext *data.Ciphertext) error {
+	lwe, _, err := decodeLWEMPK(mpk)
+	if err != nil {
+		return err
+	}
+	if len(ciphertext.Points) != 0 {
+		return errors.New("expected vector, got points")
+	}
+	if n := lwe.Params.N + mpk.L; len(ciphertext.Vector) != n {
+		return errors.Errorf("expected %d elements, got %d", n, len(ciphertext.Vector))
+	}
+	for i, x := range ciphertext.Vector {
+		if x == nil || x.Sign() < 0 || x.Cmp(lwe.Params.Q) >= 0 {
+			return errors.Errorf("element %d is out of range [0; q-1]", i)
+		}
+	}
+	return nil
+}
+
+// Noise of public key is sampled from discrete gaussian with parameter
+// LSigma, it never exceeds lweNoiseTail * LSigma in practice
+const lweNoiseTail = 20
+
+var _ VerifiableKeyScheme = LWE{}
+
+// Key of i-th recipient is i-th column SK_i of msk, and public key is
+// PK = A * SK + E, so PK_i - A * key must be as small as noise
+//
+// Key derived for another vector leaves residue uniformly distributed over
+// Z_q, which exceeds the noise bound with overwhelming probability.
+func (LWE) VerifyKey(mpk data.MPK, sk data.RecipientSecretKey) error {
+	lwe, mpkKey, err := decodeLWEMPK(mpk)
+	if err != nil {
+		return err
+	}
+	var key gofe.Vector
+	if err := json.Unmarshal(sk.DerivedKey, &key); err != nil {
+		return errors.Wrap(err, "decode derived key")
+	}
+	params := lwe.Params
+	if params.LSigma == nil {
+		return errors.New("malformed mpk")
+	}
+	if len(key) != params.N {
+		return errors.Errorf("expected key of %d elements, got %d", params.N, len(key))
+	}
+	for _, x := range key {
+		if x == nil {
+			return errors.New("malformed derived key")
+		}
+	}
+
+	bound := new(big.Int).Mul(params.LSigma, big.NewInt(lweNoiseTail))
+	half := new(big.Int).Rsh(params.Q, 1)
+	matches := true
+	product := new(big.Int)
+	expandLWEMatrix(mpkKey.Seed, params.M, params.N, params.Q, func(j int, row gofe.Vector) {
+		if !matches {
+			return
+		}
+		noise := lweDot(row, key, product)
+		noise.Sub(mpkKey.PK[j][sk.I], noise).Mod(noise, params.Q)
+		if noise.Cmp(half) > 0 {
+			noise.Sub(noise, params.Q)
+		}
+		matches = noise.CmpAbs(bound) <= 0
+	})
+	if !matches {
+		return errors.Errorf("key doesn't match column %d of mpk", sk.I)
+	}
+	return nil
+}
+
+var _ ConfigurableScheme = LWE{}
+
+// Dimension reaches security level of the profile, which is the level its
+// modulus gives schemes over Z_p* unless set explicitly. Modulus Q is derived
+// from the bound, which is max signals of the profile, but never more than
+// lweMaxSignals, so accumulator may restart sooner than profile asks.
+func (s LWE) Configure(profile Profile) (Scheme, data.Profile, error) {
+	securityBits := profile.SecurityBits
+	if securityBits == 0 {
+		securityBits = modulusSecurityBits(profile.ModulusBits)
+	}
+	maxSignals := profile.MaxSignals
+	if maxSignals > lweMaxSignals {
+		maxSignals = lweMaxSignals
+	}
+	scheme := LWE{N: s.N, Bound: big.NewInt(maxSignals), SecurityBits: securityBits}
+	return scheme, data.Profile{SecurityBits: securityBits, MaxSignals: maxSignals}, nil
+}
+
+var _ BoundedScheme = LWE{}
+
+// Plaintext space is [0; BoundX), noise is tolerated up to the same amount of
+// accumulated signals
+func (LWE) Capacity(mpk data.MPK) (*big.Int, error) {
+	lwe, _, err := decodeLWEMPK(mpk)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).Sub(lwe.Params.BoundX, big.NewInt(1)), nil
+}
@@ -0,0 +1,199 @@
+package gofe
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+// Accumulated ciphertext must decrypt correctly until it reaches the bound
+func TestLWEAccumulatesUpToBound(t *testing.T) {
+	scheme := LWE{N: 16, Bound: big.NewInt(64)}
+	mpk, sk, err := GenerateMasterKeysWith(scheme, 3)
+	assert.NoError(t, err, "keygen failed")
+
+	// x = [1 0 0]
+	x := gofe.NewConstantVector(3, big.NewInt(0))
+	x[0] = big.NewInt(1)
+	acc, err := Encrypt(mpk, x)
+	assert.NoError(t, err, "encrypt x")
+
+	for i := 1; i < 64; i++ {
+		delta, err := Encrypt(mpk, x)
+		assert.NoError(t, err, "encrypt x")
+		err = Accumulate(mpk, &acc, &delta)
+		assert.NoError(t, err, "acc + delta")
+	}
+
+	v0, err := Decrypt(mpk, sk[0], &acc)
+	assert.NoError(t, err, "decrypt acc using sk0")
+	assert.Equal(t, big.NewInt(64), v0)
+
+	for j := 1; j < 3; j++ {
+		vj, err := Decrypt(mpk, sk[j], &acc)
+		assert.NoError(t, err, "decrypt acc using skj", j)
+		assert.Equal(t, big.NewInt(0), vj)
+	}
+}
+
+// Registered scheme must tolerate as many signals as its bound at its own
+// dimension, not only at toy one
+//
+// Encryption at registered dimension takes seconds, so two signals are
+// encrypted and accumulated in turns.
+func TestLWEAccumulatesUpToRegisteredBound(t *testing.T) {
+	if testing.Short() {
+		t.Skip("keygen and encryption at registered dimension are slow")
+	}
+	scheme, err := LookupScheme(LWESchemeID)
+	assert.NoError(t, err)
+	mpk, sk, err := GenerateMasterKeysWith(scheme, 2)
+	assert.NoError(t, err, "keygen failed")
+	bound := scheme.(LWE).Bound.Int64()
+
+	x := gofe.NewConstantVector(2, big.NewInt(0))
+	x[0] = big.NewInt(1)
+	signals := make([]data.Ciphertext, 2)
+	for i := range signals {
+		signals[i], err = Encrypt(mpk, x)
+		assert.NoError(t, err, "encrypt x")
+	}
+	acc := signals[0].Copy()
+	for i := int64(1); i < bound; i++ {
+		assert.NoError(t, Accumulate(mpk, acc, &signals[i%2]), "acc + delta")
+	}
+
+	v0, err := Decrypt(mpk, sk[0], acc)
+	assert.NoError(t, err, "decrypt acc using sk0")
+	assert.Equal(t, big.NewInt(bound), v0)
+	v1, err := Decrypt(mpk, sk[1], acc)
+	assert.NoError(t, err, "decrypt acc using sk1")
+	assert.Equal(t, big.NewInt(0), v1)
+}
+
+func TestLWESecurityBits(t *testing.T) {
+	q := new(big.Int).Lsh(big.NewInt(1), lweReferenceLogQ)
+	assert.Equal(t, lweReferenceBits, lweSecurityBits(lweReferenceN, q, big.NewFloat(lweReferenceSigma)))
+	assert.Equal(t, lweReferenceBits/2, lweSecurityBits(lweReferenceN/2, q, big.NewFloat(lweReferenceSigma)))
+
+	// Dimension is raised until estimate reaches required level
+	for _, securityBits := range []int{0, 51} {
+		params, err := LWE{N: 32, Bound: big.NewInt(1024), SecurityBits: securityBits}.generateParams(rand.Reader, 3)
+		assert.NoError(t, err, "generate params")
+		assert.GreaterOrEqual(t, lweSecurityBits(params.N, params.Q, params.SigmaQ), securityBits)
+		if securityBits == 0 {
+			assert.Equal(t, 32, params.N, "dimension is raised with no level required")
+		}
+	}
+	_, err := LWE{N: 32, Bound: big.NewInt(1024), SecurityBits: 1024}.generateParams(rand.Reader, 3)
+	assert.Error(t, err, "unreachable security level")
+}
+
+// Profiles set security level, which is recorded, and bound, which never
+// exceeds lweMaxSignals
+func TestLWEConfigure(t *testing.T) {
+	for _, c := range []struct {
+		profile                  Profile
+		securityBits, maxSignals int
+	}{
+		{Profile{ModulusBits: 512, MaxSignals: 100}, 51, 100},
+		{Profile{ModulusBits: 2048, MaxSignals: 1 << 20}, 112, lweMaxSignals},
+		{Profile{ModulusBits: 512, SecurityBits: 128, MaxSignals: 1 << 20}, 128, lweMaxSignals},
+	} {
+		configured, security, warnings, err := ConfigureScheme(LWE{N: 32}, c.profile)
+		assert.NoError(t, err, "configure scheme")
+		assert.Equal(t, c.securityBits, configured.(LWE).SecurityBits)
+		assert.Equal(t, c.securityBits, security.SecurityBits, "recorded security level differs from actual one")
+		assert.Equal(t, int64(c.maxSignals), configured.(LWE).Bound.Int64())
+		assert.Equal(t, int64(c.maxSignals), security.MaxSignals, "recorded max signals differ from actual ones")
+		if int64(c.maxSignals) < c.profile.MaxSignals {
+			assert.NotEmpty(t, warnings, "no warning about restarting accumulator")
+		}
+		assert.Equal(t, c.securityBits, ProfileOf(security).SecurityBits, "security level is lost on rotation")
+	}
+}
+
+// Crafted mpk must be refused rather than make expansion of A panic or
+// allocate unbounded memory
+func TestLWERefusesMalformedMPK(t *testing.T) {
+	mpk, _, err := GenerateMasterKeysWith(LWE{N: 16, Bound: big.NewInt(64)}, 2)
+	assert.NoError(t, err, "keygen failed")
+	var key lweMPK
+	if err := json.Unmarshal(mpk.Key, &key); err != nil {
+		panic(err)
+	}
+	for _, n := range []int{-1, 0, key.M, 1 << 40} {
+		tampered := key
+		tampered.N = n
+		encoded, err := json.Marshal(tampered)
+		if err != nil {
+			panic(err)
+		}
+		tamperedMPK := data.MPK{Scheme: mpk.Scheme, L: mpk.L, Key: encoded}
+		assert.Error(t, ValidateMPK(tamperedMPK), "mpk of dimension %d accepted", n)
+		_, err = Encrypt(tamperedMPK, unitVector(2, 0))
+		assert.Error(t, err, "encrypted under mpk of dimension %d", n)
+	}
+}
+
+func TestLWERefusesMalformedInput(t *testing.T) {
+	mpk, sk, err := GenerateMasterKeysWith(LWE{N: 16, Bound: big.NewInt(64)}, 3)
+	assert.NoError(t, err, "keygen failed")
+	lwe, _, err := decodeLWEMPK(mpk)
+	if err != nil {
+		panic(err)
+	}
+	params := lwe.Params
+
+	_, err = Encrypt(mpk, unitVector(2, 0))
+	assert.Error(t, err, "encrypted plaintext of wrong length")
+	beyondBound := gofe.NewConstantVector(3, big.NewInt(0))
+	beyondBound[1] = new(big.Int).Add(params.BoundX, big.NewInt(1))
+	_, err = Encrypt(mpk, beyondBound)
+	assert.Error(t, err, "encrypted plaintext beyond bound")
+
+	ciphertext, err := Encrypt(mpk, unitVector(3, 1))
+	assert.NoError(t, err, "encrypt")
+	for name, element := range map[string]*big.Int{
+		"negative":     big.NewInt(-1),
+		"out of range": new(big.Int).Set(params.Q),
+		"missing":      nil,
+	} {
+		tampered := ciphertext.Copy()
+		tampered.Vector[params.N+1] = element
+		assert.Error(t, ValidateCiphertext(mpk, tampered), "element %s accepted", name)
+	}
+	truncated := ciphertext.Copy()
+	truncated.Vector = truncated.Vector[:params.N]
+	assert.Error(t, ValidateCiphertext(mpk, truncated), "ciphertext of wrong length accepted")
+
+	var key gofe.Vector
+	if err := json.Unmarshal(sk[0].DerivedKey, &key); err != nil {
+		panic(err)
+	}
+	for name, tamper := range map[string]func(key gofe.Vector) gofe.Vector{
+		// Residue of changed coordinate is uniform over Z_q, far beyond noise
+		"coordinate is changed": func(key gofe.Vector) gofe.Vector {
+			key[0] = new(big.Int).Add(key[0], big.NewInt(1))
+			return key
+		},
+		"coordinate is missing": func(key gofe.Vector) gofe.Vector { return key[1:] },
+		"coordinate is null": func(key gofe.Vector) gofe.Vector {
+			key[0] = nil
+			return key
+		},
+	} {
+		encoded, err := json.Marshal(tamper(key.Copy()))
+		if err != nil {
+			panic(err)
+		}
+		assert.Error(t, VerifyKey(mpk, data.RecipientSecretKey{I: 0, DerivedKey: encoded}, 0), "key whose %s accepted", name)
+	}
+	assert.Error(t, VerifyKey(mpk, data.RecipientSecretKey{I: 1, DerivedKey: sk[0].DerivedKey}, 1), "key of another recipient accepted")
+}
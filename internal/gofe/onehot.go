@@ -0,0 +1,346 @@
+package gofe
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"math/big"
+
+	"github.com/fentec-project/bn256"
+	"github.com/pkg/errors"
+)
+
+// Prime order group which one-hot proofs and discrete logarithm tables are
+// built upon
+//
+// Elements are *big.Int for subgroup of Z_p*, *bn256.G1 for elliptic curve
+// group and *bn256.GT for pairing target group.
+type proofGroup interface {
+	order() *big.Int
+	// Returns g^k
+	baseExp(k *big.Int) interface{}
+	exp(a interface{}, k *big.Int) interface{}
+	mul(a, b interface{}) interface{}
+	div(a, b interface{}) interface{}
+	marshal(a interface{}) []byte
+	// Decodes element and checks that it belongs to the group
+	unmarshal(bytes []byte) (interface{}, error)
+}
+
+// Subgroup of order q of Z_p* generated by g
+type zpGroup struct {
+	p, q, g *big.Int
+}
+
+func (z zpGroup) order() *big.Int {
+	return z.q
+}
+
+func (z zpGroup) baseExp(k *big.Int) interface{} {
+	return new(big.Int).Exp(z.g, k, z.p)
+}
+
+func (z zpGroup) exp(a interface{}, k *big.Int) interface{} {
+	return new(big.Int).Exp(a.(*big.Int), k, z.p)
+}
+
+func (z zpGroup) mul(a, b interface{}) interface{} {
+	product := new(big.Int).Mul(a.(*big.Int), b.(*big.Int))
+	return product.Mod(product, z.p)
+}
+
+func (z zpGroup) div(a, b interface{}) interface{} {
+	inverse := new(big.Int).ModInverse(b.(*big.Int), z.p)
+	if inverse == nil {
+		// Elements of the group are always invertible
+		panic("element is not invertible")
+	}
+	return z.mul(a, inverse)
+}
+
+// Encodes element as big-endian number of the same length as p
+func (z zpGroup) marshal(a interface{}) []byte {
+	encoded := make([]byte, (z.p.BitLen()+7)/8)
+	bytes := a.(*big.Int).Bytes()
+	copy(encoded[len(encoded)-len(bytes):], bytes)
+	return encoded
+}
+
+func (z zpGroup) unmarshal(bytes []byte) (interface{}, error) {
+	x := new(big.Int).SetBytes(bytes)
+	if err := validateInSubgroup(x, z.p, z.q); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// Group G1 of bn256 curve
+type g1Group struct{}
+
+func (g1Group) order() *big.Int {
+	return bn256.Order
+}
+
+func (g1Group) baseExp(k *big.Int) interface{} {
+	return new(bn256.G1).ScalarBaseMult(k)
+}
+
+func (g1Group) exp(a interface{}, k *big.Int) interface{} {
+	return new(bn256.G1).ScalarMult(a.(*bn256.G1), k)
+}
+
+func (g1Group) mul(a, b interface{}) interface{} {
+	return new(bn256.G1).Add(a.(*bn256.G1), b.(*bn256.G1))
+}
+
+func (g1Group) div(a, b interface{}) interface{} {
+	return new(bn256.G1).Add(a.(*bn256.G1), new(bn256.G1).Neg(b.(*bn256.G1)))
+}
+
+func (g1Group) marshal(a interface{}) []byte {
+	return marshalPoint(a.(*bn256.G1))
+}
+
+func (g1Group) unmarshal(bytes []byte) (interface{}, error) {
+	return unmarshalPoint(bytes)
+}
+
+// Proof that ciphertext c_0 = g^r, c_i = h_i^r * g^x_i encrypts a one-hot
+// vector x, i.e. every x_i is 0 or 1 and they sum up to 1
+type oneHotProof struct {
+	// Proof that x_i is 0 or 1 for every i
+	Bits []bitProof
+	// Proof that prod c_i / g = (prod h_i)^r, i.e. sum of x_i is 1
+	Sum dleqProof
+}
+
+// OR-proof that (c_0, c_i / g^b) = (g^r, h_i^r) for b = 0 or b = 1
+//
+// Branch which doesn't hold is simulated, challenges of branches
+// sum up to Fiat-Shamir challenge.
+type bitProof struct {
+	A, B [2][]byte
+	C, Z [2]*big.Int
+}
+
+// Proof that log_g u = log_h v
+type dleqProof struct {
+	A, B []byte
+	Z    *big.Int
+}
+
+const (
+	oneHotBitDomain = "pps/one-hot/bit"
+	oneHotSumDomain = "pps/one-hot/sum"
+)
+
+// Encrypts unit vector e_index as c_0 = g^r, c_i = h_i^r * g^x_i and proves
+// that it's one-hot
+//
+// Context carries group parameters which aren't implied by group elements,
+// it's hashed together with h and ciphertext.
+func encryptOneHot(random io.Reader, group proofGroup, h []interface{}, index int, context []byte) ([]interface{}, json.RawMessage, error) {
+	if index < 0 || index >= len(h) {
+		return nil, nil, errors.Errorf("index %d is out of range", index)
+	}
+	r, err := sampleScalar(random, group.order())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ciphertext := make([]interface{}, len(h)+1)
+	ciphertext[0] = group.baseExp(r)
+	for i := range h {
+		ciphertext[i+1] = group.exp(h[i], r)
+		if i == index {
+			ciphertext[i+1] = group.mul(ciphertext[i+1], group.baseExp(big.NewInt(1)))
+		}
+	}
+
+	proof, err := proveOneHot(random, group, h, ciphertext, r, index, context)
+	if err != nil {
+		return nil, nil, err
+	}
+	proofJSON, err := json.Marshal(proof)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "encode proof")
+	}
+	return ciphertext, proofJSON, nil
+}
+
+func proveOneHot(random io.Reader, group proofGroup, h []interface{}, ciphertext []interface{}, r *big.Int, index int, context []byte) (*oneHotProof, error) {
+	q := group.order()
+	ctx := oneHotContext(group, context, h, ciphertext)
+	c0 := ciphertext[0]
+	g := group.baseExp(big.NewInt(1))
+
+	proof := &oneHotProof{Bits: make([]bitProof, len(h))}
+	for i := range h {
+		x := 0
+		if i == index {
+			x = 1
+		}
+		var bit bitProof
+
+		// Simulate branch 1-x: A = g^z / c_0^c, B = h_i^z / (c_i / g^(1-x))^c
+		fake := 1 - x
+		c, err := sampleScalar(random, q)
+		if err != nil {
+			return nil, err
+		}
+		z, err := sampleScalar(random, q)
+		if err != nil {
+			return nil, err
+		}
+		v := bitBranchValue(group, ciphertext[i+1], g, fake)
+		bit.A[fake] = group.marshal(group.div(group.baseExp(z), group.exp(c0, c)))
+		bit.B[fake] = group.marshal(group.div(group.exp(h[i], z), group.exp(v, c)))
+		bit.C[fake], bit.Z[fake] = c, z
+
+		// Real branch x
+		w, err := sampleScalar(random, q)
+		if err != nil {
+			return nil, err
+		}
+		bit.A[x] = group.marshal(group.baseExp(w))
+		bit.B[x] = group.marshal(group.exp(h[i], w))
+		total := challenge(q, oneHotBitDomain, ctx, big.NewInt(int64(i)),
+			bytesToInt(bit.A[0]), bytesToInt(bit.B[0]), bytesToInt(bit.A[1]), bytesToInt(bit.B[1]))
+		bit.C[x] = new(big.Int).Sub(total, c)
+		bit.C[x].Mod(bit.C[x], q)
+		bit.Z[x] = new(big.Int).Mul(bit.C[x], r)
+		bit.Z[x].Add(bit.Z[x], w)
+		bit.Z[x].Mod(bit.Z[x], q)
+
+		proof.Bits[i] = bit
+	}
+
+	hSum, _ := oneHotSumStatement(group, h, ciphertext, g)
+	w, err := sampleScalar(random, q)
+	if err != nil {
+		return nil, err
+	}
+	proof.Sum.A = group.marshal(group.baseExp(w))
+	proof.Sum.B = group.marshal(group.exp(hSum, w))
+	c := challenge(q, oneHotSumDomain, ctx, bytesToInt(proof.Sum.A), bytesToInt(proof.Sum.B))
+	proof.Sum.Z = new(big.Int).Mul(c, r)
+	proof.Sum.Z.Add(proof.Sum.Z, w)
+	proof.Sum.Z.Mod(proof.Sum.Z, q)
+
+	return proof, nil
+}
+
+// Verifies that ciphertext (c_0, c_1, ..., c_l) encrypts one-hot vector
+//
+// Elements of ciphertext must already be checked to belong to the group.
+func verifyOneHot(group proofGroup, h []interface{}, ciphertext []interface{}, proofJSON json.RawMessage, context []byte) error {
+	var proof oneHotProof
+	if err := json.Unmarshal(proofJSON, &proof); err != nil {
+		return errors.Wrap(err, "decode proof")
+	}
+	if len(ciphertext) != len(h)+1 || len(proof.Bits) != len(h) {
+		return errors.New("proof doesn't match ciphertext length")
+	}
+
+	q := group.order()
+	ctx := oneHotContext(group, context, h, ciphertext)
+	c0 := ciphertext[0]
+	g := group.baseExp(big.NewInt(1))
+
+	for i, bit := range proof.Bits {
+		total := challenge(q, oneHotBitDomain, ctx, big.NewInt(int64(i)),
+			bytesToInt(bit.A[0]), bytesToInt(bit.B[0]), bytesToInt(bit.A[1]), bytesToInt(bit.B[1]))
+		if bit.C[0] == nil || bit.C[1] == nil || bit.Z[0] == nil || bit.Z[1] == nil {
+			return errors.Errorf("bit proof %d is malformed", i)
+		}
+		sum := new(big.Int).Add(bit.C[0], bit.C[1])
+		if sum.Mod(sum, q).Cmp(total) != 0 {
+			return errors.Errorf("bit proof %d has wrong challenge", i)
+		}
+
+		for b := 0; b < 2; b++ {
+			a, err := group.unmarshal(bit.A[b])
+			if err != nil {
+				return errors.Wrapf(err, "bit proof %d is malformed", i)
+			}
+			bb, err := group.unmarshal(bit.B[b])
+			if err != nil {
+				return errors.Wrapf(err, "bit proof %d is malformed", i)
+			}
+			v := bitBranchValue(group, ciphertext[i+1], g, b)
+			if !dleqHolds(group, g, h[i], c0, v, a, bb, bit.C[b], bit.Z[b]) {
+				return errors.Errorf("slot %d doesn't encrypt 0 or 1", i+1)
+			}
+		}
+	}
+
+	hSum, cSum := oneHotSumStatement(group, h, ciphertext, g)
+	if proof.Sum.Z == nil {
+		return errors.New("sum proof is malformed")
+	}
+	a, err := group.unmarshal(proof.Sum.A)
+	if err != nil {
+		return errors.Wrap(err, "sum proof is malformed")
+	}
+	b, err := group.unmarshal(proof.Sum.B)
+	if err != nil {
+		return errors.Wrap(err, "sum proof is malformed")
+	}
+	c := challenge(q, oneHotSumDomain, ctx, bytesToInt(proof.Sum.A), bytesToInt(proof.Sum.B))
+	if !dleqHolds(group, g, hSum, c0, cSum, a, b, c, proof.Sum.Z) {
+		return errors.New("slots don't sum up to 1")
+	}
+	return nil
+}
+
+// Checks g^z = a * u^c and h^z = b * v^c
+func dleqHolds(group proofGroup, g, h, u, v, a, b interface{}, c, z *big.Int) bool {
+	lhs1 := group.marshal(group.exp(g, z))
+	rhs1 := group.marshal(group.mul(a, group.exp(u, c)))
+	lhs2 := group.marshal(group.exp(h, z))
+	rhs2 := group.marshal(group.mul(b, group.exp(v, c)))
+	return string(lhs1) == string(rhs1) && string(lhs2) == string(rhs2)
+}
+
+// Returns c_i / g^b
+func bitBranchValue(group proofGroup, ci, g interface{}, b int) interface{} {
+	if b == 0 {
+		return ci
+	}
+	return group.div(ci, g)
+}
+
+// Returns prod h_i and prod c_i / g
+func oneHotSumStatement(group proofGroup, h []interface{}, ciphertext []interface{}, g interface{}) (interface{}, interface{}) {
+	hSum := h[0]
+	cSum := ciphertext[1]
+	for i := 1; i < len(h); i++ {
+		hSum = group.mul(hSum, h[i])
+		cSum = group.mul(cSum, ciphertext[i+1])
+	}
+	return hSum, group.div(cSum, g)
+}
+
+// Hashes context together with mpk vector and ciphertext, so proof can't be
+// moved to another ciphertext or mpk
+func oneHotContext(group proofGroup, context []byte, h []interface{}, ciphertext []interface{}) *big.Int {
+	hash := sha256.New()
+	_, _ = hash.Write(context)
+	for _, e := range append(append([]interface{}{}, h...), ciphertext...) {
+		_, _ = hash.Write(group.marshal(e))
+	}
+	return new(big.Int).SetBytes(hash.Sum(nil))
+}
+
+func sampleScalar(random io.Reader, q *big.Int) (*big.Int, error) {
+	k, err := rand.Int(random, q)
+	if err != nil {
+		return nil, errors.Wrap(err, "sample scalar")
+	}
+	return k, nil
+}
+
+func bytesToInt(bytes []byte) *big.Int {
+	return new(big.Int).SetBytes(bytes)
+}
@@ -0,0 +1,93 @@
+package gofe
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+func TestOneHotProofs(t *testing.T) {
+	forEachScheme(t, func(t *testing.T, scheme Scheme) {
+		if _, ok := scheme.(ProvableScheme); !ok {
+			t.Skip("scheme doesn't support one-hot proofs")
+		}
+		mpk, sk, err := GenerateMasterKeysWith(scheme, 3)
+		assert.NoError(t, err, "keygen failed")
+
+		// Round 1 signals party 2, round 2 signals party 1
+		delta1, proof1, err := EncryptSignal(mpk, 1)
+		assert.NoError(t, err, "encrypt signal")
+		round1 := &data.Round{Ciphertext: *delta1.Copy(), Delta: &delta1, Proof: proof1}
+		assert.NoError(t, VerifyRound(mpk, nil, round1), "verify round 1")
+
+		delta2, proof2, err := EncryptSignal(mpk, 0)
+		assert.NoError(t, err, "encrypt signal")
+		ciphertext2 := round1.Ciphertext.Copy()
+		assert.NoError(t, Accumulate(mpk, ciphertext2, &delta2), "accumulate")
+		round2 := &data.Round{Ciphertext: *ciphertext2, Delta: &delta2, Proof: proof2}
+		assert.NoError(t, VerifyRound(mpk, &round1.Ciphertext, round2), "verify round 2")
+
+		for j, expected := range []int64{1, 1, 0} {
+			v, err := Decrypt(mpk, sk[j], &round2.Ciphertext)
+			assert.NoError(t, err, "decrypt using sk", j)
+			assert.Equal(t, big.NewInt(expected), v, "wrong decryption using sk", j)
+		}
+
+		t.Run("Missing proof", func(t *testing.T) {
+			err := VerifyRound(mpk, nil, &data.Round{Ciphertext: delta1, Delta: &delta1})
+			assert.Error(t, err)
+		})
+
+		t.Run("Proof of another round", func(t *testing.T) {
+			err := VerifyRound(mpk, &round1.Ciphertext, &data.Round{Ciphertext: *ciphertext2, Delta: &delta2, Proof: proof1})
+			assert.Error(t, err)
+		})
+	})
+}
+
+func TestOneHotProofsRejectMaliciousSignals(t *testing.T) {
+	mpk, _, err := GenerateMasterKeysWith(DefaultScheme, 3)
+	assert.NoError(t, err, "keygen failed")
+	zp, zpH, zpContext, err := ddhProofGroup(mpk)
+	assert.NoError(t, err)
+
+	ecMPK, _, err := GenerateMasterKeysWith(ECDDH{Bound: big.NewInt(1024)}, 3)
+	assert.NoError(t, err, "keygen failed")
+	_, points, err := decodeECDDHMPK(ecMPK)
+	assert.NoError(t, err)
+
+	groups := map[string]struct {
+		group   proofGroup
+		h       []interface{}
+		context []byte
+	}{
+		"zp": {zp, zpH, zpContext},
+		"g1": {g1Group{}, g1Elements(points), []byte(ECDDHSchemeID)},
+	}
+	for name, g := range groups {
+		t.Run(name, func(t *testing.T) {
+			for _, x := range [][]int64{{0, 500, 0}, {1, 1, 0}, {0, 0, 0}, {2, 0, 0}} {
+				// Cheating sender knows randomness and claims that x[0] is the only 1
+				r, err := sampleScalar(rand.Reader, g.group.order())
+				assert.NoError(t, err)
+				ciphertext := make([]interface{}, len(x)+1)
+				ciphertext[0] = g.group.baseExp(r)
+				for i, xi := range x {
+					ciphertext[i+1] = g.group.mul(g.group.exp(g.h[i], r), g.group.baseExp(big.NewInt(xi)))
+				}
+
+				proof, err := proveOneHot(rand.Reader, g.group, g.h, ciphertext, r, 0, g.context)
+				assert.NoError(t, err)
+				proofJSON, err := json.Marshal(proof)
+				assert.NoError(t, err)
+				err = verifyOneHot(g.group, g.h, ciphertext, proofJSON, g.context)
+				assert.Error(t, err, "accepted proof for x =", x)
+			}
+		})
+	}
+}
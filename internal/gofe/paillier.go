@@ -0,0 +1,345 @@
+package gofe
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"math/big"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/innerprod/fullysec"
+	"github.com/fentec-project/gofe/sample"
+	"github.com/pkg/errors"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+const PaillierSchemeID = "paillier"
+
+// Paillier is a Scheme based on fullysec.Paillier (fully secure scheme under
+// composite residuosity assumption)
+//
+// Unlike DDH-based schemes, decryption doesn't involve solving discrete
+// logarithm, so accumulated value is bounded only by N/2.
+type Paillier struct {
+	// Security parameter
+	Lambda int
+	// Bit length of each of two safe primes forming N
+	BitLength int
+	// Bound of plaintext coordinates of a single (not accumulated) encryption
+	Bound *big.Int
+}
+
+type paillierMPK struct {
+	Params *fullysec.PaillierParams
+	Vector gofe.Vector
+}
+
+type paillierMSK struct {
+	Vector gofe.Vector
+}
+
+func init() {
+	RegisterScheme(Paillier{Lambda: 128, BitLength: 512, Bound: big.NewInt(1024)})
+}
+
+func (Paillier) ID() string {
+	return PaillierSchemeID
+}
+
+func (s Paillier) Setup(parties int) (data.MPK, data.MSK, error) {
+	// Derived keys are always unit vectors, thus bounded by 2
+	paillier, err := fullysec.NewPaillier(parties, s.Lambda, s.BitLength, s.Bound, big.NewInt(2))
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "scheme could not be properly configured")
+	}
+
+	msk, mpk, err := paillier.GenerateMasterKeys()
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "generate master key")
+	}
+	return encodePaillierKeys(paillier.Params, msk, mpk)
+}
+
+var _ SeedableScheme = Paillier{}
+
+func (s Paillier) SetupFrom(random io.Reader, parties int) (data.MPK, data.MSK, error) {
+	params, err := generatePaillierParams(random, parties, s.Lambda, s.BitLength, s.Bound, big.NewInt(2))
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "scheme could not be properly configured")
+	}
+
+	sampler := newGaussianSampler(random, params.LSigma)
+	msk, err := gofe.NewRandomVector(parties, sampler)
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "generate master key")
+	}
+	mpk := make(gofe.Vector, parties)
+	for i, x := range msk {
+		mpk[i] = new(big.Int).Exp(params.G, x, params.NSquare)
+	}
+	return encodePaillierKeys(params, msk, mpk)
+}
+
+// Generates parameters the same way fullysec.NewPaillier does, but draws all
+// the randomness from given source
+func generatePaillierParams(random io.Reader, parties, lambda, bitLength int, boundX, boundY *big.Int) (*fullysec.PaillierParams, error) {
+	p, _, err := generateSafePrime(random, bitLength)
+	if err != nil {
+		return nil, err
+	}
+	q, _, err := generateSafePrime(random, bitLength)
+	if err != nil {
+		return nil, err
+	}
+	n := new(big.Int).Mul(p, q)
+	nSquare := new(big.Int).Mul(n, n)
+
+	twiceL := big.NewInt(int64(2 * parties))
+	if n.Cmp(new(big.Int).Mul(twiceL, new(big.Int).Mul(boundX, boundX))) <= 0 {
+		return nil, errors.New("boundX and l are too big for bit length")
+	}
+	if n.Cmp(new(big.Int).Mul(twiceL, new(big.Int).Mul(boundY, boundY))) <= 0 {
+		return nil, errors.New("boundY and l are too big for bit length")
+	}
+
+	// g generates subgroup of 2n-th residues
+	gPrime, err := sampleRange(random, big.NewInt(0), nSquare)
+	if err != nil {
+		return nil, errors.Wrap(err, "sample generator")
+	}
+	g := new(big.Int).Exp(gPrime, n, nSquare)
+	g.Exp(g, big.NewInt(2), nSquare)
+	if new(big.Int).ModInverse(g, nSquare) == nil {
+		return nil, errors.New("generator is not invertible")
+	}
+
+	sigma := new(big.Float).SetInt(new(big.Int).Exp(n, big.NewInt(5), nil))
+	sigma.Mul(sigma, big.NewFloat(float64(lambda)))
+	sigma.Sqrt(sigma)
+	sigma.Add(sigma, big.NewFloat(2))
+	lSigmaF := new(big.Float).Quo(sigma, sample.SigmaCDT)
+	lSigma, _ := lSigmaF.Int(nil)
+	lSigma.Add(lSigma, big.NewInt(1))
+	sigma.Mul(sample.SigmaCDT, lSigmaF)
+
+	return &fullysec.PaillierParams{
+		L:       parties,
+		N:       n,
+		NSquare: nSquare,
+		BoundX:  boundX,
+		BoundY:  boundY,
+		Sigma:   sigma,
+		LSigma:  lSigma,
+		Lambda:  lambda,
+		G:       g,
+	}, nil
+}
+
+func encodePaillierKeys(params *fullysec.PaillierParams, msk, mpk gofe.Vector) (data.MPK, data.MSK, error) {
+	mpkJSON, err := json.Marshal(paillierMPK{Params: params, Vector: mpk})
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "encode mpk")
+	}
+	mskJSON, err := json.Marshal(paillierMSK{Vector: msk})
+	if err != nil {
+		return data.MPK{}, data.MSK{}, errors.Wrap(err, "encode msk")
+	}
+
+	return data.MPK{Scheme: PaillierSchemeID, L: params.L, Key: mpkJSON},
+		data.MSK{Scheme: PaillierSchemeID, Key: mskJSON},
+		nil
+}
+
+func (Paillier) DeriveKey(mpk data.MPK, msk data.MSK, i int) (data.RecipientSecretKey, error) {
+	paillier, _, err := decodePaillierMPK(mpk)
+	if err != nil {
+		return data.RecipientSecretKey{}, err
+	}
+	if msk.Scheme != PaillierSchemeID {
+		return data.RecipientSecretKey{}, errors.Errorf("expected %s msk, got %s", PaillierSchemeID, msk.Scheme)
+	}
+	var key paillierMSK
+	if err := json.Unmarshal(msk.Key, &key); err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "decode msk")
+	}
+
+	sk, err := paillier.DeriveKey(key.Vector, unitVector(mpk.L, i))
+	if err != nil {
+		return data.RecipientSecretKey{}, err
+	}
+	skJSON, err := json.Marshal(sk)
+	if err != nil {
+		return data.RecipientSecretKey{}, errors.Wrap(err, "encode derived key")
+	}
+	return data.RecipientSecretKey{I: i, DerivedKey: skJSON}, nil
+}
+
+func (s Paillier) Encrypt(mpk data.MPK, x gofe.Vector) (data.Ciphertext, error) {
+	return s.EncryptFrom(rand.Reader, mpk, x)
+}
+
+// Encrypts x as ct_0 = g^r, ct_i = (1 + x_i N) * h_i^r, the same way
+// fullysec.Paillier does
+func (Paillier) EncryptFrom(random io.Reader, mpk data.MPK, x gofe.Vector) (data.Ciphertext, error) {
+	paillier, vector, err := decodePaillierMPK(mpk)
+	if err != nil {
+		return data.Ciphertext{}, err
+	}
+	params := paillier.Params
+	if len(x) != mpk.L {
+		return data.Ciphertext{}, errors.New("plaintext has wrong length")
+	}
+	if err := x.CheckBound(params.BoundX); err != nil {
+		return data.Ciphertext{}, err
+	}
+	r, err := sampleRange(random, big.NewInt(0), new(big.Int).Quo(params.N, big.NewInt(4)))
+	if err != nil {
+		return data.Ciphertext{}, errors.Wrap(err, "sample randomness")
+	}
+
+	ciphertext := make(gofe.Vector, len(x)+1)
+	ciphertext[0] = new(big.Int).Exp(params.G, r, params.NSquare)
+	for i, xi := range x {
+		t := new(big.Int).Mul(xi, params.N)
+		t.Add(t, big.NewInt(1))
+		hr := new(big.Int).Exp(vector[i], r, params.NSquare)
+		ciphertext[i+1] = t.Mul(t, hr).Mod(t, params.NSquare)
+	}
+	return data.Ciphertext{Vector: ciphertext}, nil
+}
+
+// Multiplies ciphertexts component-wise in Z_{N^2}
+//
+// Reduction keeps accumulated ciphertext at constant size no matter how many
+// signals it carries.
+func (Paillier) Accumulate(mpk data.MPK, acc *data.Ciphertext, delta *data.Ciphertext) error {
+	paillier, _, err := decodePaillierMPK(mpk)
+	if err != nil {
+		return err
+	}
+	if len(acc.Vector) != len(delta.Vector) {
+		return errors.New("given ciphertexts have different lengths")
+	}
+
+	for i, x := range delta.Vector {
+		acc.Vector[i] = new(big.Int).Mul(acc.Vector[i], x)
+		acc.Vector[i].Mod(acc.Vector[i], paillier.Params.NSquare)
+	}
+	return nil
+}
+
+func (Paillier) Decrypt(mpk data.MPK, sk data.RecipientSecretKey, ciphertext *data.Ciphertext) (*big.Int, error) {
+	paillier, _, err := decodePaillierMPK(mpk)
+	if err != nil {
+		return nil, err
+	}
+	var key big.Int
+	if err := json.Unmarshal(sk.DerivedKey, &key); err != nil {
+		return nil, errors.Wrap(err, "decode derived key")
+	}
+	return paillier.Decrypt(ciphertext.Vector, &key, unitVector(mpk.L, sk.I))
+}
+
+func decodePaillierMPK(mpk data.MPK) (*fullysec.Paillier, gofe.Vector, error) {
+	if mpk.Scheme != PaillierSchemeID {
+		return nil, nil, errors.Errorf("expected %s mpk, got %s", PaillierSchemeID, mpk.Scheme)
+	}
+	var key paillierMPK
+	if err := json.Unmarshal(mpk.Key, &key); err != nil {
+		return nil, nil, errors.Wrap(err, "decode mpk")
+	}
+	if key.Params == nil || key.Params.L != mpk.L || len(key.Vector) != mpk.L {
+		return nil, nil, errors.New("malformed mpk")
+	}
+	return fullysec.NewPaillierFromParams(key.Params), key.Vector, nil
+}
+
+var _ ValidatingScheme = Paillier{}
+
+// Factorization of N can't be checked publicly, so only ranges of
+// parameters and elements are validated
+func (Paillier) ValidateMPK(mpk data.MPK) error {
+	paillier, h, err := decodePaillierMPK(mpk)
+	if err != nil {
+		return err
+	}
+	params := paillier.Params
+	if params.N == nil || params.N.BitLen() < minModulusBits {
+		return errors.Errorf("modulus must be at least %d bits long", minModulusBits)
+	}
+	if params.NSquare == nil || params.NSquare.Cmp(new(big.Int).Mul(params.N, params.N)) != 0 {
+		return errors.New("modulus squared doesn't match modulus")
+	}
+	if params.BoundX == nil || params.BoundX.Sign() <= 0 || params.BoundY == nil || params.BoundY.Sign() <= 0 {
+		return errors.New("bounds must be positive")
+	}
+	if err := validateUnit(params.G, params.NSquare); err != nil {
+		return errors.Wrap(err, "generator")
+	}
+	for i, x := range h {
+		if err := validateUnit(x, params.NSquare); err != nil {
+			return errors.Wrapf(err, "element %d", i)
+		}
+	}
+	return nil
+}
+
+func (Paillier) ValidateCiphertext(mpk data.MPK, ciphertext *data.Ciphertext) error {
+	paillier, _, err := decodePaillierMPK(mpk)
+	if err != nil {
+		return err
+	}
+	if len(ciphertext.Points) != 0 {
+		return errors.New("expected vector, got points")
+	}
+	if len(ciphertext.Vector) != mpk.L+1 {
+		return errors.Errorf("expected %d elements, got %d", mpk.L+1, len(ciphertext.Vector))
+	}
+	for i, x := range ciphertext.Vector {
+		if err := validateUnit(x, paillier.Params.NSquare); err != nil {
+			return errors.Wrapf(err, "element %d", i)
+		}
+	}
+	return nil
+}
+
+var _ VerifiableKeyScheme = Paillier{}
+
+// Key of i-th recipient is msk_i (possibly negative), so it must satisfy
+// g^key = h_i mod N^2
+func (Paillier) VerifyKey(mpk data.MPK, sk data.RecipientSecretKey) error {
+	paillier, h, err := decodePaillierMPK(mpk)
+	if err != nil {
+		return err
+	}
+	var key big.Int
+	if err := json.Unmarshal(sk.DerivedKey, &key); err != nil {
+		return errors.Wrap(err, "decode derived key")
+	}
+	expected := new(big.Int).Exp(paillier.Params.G, &key, paillier.Params.NSquare)
+	if expected == nil || expected.Cmp(h[sk.I]) != 0 {
+		return errors.Errorf("key doesn't match element %d of mpk", sk.I)
+	}
+	return nil
+}
+
+var _ ConfigurableScheme = Paillier{}
+
+// Modulus N consists of two primes of half the length. Primes are never
+// shorter than the scheme's own BitLength, so a profile can't weaken keys
+// below defaults, and recorded ModulusBits is the length N actually gets.
+// Accumulated value is bounded by N/2, so max signals don't affect
+// parameters.
+func (s Paillier) Configure(profile Profile) (Scheme, data.Profile, error) {
+	bitLength := profile.ModulusBits / 2
+	if bitLength < s.BitLength {
+		bitLength = s.BitLength
+	}
+	scheme := Paillier{Lambda: s.Lambda, BitLength: bitLength, Bound: s.Bound}
+	return scheme, data.Profile{
+		ModulusBits:  2 * bitLength,
+		SecurityBits: modulusSecurityBits(2 * bitLength),
+		MaxSignals:   profile.MaxSignals,
+	}, nil
+}
@@ -0,0 +1,99 @@
+package gofe
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+// Accumulated value goes far beyond bound of discrete logarithm that
+// DDH-based schemes would need to solve
+func TestPaillierAccumulatedValueIsUnbounded(t *testing.T) {
+	scheme, err := LookupScheme(PaillierSchemeID)
+	assert.NoError(t, err)
+	mpk, sk, err := GenerateMasterKeysWith(scheme, 2)
+	assert.NoError(t, err, "keygen failed")
+
+	// x = [1023 0]
+	x := gofe.NewConstantVector(2, big.NewInt(0))
+	x[0] = big.NewInt(1023)
+	acc, err := Encrypt(mpk, x)
+	assert.NoError(t, err, "encrypt x")
+
+	// acc = 2^20 * Encrypt(x)
+	for i := 0; i < 20; i++ {
+		accCopy := data.Ciphertext{Vector: acc.Vector.Copy()}
+		err = Accumulate(mpk, &acc, &accCopy)
+		assert.NoError(t, err, "acc + acc")
+	}
+
+	v0, err := Decrypt(mpk, sk[0], &acc)
+	assert.NoError(t, err, "decrypt acc using sk0")
+	assert.Equal(t, big.NewInt(1023<<20), v0)
+
+	v1, err := Decrypt(mpk, sk[1], &acc)
+	assert.NoError(t, err, "decrypt acc using sk1")
+	assert.Equal(t, big.NewInt(0), v1)
+}
+
+// Profiles set length of N, which is never shorter than N of registered
+// scheme (two 512-bit primes)
+func TestPaillierConfigure(t *testing.T) {
+	scheme, err := LookupScheme(PaillierSchemeID)
+	assert.NoError(t, err)
+	for _, c := range []struct {
+		modulusBits, bitLength int
+	}{{512, 512}, {1024, 512}, {2048, 1024}} {
+		configured, security, _, err := ConfigureScheme(scheme, Profile{ModulusBits: c.modulusBits, MaxSignals: 1024})
+		assert.NoError(t, err, "configure scheme")
+		assert.Equal(t, c.bitLength, configured.(Paillier).BitLength, "wrong length of primes for %d-bit modulus", c.modulusBits)
+		assert.Equal(t, 2*c.bitLength, security.ModulusBits, "recorded modulus differs from actual one")
+	}
+}
+
+func TestPaillierRefusesMalformedInput(t *testing.T) {
+	scheme, err := LookupScheme(PaillierSchemeID)
+	assert.NoError(t, err)
+	mpk, sk, err := GenerateMasterKeysWith(scheme, 3)
+	assert.NoError(t, err, "keygen failed")
+	paillier, _, err := decodePaillierMPK(mpk)
+	if err != nil {
+		panic(err)
+	}
+	params := paillier.Params
+
+	_, err = Encrypt(mpk, unitVector(4, 0))
+	assert.Error(t, err, "encrypted plaintext of wrong length")
+	beyondBound := gofe.NewConstantVector(3, big.NewInt(0))
+	beyondBound[1] = new(big.Int).Add(params.BoundX, big.NewInt(1))
+	_, err = Encrypt(mpk, beyondBound)
+	assert.Error(t, err, "encrypted plaintext beyond bound")
+
+	ciphertext, err := Encrypt(mpk, unitVector(3, 1))
+	assert.NoError(t, err, "encrypt")
+	for name, element := range map[string]*big.Int{
+		"multiple of N": new(big.Int).Mul(params.N, big.NewInt(3)),
+		"zero":          big.NewInt(0),
+		"out of range":  new(big.Int).Add(ciphertext.Vector[1], params.NSquare),
+	} {
+		tampered := ciphertext.Copy()
+		tampered.Vector[1] = element
+		assert.Error(t, ValidateCiphertext(mpk, tampered), "element %s accepted", name)
+	}
+
+	var key big.Int
+	if err := json.Unmarshal(sk[0].DerivedKey, &key); err != nil {
+		panic(err)
+	}
+	encoded, err := json.Marshal(new(big.Int).Add(&key, big.NewInt(1)))
+	if err != nil {
+		panic(err)
+	}
+	assert.Error(t, VerifyKey(mpk, data.RecipientSecretKey{I: 0, DerivedKey: encoded}, 0), "changed key accepted")
+	assert.Error(t, VerifyKey(mpk, data.RecipientSecretKey{I: 0, DerivedKey: json.RawMessage(`"key"`)}, 0), "malformed key accepted")
+}
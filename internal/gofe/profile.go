@@ -0,0 +1,156 @@
+package gofe
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+// Profile is a set of security parameters which a scheme is configured with
+type Profile struct {
+	// Name of predefined profile, it's empty for custom parameters
+	Name string
+	// Bit length of modulus of schemes working over Z_p* or Z_N
+	ModulusBits int
+	// Security level of schemes without such modulus, 0 means the level that
+	// ModulusBits gives
+	SecurityBits int
+	// Amount of signals every recipient must be able to receive
+	MaxSignals int64
+}
+
+const (
+	minModulusBits = 512
+	maxModulusBits = 8192
+	// Larger capacity makes decryption solve too large discrete logarithm
+	maxMaxSignals = 1 << 40
+	// Capacity which makes discrete logarithm tables noticeably large
+	largeMaxSignals = 1 << 32
+	// Security level recommended for production use
+	recommendedSecurityBits = 112
+)
+
+// Predefined profiles, the first one is used by default
+var Profiles = []Profile{
+	{Name: "demo", ModulusBits: 512, MaxSignals: 1 << 20},
+	{Name: "standard", ModulusBits: 2048, MaxSignals: 1 << 20},
+	{Name: "high", ModulusBits: 3072, MaxSignals: 1 << 24},
+}
+
+// Looks up predefined profile by its name
+func LookupProfile(name string) (Profile, error) {
+	for _, profile := range Profiles {
+		if profile.Name == name {
+			return profile, nil
+		}
+	}
+	return Profile{}, errors.Errorf("unknown profile %q", name)
+}
+
+// Returns names of predefined profiles
+func ProfileNames() []string {
+	names := make([]string, len(Profiles))
+	for i, profile := range Profiles {
+		names[i] = profile.Name
+	}
+	return names
+}
+
+// Checks that profile parameters are supported
+func (p Profile) Validate() error {
+	if p.ModulusBits < minModulusBits || p.ModulusBits > maxModulusBits {
+		return errors.Errorf("expected modulus bits in range [%d; %d]", minModulusBits, maxModulusBits)
+	}
+	if p.ModulusBits%8 != 0 {
+		return errors.New("modulus bits must be multiple of 8")
+	}
+	if p.SecurityBits < 0 {
+		return errors.New("security bits must not be negative")
+	}
+	if p.MaxSignals < 1 || p.MaxSignals > maxMaxSignals {
+		return errors.Errorf("expected max signals in range [1; %d]", int64(maxMaxSignals))
+	}
+	return nil
+}
+
+// Configures scheme according to profile
+//
+// Returns configured scheme, description of the security it provides (to be
+// recorded in mpk) and warnings about weak or impractical choices.
+func ConfigureScheme(scheme Scheme, profile Profile) (Scheme, data.Profile, []string, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, data.Profile{}, nil, err
+	}
+	configurable, ok := scheme.(ConfigurableScheme)
+	if !ok {
+		return nil, data.Profile{}, nil, errors.Errorf("scheme %s doesn't support security profiles", scheme.ID())
+	}
+	configured, security, err := configurable.Configure(profile)
+	if err != nil {
+		return nil, data.Profile{}, nil, err
+	}
+	security.Name = profile.Name
+
+	var warnings []string
+	if security.SecurityBits == 0 {
+		warnings = append(warnings, fmt.Sprintf("security level of scheme %s is not estimated, use it only for demo", scheme.ID()))
+	} else if security.SecurityBits < recommendedSecurityBits {
+		warnings = append(warnings, fmt.Sprintf("scheme %s provides only ~%d bits of security (at least %d recommended), use it only for demo",
+			scheme.ID(), security.SecurityBits, recommendedSecurityBits))
+	}
+	if security.MaxSignals < profile.MaxSignals {
+		warnings = append(warnings, fmt.Sprintf("scheme %s accumulates at most %d signals, accumulator restarts every %d rounds",
+			scheme.ID(), security.MaxSignals, security.MaxSignals))
+	}
+	if _, ok := scheme.(DlogScheme); ok && profile.MaxSignals > largeMaxSignals {
+		warnings = append(warnings, fmt.Sprintf("decrypting up to %d signals requires large discrete logarithm tables", profile.MaxSignals))
+	}
+	return configured, security, warnings, nil
+}
+
+// Returns profile which configures scheme to provide security recorded in mpk
+func ProfileOf(recorded data.Profile) Profile {
+	profile := Profile{Name: recorded.Name, ModulusBits: recorded.ModulusBits, MaxSignals: recorded.MaxSignals}
+	// Schemes of fixed modulus record its actual length (or nothing), and
+	// ignore requested one anyway. Schemes without modulus record only
+	// security level they're configured to.
+	if profile.ModulusBits < minModulusBits {
+		profile.ModulusBits = minModulusBits
+		profile.SecurityBits = recorded.SecurityBits
+	}
+	return profile
+}
+
+// Estimates security level in bits of discrete logarithm in Z_p* or
+// factorization of N having given bit length, according to NIST SP 800-57
+func modulusSecurityBits(bits int) int {
+	switch {
+	case bits >= 15360:
+		return 256
+	case bits >= 7680:
+		return 192
+	case bits >= 3072:
+		return 128
+	case bits >= 2048:
+		return 112
+	case bits >= 1024:
+		return 80
+	default:
+		// Rough estimate, such moduli are breakable by academic efforts
+		return bits / 10
+	}
+}
+
+// Security level of bn256 curve after recent attacks on pairing-friendly
+// curves
+const bn256SecurityBits = 100
+
+// Returns plaintext bound b, such that L * b^2 >= maxSignals for any L >= 1,
+// it suits schemes decrypting inner product bounded by L * b^2
+func innerProductBound(maxSignals int64) *big.Int {
+	return big.NewInt(int64(math.Ceil(math.Sqrt(float64(maxSignals)))))
+}
@@ -0,0 +1,85 @@
+package gofe
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupProfile(t *testing.T) {
+	for _, name := range ProfileNames() {
+		profile, err := LookupProfile(name)
+		assert.NoError(t, err, "lookup profile", name)
+		assert.Equal(t, name, profile.Name)
+		assert.NoError(t, profile.Validate(), "validate profile", name)
+	}
+	_, err := LookupProfile("unknown")
+	assert.Error(t, err)
+}
+
+func TestProfileValidate(t *testing.T) {
+	for _, profile := range []Profile{
+		{ModulusBits: 256, MaxSignals: 1024},
+		{ModulusBits: 16384, MaxSignals: 1024},
+		{ModulusBits: 1001, MaxSignals: 1024},
+		{ModulusBits: 2048, MaxSignals: 0},
+		{ModulusBits: 2048, MaxSignals: 1 << 50},
+	} {
+		assert.Error(t, profile.Validate(), "profile %+v is valid", profile)
+	}
+}
+
+func TestConfigureScheme(t *testing.T) {
+	forEachScheme(t, func(t *testing.T, scheme Scheme) {
+		profile := Profile{Name: "test", ModulusBits: 512, MaxSignals: 100}
+		configured, security, warnings, err := ConfigureScheme(scheme, profile)
+		assert.NoError(t, err, "configure scheme")
+		assert.Equal(t, scheme.ID(), configured.ID(), "wrong scheme")
+		assert.Equal(t, "test", security.Name)
+		assert.Equal(t, profile.MaxSignals, security.MaxSignals)
+		assert.NotEmpty(t, warnings, "no warnings about weak profile")
+
+		mpk, sk, encrypt := setupScheme(t, configured, 2)
+		ciphertext, err := encrypt(unitVector(2, 1))
+		assert.NoError(t, err, "encrypt")
+		v, err := Decrypt(mpk, sk[1], &ciphertext)
+		assert.NoError(t, err, "decrypt")
+		assert.Equal(t, big.NewInt(1), v)
+
+		if dlog, ok := configured.(DlogScheme); ok {
+			params, err := dlog.DlogParams(mpk)
+			assert.NoError(t, err)
+			assert.True(t, params.Bound.Cmp(big.NewInt(profile.MaxSignals)) >= 0, "capacity is below max signals")
+		}
+	})
+}
+
+func TestEpochLength(t *testing.T) {
+	forEachScheme(t, func(t *testing.T, scheme Scheme) {
+		configured, security, _, err := ConfigureScheme(scheme, Profile{ModulusBits: 512, MaxSignals: 5})
+		assert.NoError(t, err, "configure scheme")
+		mpk, _, _ := setupScheme(t, configured, 2)
+		mpk.Profile = &security
+
+		length, err := EpochLength(mpk)
+		assert.NoError(t, err)
+		bounded, ok := configured.(BoundedScheme)
+		if !ok {
+			assert.Zero(t, length, "epochs of unbounded scheme")
+			return
+		}
+		capacity, err := bounded.Capacity(mpk)
+		assert.NoError(t, err)
+		assert.True(t, capacity.Cmp(big.NewInt(5)) >= 0, "capacity is below max signals")
+		assert.Equal(t, 5, length, "epoch isn't capped by max signals")
+
+		mpk.Profile = nil
+		length, err = EpochLength(mpk)
+		assert.NoError(t, err)
+		if capacity.IsInt64() && capacity.Int64() <= math.MaxInt32 {
+			assert.Equal(t, int(capacity.Int64()), length)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package gofe
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+)
+
+// Computes Fiat-Shamir challenge in Z_q
+//
+// Domain separates challenges of different proofs, values must be
+// non-negative.
+func challenge(q *big.Int, domain string, values ...*big.Int) *big.Int {
+	hash := sha256.New()
+	writeChunk := func(chunk []byte) {
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(chunk)))
+		_, _ = hash.Write(length[:])
+		_, _ = hash.Write(chunk)
+	}
+
+	writeChunk([]byte(domain))
+	for _, v := range values {
+		writeChunk(v.Bytes())
+	}
+	return new(big.Int).Mod(new(big.Int).SetBytes(hash.Sum(nil)), q)
+}
@@ -0,0 +1,176 @@
+package gofe
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// Returns deterministic stream of bytes derived from seed and label
+//
+// INSECURE: anyone knowing the seed predicts every key and ciphertext
+// produced from the stream. It's meant only for reproducing test vectors
+// and bug reports. Label separates streams of different operations seeded
+// by the same seed, e.g. different rounds must never share randomness.
+func NewSeededReader(seed []byte, label string) io.Reader {
+	h := sha256.New()
+	writeLengthPrefixed(h, seed)
+	writeLengthPrefixed(h, []byte(label))
+	return &seededReader{key: h.Sum(nil)}
+}
+
+// Produces SHA-256(key || counter) blocks in counter mode
+type seededReader struct {
+	key     []byte
+	counter uint64
+	block   []byte
+}
+
+func (r *seededReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.block) == 0 {
+			var counter [8]byte
+			binary.BigEndian.PutUint64(counter[:], r.counter)
+			r.counter++
+			block := sha256.Sum256(append(append([]byte(nil), r.key...), counter[:]...))
+			r.block = block[:]
+		}
+		copied := copy(p[n:], r.block)
+		r.block = r.block[copied:]
+		n += copied
+	}
+	return n, nil
+}
+
+func writeLengthPrefixed(w io.Writer, bytes []byte) {
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(bytes)))
+	_, _ = w.Write(length[:])
+	_, _ = w.Write(bytes)
+}
+
+// Checks whether rand is the system's secure source of randomness
+func isSystemRandom(random io.Reader) bool {
+	return random == rand.Reader
+}
+
+func errNotSeedable(scheme Scheme) error {
+	return errors.Errorf("scheme %s draws randomness inside gofe library, it can't use custom randomness source", scheme.ID())
+}
+
+// Samples uniformly random integer in range [min; max)
+func sampleRange(random io.Reader, min, max *big.Int) (*big.Int, error) {
+	width := new(big.Int).Sub(max, min)
+	if width.Sign() <= 0 {
+		return nil, errors.New("empty range")
+	}
+	// rand.Int reads from given source as is (unlike rand.Prime), so its
+	// output is reproducible for deterministic sources
+	k, err := rand.Int(random, width)
+	if err != nil {
+		return nil, err
+	}
+	return k.Add(k, min), nil
+}
+
+// Generates prime of given bit length
+//
+// Unlike rand.Prime, all the randomness is drawn from given source, so the
+// prime is reproducible for deterministic sources. Candidates are tested
+// sequentially rather than in parallel as gofe does, so SetupFrom of every
+// scheme built on it is noticeably slower than Setup for large moduli.
+func generatePrime(random io.Reader, bits int) (*big.Int, error) {
+	if bits < 2 {
+		return nil, errors.New("prime must be at least 2 bits long")
+	}
+	bytes := make([]byte, (bits+7)/8)
+	p := new(big.Int)
+	for {
+		if _, err := io.ReadFull(random, bytes); err != nil {
+			return nil, errors.Wrap(err, "read randomness")
+		}
+		// p is exactly bits long and odd
+		excess := uint(len(bytes)*8 - bits)
+		bytes[0] &= 0xff >> excess
+		bytes[0] |= 0x80 >> excess
+		bytes[len(bytes)-1] |= 1
+		p.SetBytes(bytes)
+		if p.ProbablyPrime(20) {
+			return p, nil
+		}
+	}
+}
+
+// Small odd primes, candidates divisible by them are discarded before
+// running expensive primality tests
+var smallPrimes = func() []uint64 {
+	var primes []uint64
+	for n := uint64(3); n < 2000; n += 2 {
+		prime := true
+		for _, p := range primes {
+			if p*p > n {
+				break
+			}
+			if n%p == 0 {
+				prime = false
+				break
+			}
+		}
+		if prime {
+			primes = append(primes, n)
+		}
+	}
+	return primes
+}()
+
+// Generates safe prime p = 2q + 1 of given bit length, where q is prime too
+//
+// Unlike rand.Prime, all the randomness is drawn from given source, so the
+// prime is reproducible for deterministic sources. Candidates are tested
+// sequentially rather than in parallel as gofe does, so SetupFrom of every
+// scheme built on it is noticeably slower than Setup for large moduli.
+func generateSafePrime(random io.Reader, bits int) (p, q *big.Int, err error) {
+	if bits < 3 {
+		return nil, nil, errors.New("safe prime must be at least 3 bits long")
+	}
+	bytes := make([]byte, (bits-1+7)/8)
+	q, p = new(big.Int), new(big.Int)
+	mod := new(big.Int)
+	for {
+		if _, err := io.ReadFull(random, bytes); err != nil {
+			return nil, nil, errors.Wrap(err, "read randomness")
+		}
+		// q is exactly bits-1 bits long and odd, so p is exactly bits long
+		excess := uint(len(bytes)*8 - (bits - 1))
+		bytes[0] &= 0xff >> excess
+		bytes[0] |= 0x80 >> excess
+		bytes[len(bytes)-1] |= 1
+		q.SetBytes(bytes)
+		p.Lsh(q, 1).Add(p, big.NewInt(1))
+
+		if hasSmallFactor(q, p, mod) {
+			continue
+		}
+		if q.ProbablyPrime(20) && p.ProbablyPrime(20) {
+			return p, q, nil
+		}
+	}
+}
+
+func hasSmallFactor(q, p, mod *big.Int) bool {
+	for _, prime := range smallPrimes {
+		divisor := new(big.Int).SetUint64(prime)
+		if q.Cmp(divisor) == 0 {
+			return false
+		}
+		if mod.Mod(q, divisor).Sign() == 0 || mod.Mod(p, divisor).Sign() == 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package gofe
+
+import (
+	"crypto/rand"
+	"io"
+	"math/big"
+	"testing"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeededReader(t *testing.T) {
+	read := func(seed, label string) []byte {
+		bytes := make([]byte, 100)
+		_, err := io.ReadFull(NewSeededReader([]byte(seed), label), bytes)
+		if err != nil {
+			panic(err)
+		}
+		return bytes
+	}
+	assert.Equal(t, read("seed", "keygen"), read("seed", "keygen"), "stream isn't deterministic")
+	assert.NotEqual(t, read("seed", "keygen"), read("seed", "round"), "labels share stream")
+	assert.NotEqual(t, read("seed", "keygen"), read("seed2", "keygen"), "seeds share stream")
+	assert.NotEqual(t, read("se", "edkeygen"), read("seed", "keygen"), "seed and label aren't separated")
+}
+
+func TestGenerateSafePrime(t *testing.T) {
+	p, q, err := generateSafePrime(NewSeededReader([]byte("seed"), "prime"), 256)
+	assert.NoError(t, err, "generate safe prime")
+	assert.Equal(t, 256, p.BitLen(), "wrong bit length")
+	assert.True(t, p.ProbablyPrime(20), "p isn't prime")
+	assert.True(t, q.ProbablyPrime(20), "q isn't prime")
+	assert.Equal(t, p, new(big.Int).Add(new(big.Int).Lsh(q, 1), big.NewInt(1)), "p != 2q + 1")
+
+	p2, _, err := generateSafePrime(NewSeededReader([]byte("seed"), "prime"), 256)
+	assert.NoError(t, err, "generate safe prime")
+	assert.Equal(t, p, p2, "prime isn't reproducible")
+}
+
+func TestSeededKeygen(t *testing.T) {
+	forEachScheme(t, func(t *testing.T, scheme Scheme) {
+		random := func(label string) io.Reader {
+			return NewSeededReader([]byte("seed"), label)
+		}
+		if _, ok := scheme.(SeedableScheme); !ok {
+			_, _, err := GenerateMasterKeysFrom(random("keygen"), scheme, 2)
+			assert.Error(t, err, "seeded keygen of scheme which can't be seeded")
+			return
+		}
+
+		mpk, sk, err := GenerateMasterKeysFrom(random("keygen"), scheme, 2)
+		assert.NoError(t, err, "keygen failed")
+		mpk2, sk2, err := GenerateMasterKeysFrom(random("keygen"), scheme, 2)
+		assert.NoError(t, err, "keygen failed")
+		assert.Equal(t, mpk, mpk2, "mpk isn't reproducible")
+		assert.Equal(t, sk, sk2, "derived keys aren't reproducible")
+
+		ciphertext, _, err := EncryptSignalFrom(random("round"), mpk, 1)
+		assert.NoError(t, err, "encrypt")
+		ciphertext2, _, err := EncryptSignalFrom(random("round"), mpk, 1)
+		assert.NoError(t, err, "encrypt")
+		assert.Equal(t, ciphertext, ciphertext2, "ciphertext isn't reproducible")
+		ciphertext3, _, err := EncryptSignalFrom(rand.Reader, mpk, 1)
+		assert.NoError(t, err, "encrypt")
+		assert.NotEqual(t, ciphertext, ciphertext3, "system randomness is ignored")
+
+		for i, expected := range []int64{0, 1} {
+			v, err := Decrypt(mpk, sk[i], &ciphertext)
+			assert.NoError(t, err, "decrypt")
+			assert.Equal(t, expected, v.Int64(), "wrong signal of party", i+1)
+		}
+
+		x := gofe.NewVector([]*big.Int{big.NewInt(2), big.NewInt(3)})
+		ciphertext, err = EncryptFrom(random("vector"), mpk, x)
+		assert.NoError(t, err, "encrypt vector")
+		ciphertext2, err = EncryptFrom(random("vector"), mpk, x)
+		assert.NoError(t, err, "encrypt vector")
+		assert.Equal(t, ciphertext, ciphertext2, "ciphertext of vector isn't reproducible")
+	})
+}
+
+func TestGaussianSampler(t *testing.T) {
+	sample := func() gofe.Vector {
+		v, err := gofe.NewRandomVector(2000, newGaussianSampler(NewSeededReader([]byte("seed"), "noise"), big.NewInt(10)))
+		if err != nil {
+			panic(err)
+		}
+		return v
+	}
+	v := sample()
+	assert.Equal(t, v, sample(), "samples aren't reproducible")
+
+	// sigma = 10 * sqrt(1/2ln(2)) ~ 8.5, so mean is within a few tenths of
+	// zero and no sample lies beyond 10 sigma
+	sum := big.NewInt(0)
+	for _, x := range v {
+		sum.Add(sum, x)
+		assert.True(t, x.CmpAbs(big.NewInt(85)) < 0, "sample %d is too far from mean", x)
+	}
+	mean := float64(sum.Int64()) / float64(len(v))
+	assert.InDelta(t, 0, mean, 1, "samples aren't centered")
+}
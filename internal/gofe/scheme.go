@@ -0,0 +1,242 @@
+package gofe
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+	"sort"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/pkg/errors"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+// Scheme is an inner-product functional encryption scheme which signalling
+// is built upon
+//
+// Scheme must be additively homomorphic: accumulating encryption of x1 into
+// encryption of x2 must result in encryption of x1+x2. Keys and ciphertexts
+// are passed in their serializable form, every scheme defines its own shape
+// of MPK.Key, MSK.Key and RecipientSecretKey.DerivedKey.
+type Scheme interface {
+	// Unique identifier of the scheme, it's recorded in round 0
+	ID() string
+	// Generates master keys for `parties` recipients
+	Setup(parties int) (data.MPK, data.MSK, error)
+	// Derives secret key of i-th recipient (0-indexed), i.e. key for y = e_i
+	DeriveKey(mpk data.MPK, msk data.MSK, i int) (data.RecipientSecretKey, error)
+	// Encrypts plaintext vector x
+	Encrypt(mpk data.MPK, x gofe.Vector) (data.Ciphertext, error)
+	// Performs acc = acc + delta homomorphically
+	//
+	// In case of error, acc is not modified.
+	Accumulate(mpk data.MPK, acc *data.Ciphertext, delta *data.Ciphertext) error
+	// Decrypts inner product <x, e_i> where i is index of recipient
+	Decrypt(mpk data.MPK, sk data.RecipientSecretKey, ciphertext *data.Ciphertext) (*big.Int, error)
+}
+
+// DistributedScheme is a Scheme which master key consists of independent
+// per-recipient parts
+//
+// It allows every recipient to generate its own part of master key (and thus
+// derive its own secret key), so no one ever holds the whole master secret key.
+type DistributedScheme interface {
+	Scheme
+	// Generates public parameters of the scheme, they don't involve any secrets
+	GenerateParams(random io.Reader, parties int) (json.RawMessage, error)
+	// Generates i-th part of master key (0-indexed)
+	//
+	// Returns public part of it (which includes proof of knowledge of its
+	// secret) and the secret key of i-th recipient.
+	GenerateKeyPart(random io.Reader, params json.RawMessage, i int) (json.RawMessage, data.RecipientSecretKey, error)
+	// Verifies public parts of master key and assembles mpk from them
+	CombineKeyParts(params json.RawMessage, parts []json.RawMessage) (data.MPK, error)
+}
+
+// MultiClientScheme is a Scheme without central authority
+//
+// Every slot of plaintext vector is encrypted by its own client using its own
+// secret key, and derived keys are assembled from shares contributed by all
+// the clients. Ciphertexts produced by clients can't be created from mpk
+// alone, so Encrypt of such scheme always fails: slots are encrypted by
+// EncryptSlot and assembled by CombineSlots instead.
+type MultiClientScheme interface {
+	Scheme
+	// Generates secret keys of `parties` clients, i-th client owns i-th slot
+	SetupClients(parties int) (data.MPK, []data.ClientKey, error)
+	// Derives share of secret key of i-th recipient (0-indexed)
+	DeriveKeyShare(mpk data.MPK, client data.ClientKey, i int) (json.RawMessage, error)
+	// Assembles secret key of i-th recipient from shares of all the clients
+	CombineKeyShares(mpk data.MPK, i int, shares []json.RawMessage) (data.RecipientSecretKey, error)
+	// Encrypts client's slot of plaintext under given label
+	//
+	// Label must never be reused, all slots of a ciphertext are
+	// encrypted under the same label.
+	EncryptSlot(mpk data.MPK, client data.ClientKey, x *big.Int, label string) ([]byte, error)
+	// Assembles ciphertext from slots encrypted under given label
+	CombineSlots(mpk data.MPK, label string, slots [][]byte) (data.Ciphertext, error)
+}
+
+// ThresholdScheme is a Scheme which derived key of i-th recipient consists of
+// i-th coordinates of msk vectors modulo prime q
+//
+// It allows splitting msk between n authorities using Shamir's secret sharing,
+// so any k of them can derive recipient's key, whereas fewer than k learn
+// nothing about msk.
+type ThresholdScheme interface {
+	Scheme
+	// Returns msk vectors and prime modulus q they are defined over
+	MasterKeyVectors(mpk data.MPK, msk data.MSK) ([]gofe.Vector, *big.Int, error)
+	// Returns prime modulus q which msk vectors are defined over
+	MasterKeyModulus(mpk data.MPK) (*big.Int, error)
+	// Encodes derived key of i-th recipient out of i-th coordinates of msk vectors
+	EncodeDerivedKey(mpk data.MPK, i int, key gofe.Vector) (data.RecipientSecretKey, error)
+}
+
+// ProvableScheme is a Scheme able to prove that a ciphertext encrypts a
+// one-hot vector, i.e. 0/1 vector with exactly one 1
+//
+// It stops malicious sender from draining recipients' decryption bound or
+// signalling many recipients at once.
+type ProvableScheme interface {
+	Scheme
+	// Encrypts unit vector e_i and proves that ciphertext is one-hot
+	EncryptOneHot(random io.Reader, mpk data.MPK, i int) (data.Ciphertext, json.RawMessage, error)
+	// Verifies proof that ciphertext encrypts one-hot vector
+	VerifyOneHot(mpk data.MPK, ciphertext *data.Ciphertext, proof json.RawMessage) error
+}
+
+// AmountScheme is a ProvableScheme able to encrypt an amount to a single
+// recipient, i.e. vector a * e_i, and prove that amount is within a bound
+//
+// Proof reveals neither recipient nor amount. Rounds of mpk allowing amounts
+// (MPK.MaxAmount > 0) carry such proofs instead of one-hot proofs.
+type AmountScheme interface {
+	ProvableScheme
+	// Encrypts a * e_i and proves that 1 <= a <= bound
+	EncryptAmount(random io.Reader, mpk data.MPK, i int, amount, bound int64) (data.Ciphertext, json.RawMessage, error)
+	// Verifies proof that ciphertext encrypts a * e_i for some i and
+	// 1 <= a <= bound
+	VerifyAmount(mpk data.MPK, ciphertext *data.Ciphertext, bound int64, proof json.RawMessage) error
+}
+
+// DlogScheme is a Scheme which decryption ends up with g^v and solves discrete
+// logarithm of it
+//
+// It allows precomputing table of discrete logarithms once and reusing it for
+// every decryption under the same mpk.
+type DlogScheme interface {
+	Scheme
+	// Returns parameters of discrete logarithm problem solved by Decrypt
+	DlogParams(mpk data.MPK) (DlogParams, error)
+	// Decrypts g^<x, e_i> where i is index of recipient, element is encoded
+	// by the group
+	DecryptElement(mpk data.MPK, sk data.RecipientSecretKey, ciphertext *data.Ciphertext) ([]byte, error)
+}
+
+// ConfigurableScheme is a Scheme which parameters can be derived from
+// security profile
+type ConfigurableScheme interface {
+	Scheme
+	// Returns scheme configured according to profile and description of
+	// security it provides (SecurityBits is 0 if it's not estimated)
+	Configure(profile Profile) (Scheme, data.Profile, error)
+}
+
+// BoundedScheme is a Scheme which decrypts accumulated value only while it
+// stays within a bound
+//
+// Since every round signals exactly one recipient, no one receives more
+// signals than there are rounds. So accumulator chain is restarted every
+// Capacity rounds (Capacity / MPK.MaxAmount if rounds carry amounts), and
+// decryption never exceeds the bound.
+type BoundedScheme interface {
+	Scheme
+	// Returns max amount of signals every recipient is able to receive
+	Capacity(mpk data.MPK) (*big.Int, error)
+}
+
+// SeedableScheme is a Scheme drawing all the randomness of key generation and
+// encryption from given source
+//
+// Schemes which don't implement it use randomness of gofe library, so their
+// keys and ciphertexts can't be reproduced.
+type SeedableScheme interface {
+	Scheme
+	// Same as Setup, but randomness is read from random
+	SetupFrom(random io.Reader, parties int) (data.MPK, data.MSK, error)
+	// Same as Encrypt, but randomness is read from random
+	EncryptFrom(random io.Reader, mpk data.MPK, x gofe.Vector) (data.Ciphertext, error)
+}
+
+// ValidatingScheme is a Scheme able to check that mpk received from untrusted
+// party is well-formed
+//
+// Malformed mpk (e.g. composite modulus or generator of small subgroup) may
+// let anyone learn recipients of signals encrypted under it.
+type ValidatingScheme interface {
+	Scheme
+	// Checks group parameters and elements of mpk
+	ValidateMPK(mpk data.MPK) error
+	// Checks that ciphertext has as many components as ciphertexts under
+	// mpk have, and each of them is element of the right group
+	ValidateCiphertext(mpk data.MPK, ciphertext *data.Ciphertext) error
+}
+
+// VerifiableKeyScheme is a Scheme which derived key of i-th recipient can be
+// checked against i-th slot of mpk
+//
+// It lets recipient make sure authority derived its key for y = e_i, and not
+// for another vector which would decrypt signals of other recipients.
+type VerifiableKeyScheme interface {
+	Scheme
+	// Checks that sk is key for y = e_i where i = sk.I
+	VerifyKey(mpk data.MPK, sk data.RecipientSecretKey) error
+}
+
+// DefaultScheme is used by keygen unless another scheme is requested
+var DefaultScheme Scheme = DDH{ModulusLength: 512, Bound: big.NewInt(1024)}
+
+var schemes = map[string]Scheme{}
+
+// Registers scheme so it can be looked up by its ID
+//
+// Registering two schemes with the same ID causes panic.
+func RegisterScheme(scheme Scheme) {
+	if _, ok := schemes[scheme.ID()]; ok {
+		panic("scheme " + scheme.ID() + " is already registered")
+	}
+	schemes[scheme.ID()] = scheme
+}
+
+// Looks up registered scheme by its ID
+func LookupScheme(id string) (Scheme, error) {
+	scheme, ok := schemes[id]
+	if !ok {
+		return nil, errors.Errorf("unknown scheme %q", id)
+	}
+	return scheme, nil
+}
+
+// Returns IDs of all registered schemes in alphabetical order
+func SchemeIDs() []string {
+	ids := make([]string, 0, len(schemes))
+	for id := range schemes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Returns scheme which produced given mpk
+func schemeOf(mpk data.MPK) (Scheme, error) {
+	return LookupScheme(mpk.Scheme)
+}
+
+// Returns vector of length l having 1 at i-th position and 0 elsewhere
+func unitVector(l, i int) gofe.Vector {
+	y := gofe.NewConstantVector(l, big.NewInt(0))
+	y[i] = big.NewInt(1)
+	return y
+}
@@ -0,0 +1,215 @@
+package gofe
+
+import (
+	"crypto/rand"
+	"io"
+	"math/big"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/pkg/errors"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+// Generates master keys using given scheme and splits msk between n
+// authorities, any k of them are able to derive a key for any party
+//
+// msk exists only in memory of this function, after that it's
+// represented only by its shares.
+func GenerateThresholdKeys(scheme ThresholdScheme, parties, k, n int) (data.MPK, []data.MSKShare, error) {
+	return GenerateThresholdKeysFrom(rand.Reader, scheme, parties, k, n)
+}
+
+// Same as GenerateThresholdKeys, but randomness of keys and shares is drawn
+// from given source
+func GenerateThresholdKeysFrom(random io.Reader, scheme ThresholdScheme, parties, k, n int) (data.MPK, []data.MSKShare, error) {
+	mpk, msk, err := setup(random, scheme, parties)
+	if err != nil {
+		return data.MPK{}, nil, errors.Wrap(err, "setup")
+	}
+	shares, err := splitMasterKey(random, mpk, msk, k, n)
+	if err != nil {
+		return data.MPK{}, nil, err
+	}
+	return mpk, shares, nil
+}
+
+// Splits msk into n shares using Shamir's secret sharing with threshold k
+func SplitMasterKey(mpk data.MPK, msk data.MSK, k, n int) ([]data.MSKShare, error) {
+	return splitMasterKey(rand.Reader, mpk, msk, k, n)
+}
+
+func splitMasterKey(random io.Reader, mpk data.MPK, msk data.MSK, k, n int) ([]data.MSKShare, error) {
+	scheme, err := thresholdSchemeOf(mpk)
+	if err != nil {
+		return nil, err
+	}
+	if k < 1 || k > n {
+		return nil, errors.Errorf("expected threshold in range [1; %d]", n)
+	}
+	vectors, q, err := scheme.MasterKeyVectors(mpk, msk)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([]data.MSKShare, n)
+	for a := range shares {
+		shares[a] = data.MSKShare{
+			Scheme:    mpk.Scheme,
+			Authority: a + 1,
+			Threshold: k,
+			Vectors:   make([]gofe.Vector, len(vectors)),
+		}
+	}
+	for v, vector := range vectors {
+		if len(vector) != mpk.L {
+			return nil, errors.New("malformed msk")
+		}
+		for a := range shares {
+			shares[a].Vectors[v] = make(gofe.Vector, mpk.L)
+		}
+		for j, secret := range vector {
+			points, err := shamirSplit(random, secret, k, n, q)
+			if err != nil {
+				return nil, err
+			}
+			for a := range shares {
+				shares[a].Vectors[v][j] = points[a]
+			}
+		}
+	}
+	return shares, nil
+}
+
+// Derives share of i-th recipient's key (0-indexed) using authority's share of msk
+func DeriveThresholdShare(mpk data.MPK, share data.MSKShare, i int) (data.DerivedKeyShare, error) {
+	if _, err := thresholdSchemeOf(mpk); err != nil {
+		return data.DerivedKeyShare{}, err
+	}
+	if share.Scheme != mpk.Scheme {
+		return data.DerivedKeyShare{}, errors.Errorf("expected %s msk share, got %s", mpk.Scheme, share.Scheme)
+	}
+	if i < 0 || i >= mpk.L {
+		return data.DerivedKeyShare{}, errors.Errorf("expected party in range [1; %d]", mpk.L)
+	}
+
+	key := make(gofe.Vector, len(share.Vectors))
+	for v, vector := range share.Vectors {
+		if len(vector) != mpk.L || vector[i] == nil {
+			return data.DerivedKeyShare{}, errors.New("malformed msk share")
+		}
+		key[v] = new(big.Int).Set(vector[i])
+	}
+	return data.DerivedKeyShare{Authority: share.Authority, Threshold: share.Threshold, I: i, Key: key}, nil
+}
+
+// Combines shares of i-th recipient's key produced by at least k distinct
+// authorities
+func CombineThresholdShares(mpk data.MPK, i int, shares []data.DerivedKeyShare) (data.RecipientSecretKey, error) {
+	scheme, err := thresholdSchemeOf(mpk)
+	if err != nil {
+		return data.RecipientSecretKey{}, err
+	}
+	q, err := scheme.MasterKeyModulus(mpk)
+	if err != nil {
+		return data.RecipientSecretKey{}, err
+	}
+	if len(shares) == 0 {
+		return data.RecipientSecretKey{}, errors.New("no key shares given")
+	}
+
+	k, size := shares[0].Threshold, len(shares[0].Key)
+	xs := make([]int, len(shares))
+	seen := map[int]bool{}
+	for s, share := range shares {
+		if share.I != i {
+			return data.RecipientSecretKey{}, errors.Errorf("share of authority %d is derived for party %d", share.Authority, share.I+1)
+		}
+		if share.Threshold != k || len(share.Key) != size {
+			return data.RecipientSecretKey{}, errors.Errorf("share of authority %d is inconsistent with others", share.Authority)
+		}
+		if share.Authority < 1 || seen[share.Authority] {
+			return data.RecipientSecretKey{}, errors.Errorf("unexpected share of authority %d", share.Authority)
+		}
+		seen[share.Authority] = true
+		xs[s] = share.Authority
+	}
+	if len(shares) < k {
+		return data.RecipientSecretKey{}, errors.Errorf("expected at least %d key shares, got %d", k, len(shares))
+	}
+
+	key := make(gofe.Vector, size)
+	for v := range key {
+		ys := make([]*big.Int, len(shares))
+		for s, share := range shares {
+			if share.Key[v] == nil {
+				return data.RecipientSecretKey{}, errors.Errorf("share of authority %d is malformed", share.Authority)
+			}
+			ys[s] = share.Key[v]
+		}
+		key[v] = shamirCombine(xs, ys, q)
+	}
+	return scheme.EncodeDerivedKey(mpk, i, key)
+}
+
+func thresholdSchemeOf(mpk data.MPK) (ThresholdScheme, error) {
+	scheme, err := schemeOf(mpk)
+	if err != nil {
+		return nil, err
+	}
+	threshold, ok := scheme.(ThresholdScheme)
+	if !ok {
+		return nil, errors.Errorf("scheme %s doesn't support threshold key derivation", mpk.Scheme)
+	}
+	return threshold, nil
+}
+
+// Evaluates random polynomial f of degree k-1 with f(0) = secret at
+// points 1..n modulo q
+func shamirSplit(random io.Reader, secret *big.Int, k, n int, q *big.Int) ([]*big.Int, error) {
+	coefficients := make([]*big.Int, k)
+	coefficients[0] = new(big.Int).Mod(secret, q)
+	for c := 1; c < k; c++ {
+		coefficient, err := rand.Int(random, q)
+		if err != nil {
+			return nil, errors.Wrap(err, "sample polynomial")
+		}
+		coefficients[c] = coefficient
+	}
+
+	points := make([]*big.Int, n)
+	for a := range points {
+		x := big.NewInt(int64(a + 1))
+		y := new(big.Int)
+		for c := k - 1; c >= 0; c-- {
+			y.Mul(y, x)
+			y.Add(y, coefficients[c])
+			y.Mod(y, q)
+		}
+		points[a] = y
+	}
+	return points, nil
+}
+
+// Interpolates f(0) out of points (xs[s], ys[s]) using Lagrange coefficients
+// modulo prime q
+func shamirCombine(xs []int, ys []*big.Int, q *big.Int) *big.Int {
+	result := new(big.Int)
+	for s := range xs {
+		num, den := big.NewInt(1), big.NewInt(1)
+		for t := range xs {
+			if t == s {
+				continue
+			}
+			num.Mul(num, big.NewInt(int64(xs[t])))
+			num.Mod(num, q)
+			den.Mul(den, big.NewInt(int64(xs[t]-xs[s])))
+			den.Mod(den, q)
+		}
+		lambda := num.Mul(num, den.ModInverse(den, q))
+		term := lambda.Mul(lambda, ys[s])
+		result.Add(result, term)
+		result.Mod(result, q)
+	}
+	return result
+}
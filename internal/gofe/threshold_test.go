@@ -0,0 +1,85 @@
+package gofe
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+func TestShamir(t *testing.T) {
+	q := big.NewInt(7919)
+	secret := big.NewInt(1234)
+	points, err := shamirSplit(rand.Reader, secret, 3, 5, q)
+	assert.NoError(t, err, "split")
+	assert.Len(t, points, 5)
+
+	for _, xs := range [][]int{{1, 2, 3}, {5, 3, 1}, {2, 3, 4, 5}, {1, 2, 3, 4, 5}} {
+		ys := make([]*big.Int, len(xs))
+		for s, x := range xs {
+			ys[s] = points[x-1]
+		}
+		assert.Equal(t, secret, shamirCombine(xs, ys, q), "combine shares of", xs)
+	}
+}
+
+func TestThresholdDerivation(t *testing.T) {
+	forEachScheme(t, func(t *testing.T, scheme Scheme) {
+		threshold, ok := scheme.(ThresholdScheme)
+		if !ok {
+			_, err := SplitMasterKey(data.MPK{Scheme: scheme.ID()}, data.MSK{}, 2, 3)
+			assert.Error(t, err, "scheme doesn't support threshold")
+			return
+		}
+
+		// 2-of-3 authorities
+		mpk, mskShares, err := GenerateThresholdKeys(threshold, 3, 2, 3)
+		assert.NoError(t, err, "keygen failed")
+		assert.Len(t, mskShares, 3)
+
+		// x = [0 1 0]
+		x := gofe.NewConstantVector(3, big.NewInt(0))
+		x[1] = big.NewInt(1)
+		ciphertext, err := Encrypt(mpk, x)
+		assert.NoError(t, err, "encrypt")
+
+		for _, authorities := range [][]int{{1, 2}, {3, 1}, {1, 2, 3}} {
+			for j := 0; j < 3; j++ {
+				var shares []data.DerivedKeyShare
+				for _, a := range authorities {
+					share, err := DeriveThresholdShare(mpk, mskShares[a-1], j)
+					assert.NoError(t, err, "derive share by authority", a)
+					shares = append(shares, share)
+				}
+				sk, err := CombineThresholdShares(mpk, j, shares)
+				assert.NoError(t, err, "combine shares of", authorities)
+
+				v, err := Decrypt(mpk, sk, &ciphertext)
+				assert.NoError(t, err, "decrypt using sk", j)
+				assert.Equal(t, x[j], v, "wrong decryption using sk", j, "combined by", authorities)
+			}
+		}
+
+		t.Run("Not enough shares", func(t *testing.T) {
+			share, err := DeriveThresholdShare(mpk, mskShares[0], 0)
+			assert.NoError(t, err)
+			_, err = CombineThresholdShares(mpk, 0, []data.DerivedKeyShare{share})
+			assert.Error(t, err, "combined less than k shares")
+			_, err = CombineThresholdShares(mpk, 0, []data.DerivedKeyShare{share, share})
+			assert.Error(t, err, "combined duplicated shares")
+		})
+
+		t.Run("Shares of another party", func(t *testing.T) {
+			share1, err := DeriveThresholdShare(mpk, mskShares[0], 0)
+			assert.NoError(t, err)
+			share2, err := DeriveThresholdShare(mpk, mskShares[1], 1)
+			assert.NoError(t, err)
+			_, err = CombineThresholdShares(mpk, 0, []data.DerivedKeyShare{share1, share2})
+			assert.Error(t, err, "combined shares of different parties")
+		})
+	})
+}
@@ -0,0 +1,178 @@
+package gofe
+
+import (
+	"math/big"
+
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/pkg/errors"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+// Checks that mpk received from untrusted party (e.g. repository operator) is
+// well-formed, so encrypting under it doesn't reveal recipients
+//
+// Schemes which don't implement ValidatingScheme are accepted as is.
+func ValidateMPK(mpk data.MPK) error {
+	scheme, err := schemeOf(mpk)
+	if err != nil {
+		return err
+	}
+	if mpk.L < 1 {
+		return errors.Errorf("invalid mpk: expected at least 1 slot, got %d", mpk.L)
+	}
+	if mpk.MaxAmount < 0 {
+		return errors.Errorf("invalid mpk: negative max amount %d", mpk.MaxAmount)
+	}
+	if mpk.MaxAmount > 0 {
+		if _, ok := scheme.(AmountScheme); !ok {
+			return errors.Errorf("invalid mpk: scheme %s can't carry amounts", mpk.Scheme)
+		}
+		if _, err := EpochLength(mpk); err != nil {
+			return errors.Wrap(err, "invalid mpk")
+		}
+	}
+	if validating, ok := scheme.(ValidatingScheme); ok {
+		return errors.Wrap(validating.ValidateMPK(mpk), "invalid mpk")
+	}
+	return nil
+}
+
+// MalformedCiphertextError is returned when ciphertext doesn't match mpk it's
+// supposed to be encrypted under, e.g. it has wrong amount of components or
+// components outside of the group
+type MalformedCiphertextError struct {
+	Err error
+}
+
+func (e *MalformedCiphertextError) Error() string {
+	return "malformed ciphertext: " + e.Err.Error()
+}
+
+func (e *MalformedCiphertextError) Unwrap() error {
+	return e.Err
+}
+
+// Checks that ciphertext is well-formed ciphertext under mpk, returns
+// *MalformedCiphertextError if it isn't
+//
+// Malformed ciphertext accumulated into a round would corrupt every further
+// round of its chain, so ciphertexts from untrusted sources must be checked
+// before accumulation or decryption.
+func ValidateCiphertext(mpk data.MPK, ciphertext *data.Ciphertext) error {
+	scheme, err := schemeOf(mpk)
+	if err != nil {
+		return err
+	}
+	if ciphertext == nil {
+		return &MalformedCiphertextError{Err: errors.New("ciphertext is missing")}
+	}
+	validating, ok := scheme.(ValidatingScheme)
+	if !ok {
+		return nil
+	}
+	if err := validating.ValidateCiphertext(mpk, ciphertext); err != nil {
+		return &MalformedCiphertextError{Err: err}
+	}
+	return nil
+}
+
+// Checks that vector consists of n elements of subgroup of order q
+func validateSubgroupVector(v gofe.Vector, n int, p, q *big.Int) error {
+	if len(v) != n {
+		return errors.Errorf("expected %d elements, got %d", n, len(v))
+	}
+	for i, x := range v {
+		if err := validateInSubgroup(x, p, q); err != nil {
+			return errors.Wrapf(err, "element %d", i)
+		}
+	}
+	return nil
+}
+
+// Checks that ciphertext consists of n points of G1
+func validatePoints(ciphertext *data.Ciphertext, n int) error {
+	if len(ciphertext.Vector) != 0 {
+		return errors.New("expected points, got vector")
+	}
+	if len(ciphertext.Points) != n {
+		return errors.Errorf("expected %d points, got %d", n, len(ciphertext.Points))
+	}
+	for i, point := range ciphertext.Points {
+		if _, err := unmarshalPoint(point); err != nil {
+			return errors.Wrapf(err, "point %d", i)
+		}
+	}
+	return nil
+}
+
+// Checks that p = 2q + 1 is a safe prime of at least minModulusBits bits and
+// g generates subgroup of prime order q
+func validateSchnorrGroup(p, q, g *big.Int) error {
+	if p == nil || q == nil || g == nil {
+		return errors.New("group parameters are missing")
+	}
+	if p.BitLen() < minModulusBits {
+		return errors.Errorf("modulus is %d bits long, expected at least %d", p.BitLen(), minModulusBits)
+	}
+	expected := new(big.Int).Lsh(q, 1)
+	if expected.Add(expected, big.NewInt(1)).Cmp(p) != 0 {
+		return errors.New("modulus is not 2q + 1")
+	}
+	if !q.ProbablyPrime(20) || !p.ProbablyPrime(20) {
+		return errors.New("modulus is not a safe prime")
+	}
+	if err := validateSubgroupElement(g, p, q); err != nil {
+		return errors.Wrap(err, "generator")
+	}
+	return nil
+}
+
+// Checks that x is element of subgroup of order q other than identity
+//
+// Identity is refused as it cancels the mask of ciphertext slot, i.e.
+// exposes the plaintext.
+func validateSubgroupElement(x, p, q *big.Int) error {
+	if x != nil && x.Cmp(big.NewInt(1)) == 0 {
+		return errors.New("element is identity")
+	}
+	return validateInSubgroup(x, p, q)
+}
+
+// Checks that x is in range [1; p-1] and belongs to subgroup of order q
+func validateInSubgroup(x, p, q *big.Int) error {
+	if x == nil {
+		return errors.New("element is missing")
+	}
+	if x.Sign() <= 0 || x.Cmp(p) >= 0 {
+		return errors.New("element is out of range [1; p-1]")
+	}
+	if new(big.Int).Exp(x, q, p).Cmp(big.NewInt(1)) != 0 {
+		return errors.New("element is not in subgroup of order q")
+	}
+	return nil
+}
+
+// Checks that x is invertible element of Z_n*
+func validateUnit(x, n *big.Int) error {
+	if x == nil || x.Sign() <= 0 || x.Cmp(n) >= 0 {
+		return errors.New("element is out of range [1; n-1]")
+	}
+	if new(big.Int).GCD(nil, nil, x, n).Cmp(big.NewInt(1)) != 0 {
+		return errors.New("element is not invertible")
+	}
+	return nil
+}
+
+// Checks that inner products of L vectors bounded by `bound` are below q,
+// otherwise decryption is ambiguous
+func validateBound(bound *big.Int, l int, q *big.Int) error {
+	if bound == nil || bound.Sign() <= 0 {
+		return errors.New("bound must be positive")
+	}
+	product := new(big.Int).Mul(big.NewInt(int64(2*l)), new(big.Int).Mul(bound, bound))
+	if product.Cmp(q) >= 0 {
+		return errors.New("2 * l * bound^2 must be smaller than group order")
+	}
+	return nil
+}
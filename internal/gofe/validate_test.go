@@ -0,0 +1,169 @@
+package gofe
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/fentec-project/bn256"
+	gofe "github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/innerprod/simple"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+)
+
+func TestValidateMPK(t *testing.T) {
+	forEachScheme(t, func(t *testing.T, scheme Scheme) {
+		mpk, _, _ := setupScheme(t, scheme, 3)
+		assert.NoError(t, ValidateMPK(mpk), "honest mpk refused")
+
+		wrongL := mpk
+		wrongL.L = 4
+		assert.Error(t, ValidateMPK(wrongL), "mpk of wrong length accepted")
+	})
+
+	mpk, _, err := GenerateMasterKeys(3)
+	assert.NoError(t, err, "keygen failed")
+	var key ddhMPK
+	if err := json.Unmarshal(mpk.Key, &key); err != nil {
+		panic(err)
+	}
+	tamper := func(f func(key *ddhMPK)) data.MPK {
+		var tampered ddhMPK
+		if err := json.Unmarshal(mpk.Key, &tampered); err != nil {
+			panic(err)
+		}
+		f(&tampered)
+		encoded, err := json.Marshal(tampered)
+		if err != nil {
+			panic(err)
+		}
+		return data.MPK{Scheme: mpk.Scheme, L: mpk.L, Key: encoded}
+	}
+	pMinus1 := new(big.Int).Sub(key.Params.P, big.NewInt(1))
+
+	tests := map[string]func(key *ddhMPK){
+		"composite modulus": func(key *ddhMPK) {
+			key.Params.P = new(big.Int).Add(key.Params.P, big.NewInt(2))
+		},
+		"modulus isn't safe prime": func(key *ddhMPK) {
+			key.Params.Q = new(big.Int).Add(key.Params.Q, big.NewInt(2))
+		},
+		"short modulus": func(key *ddhMPK) {
+			params, err := simple.NewDDH(3, 256, big.NewInt(16))
+			if err != nil {
+				panic(err)
+			}
+			key.Params = params.Params
+		},
+		"generator of small subgroup": func(key *ddhMPK) {
+			key.Params.G = pMinus1
+		},
+		"identity generator": func(key *ddhMPK) {
+			key.Params.G = big.NewInt(1)
+		},
+		"element out of range": func(key *ddhMPK) {
+			key.Vector[1] = new(big.Int).Add(key.Vector[1], key.Params.P)
+		},
+		"element outside subgroup": func(key *ddhMPK) {
+			key.Vector[2] = new(big.Int).Sub(key.Params.P, key.Vector[2])
+		},
+		"identity element": func(key *ddhMPK) {
+			key.Vector[0] = big.NewInt(1)
+		},
+		"bound exceeds group order": func(key *ddhMPK) {
+			key.Params.Bound = key.Params.Q
+		},
+		"params of another length": func(key *ddhMPK) {
+			key.Params.L = 2
+		},
+	}
+	for name, f := range tests {
+		assert.Error(t, ValidateMPK(tamper(f)), name)
+	}
+
+	t.Run("ecddh", func(t *testing.T) {
+		mpk, _, err := GenerateMasterKeysWith(ECDDH{Bound: big.NewInt(1024)}, 2)
+		assert.NoError(t, err, "keygen failed")
+		var key ecddhMPK
+		if err := json.Unmarshal(mpk.Key, &key); err != nil {
+			panic(err)
+		}
+		key.Vector[1] = make([]byte, coordSize+1)
+		encoded, _ := json.Marshal(key)
+		assert.Error(t, ValidateMPK(data.MPK{Scheme: mpk.Scheme, L: mpk.L, Key: encoded}), "point at infinity accepted")
+	})
+}
+
+func TestValidateCiphertext(t *testing.T) {
+	forEachScheme(t, func(t *testing.T, scheme Scheme) {
+		mpk, sk, encrypt := setupScheme(t, scheme, 3)
+		ciphertext, err := encrypt(unitVector(3, 1))
+		assert.NoError(t, err, "encrypt")
+		assert.NoError(t, ValidateCiphertext(mpk, &ciphertext), "honest ciphertext refused")
+
+		var malformed *MalformedCiphertextError
+		short := ciphertext.Copy()
+		if short.Vector != nil {
+			short.Vector = short.Vector[1:]
+		} else {
+			short.Points = short.Points[1:]
+		}
+		assert.True(t, errors.As(ValidateCiphertext(mpk, short), &malformed), "ciphertext of wrong length accepted")
+		_, err = Decrypt(mpk, sk[1], short)
+		assert.True(t, errors.As(err, &malformed), "decrypted ciphertext of wrong length")
+
+		outOfRange := ciphertext.Copy()
+		if outOfRange.Vector != nil {
+			outOfRange.Vector[1] = big.NewInt(-1)
+		} else {
+			outOfRange.Points[1] = append([]byte{pointEvenY}, bytes.Repeat([]byte{0xff}, coordSize)...)
+		}
+		assert.True(t, errors.As(ValidateCiphertext(mpk, outOfRange), &malformed), "element out of range accepted")
+
+		mixed := data.Ciphertext{Vector: ciphertext.Vector, Points: [][]byte{marshalPoint(new(bn256.G1).ScalarBaseMult(big.NewInt(1)))}}
+		if ciphertext.Vector == nil {
+			mixed = data.Ciphertext{Vector: gofe.NewConstantVector(len(ciphertext.Points), big.NewInt(1))}
+		}
+		assert.Error(t, ValidateCiphertext(mpk, &mixed), "ciphertext of another kind accepted")
+	})
+
+	// Negated element is out of subgroup of quadratic residues
+	mpk, _, err := GenerateMasterKeys(2)
+	assert.NoError(t, err, "keygen failed")
+	ciphertext, err := Encrypt(mpk, unitVector(2, 0))
+	assert.NoError(t, err, "encrypt")
+	ddh, _, err := decodeDDHMPK(mpk)
+	if err != nil {
+		panic(err)
+	}
+	ciphertext.Vector[2] = new(big.Int).Sub(ddh.Params.P, ciphertext.Vector[2])
+	assert.Error(t, ValidateCiphertext(mpk, &ciphertext), "element outside subgroup accepted")
+}
+
+func TestVerifyKey(t *testing.T) {
+	forEachScheme(t, func(t *testing.T, scheme Scheme) {
+		mpk, sk, _ := setupScheme(t, scheme, 3)
+		if _, ok := scheme.(VerifiableKeyScheme); !ok {
+			assert.Error(t, VerifyKey(mpk, sk[0], 0), "key of scheme which can't verify keys accepted")
+			return
+		}
+
+		for i := range sk {
+			assert.NoError(t, VerifyKey(mpk, sk[i], i), "honest key of party %d refused", i+1)
+		}
+		assert.Error(t, VerifyKey(mpk, sk[0], 1), "key issued for another party accepted")
+		assert.Error(t, VerifyKey(mpk, sk[0], 3), "key of party out of range accepted")
+
+		// Authority hands out key of party 2 as if it was key of party 1
+		swapped := sk[1]
+		swapped.I = 0
+		assert.Error(t, VerifyKey(mpk, swapped, 0), "key of another slot accepted")
+
+		_, otherKeys, _ := setupScheme(t, scheme, 3)
+		assert.Error(t, VerifyKey(mpk, otherKeys[0], 0), "key of another mpk accepted")
+	})
+}
@@ -0,0 +1,102 @@
+// Package memo hybrid-encrypts short payloads attached to signals (e.g. tx
+// hash or invoice id), so only recipient of the signal can read them
+//
+// Memo is sealed anonymously: ephemeral X25519 key agreement with
+// recipient's public key followed by XSalsa20-Poly1305, as in
+// box.SealAnonymous. Sealed memo carries no recipient identifier, and every
+// memo is padded to MaxSize, so neither its content nor its length tell whose
+// memo it is.
+package memo
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/nacl/box"
+)
+
+const (
+	// Max length of memo in bytes
+	MaxSize = 128
+	// Length of public and private keys
+	KeySize = 32
+	// Round number (8 bytes) followed by length of memo (2 bytes)
+	headerSize = 10
+	// Length of every sealed memo
+	SealedSize = box.AnonymousOverhead + headerSize + MaxSize
+)
+
+// ErrNotRecipient is returned when memo is sealed to another key
+var ErrNotRecipient = errors.New("memo is sealed to another key")
+
+// Key pair of memo recipient, public key is registered in directory so
+// senders can seal memos to it
+type Key struct {
+	Public  []byte
+	Private []byte
+}
+
+// Generates key pair drawing randomness from given source
+func GenerateKey(random io.Reader) (*Key, error) {
+	public, private, err := box.GenerateKey(random)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate key")
+	}
+	return &Key{Public: public[:], Private: private[:]}, nil
+}
+
+// Seals memo attached to n-th round to recipient's public key
+//
+// Round number is sealed along with memo, so repository can't move memo to
+// another round of the same recipient.
+func Seal(random io.Reader, recipient []byte, n int, memo []byte) ([]byte, error) {
+	if len(memo) > MaxSize {
+		return nil, errors.Errorf("memo is %d bytes long, expected at most %d", len(memo), MaxSize)
+	}
+	if len(recipient) != KeySize {
+		return nil, errors.Errorf("expected public key of %d bytes, got %d", KeySize, len(recipient))
+	}
+	var public [KeySize]byte
+	copy(public[:], recipient)
+
+	plaintext := make([]byte, headerSize+MaxSize)
+	binary.BigEndian.PutUint64(plaintext[:8], uint64(n))
+	binary.BigEndian.PutUint16(plaintext[8:headerSize], uint16(len(memo)))
+	copy(plaintext[headerSize:], memo)
+
+	sealed, err := box.SealAnonymous(nil, plaintext, &public, random)
+	if err != nil {
+		return nil, errors.Wrap(err, "seal memo")
+	}
+	return sealed, nil
+}
+
+// Opens memo attached to n-th round
+//
+// Returns ErrNotRecipient if memo is sealed to another key, and an error if
+// memo is sealed to this key, but for another round.
+func (k *Key) Open(n int, sealed []byte) ([]byte, error) {
+	if len(k.Public) != KeySize || len(k.Private) != KeySize {
+		return nil, errors.New("malformed memo key")
+	}
+	if len(sealed) != SealedSize {
+		return nil, errors.Errorf("expected sealed memo of %d bytes, got %d", SealedSize, len(sealed))
+	}
+	var public, private [KeySize]byte
+	copy(public[:], k.Public)
+	copy(private[:], k.Private)
+
+	plaintext, ok := box.OpenAnonymous(nil, sealed, &public, &private)
+	if !ok {
+		return nil, ErrNotRecipient
+	}
+	if round := binary.BigEndian.Uint64(plaintext[:8]); round != uint64(n) {
+		return nil, errors.Errorf("memo is sealed for round %d", round)
+	}
+	length := int(binary.BigEndian.Uint16(plaintext[8:headerSize]))
+	if length > MaxSize {
+		return nil, errors.New("malformed memo")
+	}
+	return plaintext[headerSize : headerSize+length], nil
+}
@@ -0,0 +1,52 @@
+package memo
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSealOpen(t *testing.T) {
+	generate := func() *Key {
+		key, err := GenerateKey(rand.Reader)
+		if err != nil {
+			panic(err)
+		}
+		return key
+	}
+	alice, bob := generate(), generate()
+	memo := []byte("invoice #42")
+
+	sealed, err := Seal(rand.Reader, alice.Public, 3, memo)
+	assert.NoError(t, err, "seal memo")
+	assert.Len(t, sealed, SealedSize, "sealed memo has wrong size")
+
+	opened, err := alice.Open(3, sealed)
+	assert.NoError(t, err, "open memo")
+	assert.Equal(t, memo, opened, "memo is corrupted")
+
+	_, err = bob.Open(3, sealed)
+	assert.Equal(t, ErrNotRecipient, err, "memo opened by another key")
+
+	_, err = alice.Open(4, sealed)
+	assert.Error(t, err, "memo moved to another round accepted")
+
+	t.Run("Memos are indistinguishable by size", func(t *testing.T) {
+		empty, err := Seal(rand.Reader, bob.Public, 1, nil)
+		assert.NoError(t, err, "seal empty memo")
+		long, err := Seal(rand.Reader, alice.Public, 1, bytes.Repeat([]byte{1}, MaxSize))
+		assert.NoError(t, err, "seal memo of max size")
+		assert.Equal(t, len(empty), len(long), "sealed memos differ in size")
+
+		opened, err := bob.Open(1, empty)
+		assert.NoError(t, err, "open empty memo")
+		assert.Empty(t, opened, "empty memo isn't empty")
+	})
+
+	t.Run("Long memos are refused", func(t *testing.T) {
+		_, err := Seal(rand.Reader, alice.Public, 1, make([]byte, MaxSize+1))
+		assert.Error(t, err, "memo longer than max size sealed")
+	})
+}
@@ -3,18 +3,91 @@ package recipient
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
+	"sort"
 
 	"github.com/pkg/errors"
 
 	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/memo"
 )
 
 // Party holding RecipientSecretKey which can be used
 // to decrypt a signal
 type Party struct {
 	Secret data.RecipientSecretKey
+	// Key epoch which Secret was issued for
+	Epoch int `json:",omitempty"`
+	// Keys of previous key epochs, they decrypt rounds published before
+	// party was re-keyed
+	Retired map[int]data.RecipientSecretKey `json:",omitempty"`
+	// Fingerprints of mpk of every key epoch party holds key of, they're
+	// pinned when keys are issued (legacy files without any pins are pinned
+	// on first use) and checked against repository on every load
+	Pinned map[int]string `json:",omitempty"`
+	// Key memos attached to party's signals are sealed to, its public part
+	// is registered in directory of repository
+	Memo *memo.Key `json:",omitempty"`
+}
+
+// Returns party's key of given key epoch, false if party held no key in that
+// epoch (e.g. it was added later)
+func (p *Party) KeyOf(epoch int) (data.RecipientSecretKey, bool) {
+	if epoch == p.Epoch {
+		return p.Secret, true
+	}
+	sk, ok := p.Retired[epoch]
+	return sk, ok
+}
+
+// Pins fingerprint of mpk which party's key of given epoch belongs to
+func (p *Party) Pin(epoch int, mpk data.MPK) {
+	if p.Pinned == nil {
+		p.Pinned = map[int]string{}
+	}
+	p.Pinned[epoch] = mpk.Fingerprint()
+}
+
+// Checks mpk of given key epoch against pinned fingerprint, returns false if
+// no fingerprint is pinned for this epoch
+func (p *Party) CheckPinned(epoch int, mpk data.MPK) (bool, error) {
+	pinned, ok := p.Pinned[epoch]
+	if !ok {
+		return false, nil
+	}
+	if fingerprint := mpk.Fingerprint(); fingerprint != pinned {
+		return true, errors.Errorf("mpk of key epoch %d has fingerprint %s, party pinned %s", epoch, fingerprint, pinned)
+	}
+	return true, nil
+}
+
+// Returns key epochs party holds key of in ascending order
+func (p *Party) Epochs() []int {
+	epochs := []int{p.Epoch}
+	for epoch := range p.Retired {
+		epochs = append(epochs, epoch)
+	}
+	sort.Ints(epochs)
+	return epochs
+}
+
+// Replaces party's key with key of a new key epoch, the current key is
+// retired
+func (p *Party) Rekey(epoch int, sk data.RecipientSecretKey) error {
+	if epoch <= p.Epoch {
+		return errors.Errorf("party already holds key of epoch %d", p.Epoch)
+	}
+	if sk.I != p.Secret.I {
+		return errors.Errorf("key of slot %d issued to party %d", sk.I+1, p.Secret.I+1)
+	}
+	if p.Retired == nil {
+		p.Retired = map[int]data.RecipientSecretKey{}
+	}
+	p.Retired[p.Epoch] = p.Secret
+	p.Secret, p.Epoch = sk, epoch
+	return nil
 }
 
 // Saves party secret key at `{dir}/party_{i}.json`
@@ -44,6 +117,28 @@ func (p *Party) SaveRecipient(dir string) error {
 	return nil
 }
 
+// Overwrites party secret key saved at `{dir}/party_{i}.json`
+//
+// File is replaced atomically, so party's keys aren't lost if writing fails.
+func (p *Party) UpdateRecipient(dir string) error {
+	file, err := ioutil.TempFile(dir, "party_*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "create temp file")
+	}
+	err = json.NewEncoder(file).Encode(p)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path.Join(dir, fmt.Sprintf("party_%d.json", p.Secret.I+1)))
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return errors.Wrap(err, "write party secret key")
+	}
+	return nil
+}
+
 // Loads party secret key from `{path}/party_{i}.json`
 func LoadRecipient(dir string, i int) (*Party, error) {
 	filepath := path.Join(dir, fmt.Sprintf("party_%d.json", i))
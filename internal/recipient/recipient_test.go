@@ -1,8 +1,8 @@
 package recipient
 
 import (
+	"encoding/json"
 	"io/ioutil"
-	"math/big"
 	"os"
 	"testing"
 
@@ -13,7 +13,7 @@ import (
 
 func TestMarshalling(t *testing.T) {
 	// Create fake party for test
-	secret := big.NewInt(1234)
+	secret := json.RawMessage("1234")
 	party := Party{Secret: data.RecipientSecretKey{
 		I:          1,
 		DerivedKey: secret,
@@ -35,8 +35,55 @@ func TestMarshalling(t *testing.T) {
 	})
 
 	t.Run("Load", func(t *testing.T) {
-		party2, err := LoadRecipient(dir, 1)
+		party2, err := LoadRecipient(dir, 2)
 		assert.NoError(t, err, "load party")
 		assert.Equal(t, &party, party2)
 	})
+
+	t.Run("Rekey", func(t *testing.T) {
+		rekeyed := party
+		sk := data.RecipientSecretKey{I: 1, DerivedKey: json.RawMessage("5678")}
+		assert.Error(t, rekeyed.Rekey(0, sk), "rekeyed within the same epoch")
+		assert.Error(t, rekeyed.Rekey(2, data.RecipientSecretKey{I: 0}), "rekeyed with key of another slot")
+		assert.NoError(t, rekeyed.Rekey(2, sk), "rekey")
+		assert.NoError(t, rekeyed.UpdateRecipient(dir), "update party")
+
+		party2, err := LoadRecipient(dir, 2)
+		assert.NoError(t, err, "load party")
+		assert.Equal(t, &rekeyed, party2)
+		for epoch, expected := range map[int]json.RawMessage{0: secret, 2: sk.DerivedKey} {
+			key, ok := party2.KeyOf(epoch)
+			assert.True(t, ok, "no key of epoch", epoch)
+			assert.Equal(t, expected, key.DerivedKey)
+		}
+		_, ok := party2.KeyOf(1)
+		assert.False(t, ok, "key of epoch party wasn't keyed in")
+	})
+
+	t.Run("Pin", func(t *testing.T) {
+		pinned := party
+		mpk := data.MPK{Scheme: "ddh", L: 2, Key: json.RawMessage(`{"Vector": [1, 2]}`)}
+		ok, err := pinned.CheckPinned(0, mpk)
+		assert.NoError(t, err)
+		assert.False(t, ok, "epoch is pinned before Pin")
+
+		pinned.Pin(0, mpk)
+		reformatted := mpk
+		reformatted.Key = json.RawMessage(`{"Vector":[1,2]}`)
+		reformatted.Profile = &data.Profile{Name: "demo"}
+		ok, err = pinned.CheckPinned(0, reformatted)
+		assert.NoError(t, err, "formatting or profile changed fingerprint")
+		assert.True(t, ok)
+
+		another := mpk
+		another.Key = json.RawMessage(`{"Vector":[1,3]}`)
+		_, err = pinned.CheckPinned(0, another)
+		assert.Error(t, err, "another mpk matched pinned fingerprint")
+
+		// Max signals places boundaries of accumulator epochs
+		capacity := reformatted
+		capacity.Profile = &data.Profile{Name: "demo", MaxSignals: 5}
+		_, err = pinned.CheckPinned(0, capacity)
+		assert.Error(t, err, "mpk with edited max signals matched pinned fingerprint")
+	})
 }
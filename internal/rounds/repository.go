@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/pkg/errors"
+	"io/ioutil"
 	"os"
 	"path"
 
 	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/memo"
 )
 
 // Manage access to rounds
@@ -16,6 +19,8 @@ import (
 // just stores everything at filesystem.
 type Repository struct {
 	path string
+	// Fingerprints of mpks which passed validation
+	valid map[string]bool
 }
 
 // Creates new empty repository in directory `path`
@@ -23,6 +28,9 @@ type Repository struct {
 // Will create `path` directory (if it isn't present) and file
 // `{path}/round0.json` containing `mpk`
 func NewEmptyRepository(dir string, mpk data.MPK) (*Repository, error) {
+	if err := gofe.ValidateMPK(mpk); err != nil {
+		return nil, err
+	}
 	err := os.MkdirAll(dir, 0777)
 	if err != nil {
 		return nil, errors.Wrap(err, "create dir")
@@ -44,7 +52,7 @@ func NewEmptyRepository(dir string, mpk data.MPK) (*Repository, error) {
 		return nil, errors.Wrap(err, "close file round0.json")
 	}
 
-	return &Repository{path: dir}, nil
+	return &Repository{path: dir, valid: map[string]bool{mpk.Fingerprint(): true}}, nil
 }
 
 // Tries to open existing repository
@@ -57,11 +65,38 @@ func OpenRepository(path string) (*Repository, error) {
 	if !fileInfo.IsDir() {
 		return nil, errors.New("repo is not a directory")
 	}
-	return &Repository{path: path}, nil
+	return &Repository{path: path, valid: map[string]bool{}}, nil
+}
+
+// MalformedRoundError is returned when round read from repository or being
+// published doesn't match mpk of its key epoch
+type MalformedRoundError struct {
+	Round int
+	Err   error
+}
+
+func (e *MalformedRoundError) Error() string {
+	return fmt.Sprintf("round %d is malformed: %v", e.Round, e.Err)
+}
+
+func (e *MalformedRoundError) Unwrap() error {
+	return e.Err
 }
 
 // Retrieves i-th round from repository
+//
+// Returns *MalformedRoundError if round isn't well-formed ciphertext under
+// mpk of its key epoch.
 func (r *Repository) GetRound(i int) (*data.Ciphertext, error) {
+	round, err := r.GetRoundWithProof(i)
+	if err != nil {
+		return nil, err
+	}
+	return &round.Ciphertext, nil
+}
+
+// Retrieves i-th round together with its delta ciphertext and one-hot proof
+func (r *Repository) GetRoundWithProof(i int) (*data.Round, error) {
 	filename := path.Join(r.path, fmt.Sprintf("round_%d.json", i))
 	file, err := os.Open(filename)
 	if err != nil {
@@ -71,13 +106,159 @@ func (r *Repository) GetRound(i int) (*data.Ciphertext, error) {
 		_ = file.Close()
 	}()
 
-	var ciphertext data.Ciphertext
-	err = json.NewDecoder(file).Decode(&ciphertext)
+	var round data.Round
+	err = json.NewDecoder(file).Decode(&round)
 	if err != nil {
 		return nil, errors.Wrap(err, "decode/read round")
 	}
+	if err := r.validateRound(i, &round); err != nil {
+		return nil, err
+	}
+
+	return &round, nil
+}
+
+// Checks that ciphertext and delta (if present) of n-th round are
+// well-formed under mpk of its key epoch
+func (r *Repository) validateRound(n int, round *data.Round) error {
+	mpk, _, err := r.GetMPKOf(n)
+	if err != nil {
+		return err
+	}
+	if err := gofe.ValidateCiphertext(mpk, &round.Ciphertext); err != nil {
+		return &MalformedRoundError{Round: n, Err: err}
+	}
+	if round.Memo != nil && len(round.Memo) != memo.SealedSize {
+		return &MalformedRoundError{Round: n, Err: errors.Errorf("expected sealed memo of %d bytes, got %d", memo.SealedSize, len(round.Memo))}
+	}
+	if round.Delta == nil {
+		return nil
+	}
+	if err := gofe.ValidateCiphertext(mpk, round.Delta); err != nil {
+		return &MalformedRoundError{Round: n, Err: errors.Wrap(err, "delta")}
+	}
+	return nil
+}
+
+// Verifies that n-th round is obtained by accumulating its one-hot delta
+// ciphertext into previous round (or it's just the delta if n-th round starts
+// an epoch)
+func (r *Repository) VerifyRound(n int) error {
+	round, err := r.GetRoundWithProof(n)
+	if err != nil {
+		return errors.Wrapf(err, "retrieve round %d", n)
+	}
+	return r.verifyRound(n, round)
+}
 
-	return &ciphertext, nil
+func (r *Repository) verifyRound(n int, round *data.Round) error {
+	if n < 1 {
+		return errors.New("rounds are numbered from 1")
+	}
+	epochs, err := r.KeyEpochs()
+	if err != nil {
+		return err
+	}
+	epoch := keyEpochOf(epochs, n)
+	start, err := chainStart(epochs, n)
+	if err != nil {
+		return err
+	}
+	var previous *data.Ciphertext
+	if n != start {
+		previous, err = r.GetRound(n - 1)
+		if err != nil {
+			return errors.Wrapf(err, "retrieve round %d", n-1)
+		}
+	}
+	return gofe.VerifyRound(epochs[epoch].MPK, previous, round)
+}
+
+// Recomputes accumulator chain starting from the first round, verifying
+// every round against the previous one
+//
+// Returns amount of consistent rounds n. If error is returned, round n+1 is
+// the first inconsistent one.
+func (r *Repository) VerifyChain() (int, error) {
+	epochs, err := r.KeyEpochs()
+	if err != nil {
+		return 0, err
+	}
+
+	var previous *data.Ciphertext
+	for n := 0; ; n++ {
+		start, err := chainStart(epochs, n+1)
+		if err != nil {
+			return n, err
+		}
+		if n+1 == start {
+			previous = nil
+		}
+		round, err := r.GetRoundWithProof(n + 1)
+		if err != nil && os.IsNotExist(errors.Cause(err)) {
+			return n, nil
+		} else if err != nil {
+			return n, errors.Wrapf(err, "retrieve round %d", n+1)
+		}
+		mpk := epochs[keyEpochOf(epochs, n+1)].MPK
+		if err := gofe.VerifyRound(mpk, previous, round); err != nil {
+			return n, errors.Wrapf(err, "round %d", n+1)
+		}
+		previous = &round.Ciphertext
+	}
+}
+
+// Returns amount of rounds in every accumulator epoch of current key epoch, 0
+// if accumulator is never restarted
+func (r *Repository) EpochLength() (int, error) {
+	mpk, err := r.GetMPK()
+	if err != nil {
+		return 0, errors.Wrap(err, "retrieve mpk")
+	}
+	return gofe.EpochLength(mpk)
+}
+
+// Returns number of epoch (0-indexed) which n-th round belongs to
+func EpochOf(n, length int) int {
+	if length == 0 || n < 1 {
+		return 0
+	}
+	return (n - 1) / length
+}
+
+// Returns the first round of epoch which n-th round belongs to
+//
+// The first round of an epoch starts fresh accumulator chain, i.e. it's
+// accumulated onto nothing as if it was the first round of repository.
+func EpochStart(n, length int) int {
+	if length == 0 || n < 1 {
+		return 1
+	}
+	return EpochOf(n, length)*length + 1
+}
+
+// Returns the first round of accumulator chain which n-th round belongs to
+//
+// Chain is restarted when a new key epoch begins and when accumulator of the
+// key epoch reaches its capacity.
+func (r *Repository) ChainStart(n int) (int, error) {
+	epochs, err := r.KeyEpochs()
+	if err != nil {
+		return 0, err
+	}
+	return chainStart(epochs, n)
+}
+
+func chainStart(epochs []data.KeyEpoch, n int) (int, error) {
+	epoch := epochs[keyEpochOf(epochs, n)]
+	length, err := gofe.EpochLength(epoch.MPK)
+	if err != nil {
+		return 0, errors.Wrap(err, "epoch length")
+	}
+	if n < epoch.FirstRound {
+		return epoch.FirstRound, nil
+	}
+	return epoch.FirstRound - 1 + EpochStart(n-epoch.FirstRound+1, length), nil
 }
 
 // Retrieves the last published round from repository
@@ -105,21 +286,37 @@ func (r *Repository) GetLastRound() (int, *data.Ciphertext, error) {
 	if n == 0 {
 		return 0, nil, nil
 	}
+	if err := r.validateRound(n, &data.Round{Ciphertext: ciphertext}); err != nil {
+		return 0, nil, err
+	}
 	return n, &ciphertext, nil
 }
 
 // Publishes a new round into repository
 //
 // Creates file `{repository}/round_{n}.json`. It's an error if this file
-// already exist.
-func (r *Repository) PublishRound(n int, ciphertext *data.Ciphertext) error {
+// already exist, or if round isn't previous round accumulated with its delta,
+// or if delta's one-hot proof doesn't verify. Malformed rounds are refused
+// with *MalformedRoundError.
+func (r *Repository) PublishRound(n int, round *data.Round) error {
+	if n < 1 {
+		return errors.New("rounds are numbered from 1")
+	}
+	if err := r.validateRound(n, round); err != nil {
+		return err
+	}
+	err := r.verifyRound(n, round)
+	if err != nil {
+		return errors.Wrap(err, "invalid round")
+	}
+
 	filename := path.Join(r.path, fmt.Sprintf("round_%d.json", n))
 	file, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666)
 	if err != nil {
 		return errors.Wrap(err, "create file for round")
 	}
 
-	err = json.NewEncoder(file).Encode(ciphertext)
+	err = json.NewEncoder(file).Encode(round)
 	if err != nil {
 		_ = file.Close()
 		return errors.Wrap(err, "encode/write to file")
@@ -131,22 +328,321 @@ func (r *Repository) PublishRound(n int, ciphertext *data.Ciphertext) error {
 	return nil
 }
 
-// Retrieves master public key
+// Retrieves master public key of current key epoch, new rounds are encrypted
+// under it
 func (r *Repository) GetMPK() (data.MPK, error) {
-	filename := path.Join(r.path, "round_0.json")
-	file, err := os.Open(filename)
+	epochs, err := r.KeyEpochs()
+	if err != nil {
+		return data.MPK{}, err
+	}
+	return epochs[len(epochs)-1].MPK, nil
+}
+
+// Retrieves master public key which n-th round is encrypted under, along with
+// number of its key epoch
+func (r *Repository) GetMPKOf(n int) (data.MPK, int, error) {
+	epochs, err := r.KeyEpochs()
+	if err != nil {
+		return data.MPK{}, 0, err
+	}
+	epoch := keyEpochOf(epochs, n)
+	return epochs[epoch].MPK, epoch, nil
+}
+
+// Retrieves all key epochs in order they began
+//
+// Key epoch 0 is the one created by keygen, its mpk is stored at
+// `{path}/round_0.json`. Every further epoch is stored at
+// `{path}/keys_{k}.json`.
+//
+// Repository isn't trusted, so mpk of every epoch is validated (see
+// gofe.ValidateMPK) before it's returned. Every epoch must begin after the
+// previous one, and no later than right after the last published round.
+func (r *Repository) KeyEpochs() ([]data.KeyEpoch, error) {
+	var mpk data.MPK
+	err := readJSON(path.Join(r.path, "round_0.json"), &mpk)
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieve mpk")
+	}
+	if err := r.validate(mpk); err != nil {
+		return nil, errors.Wrap(err, "mpk of key epoch 0")
+	}
+	epochs := []data.KeyEpoch{{FirstRound: 1, MPK: mpk}}
+	last := -1
+	for k := 1; ; k++ {
+		var epoch data.KeyEpoch
+		err := readJSON(path.Join(r.path, fmt.Sprintf("keys_%d.json", k)), &epoch)
+		if err != nil && os.IsNotExist(errors.Cause(err)) {
+			return epochs, nil
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "retrieve key epoch %d", k)
+		}
+		if epoch.MPK.L != mpk.L {
+			return nil, errors.Errorf("mpk of key epoch %d has %d slots, expected %d", k, epoch.MPK.L, mpk.L)
+		}
+		if previous := epochs[k-1].FirstRound; epoch.FirstRound <= previous {
+			return nil, errors.Errorf("key epoch %d begins at round %d, expected it to begin after round %d", k, epoch.FirstRound, previous)
+		}
+		if last < 0 {
+			last, err = r.lastRoundNumber()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if epoch.FirstRound > last+1 {
+			return nil, errors.Errorf("key epoch %d begins at round %d, but the last published round is %d", k, epoch.FirstRound, last)
+		}
+		if err := r.validate(epoch.MPK); err != nil {
+			return nil, errors.Wrapf(err, "mpk of key epoch %d", k)
+		}
+		epochs = append(epochs, epoch)
+	}
+}
+
+// Returns number of the last published round, rounds aren't read
+func (r *Repository) lastRoundNumber() (int, error) {
+	n := 0
+	for ; ; n++ {
+		_, err := os.Stat(path.Join(r.path, fmt.Sprintf("round_%d.json", n+1)))
+		if err != nil && os.IsNotExist(err) {
+			return n, nil
+		} else if err != nil {
+			return 0, errors.Wrap(err, "unexpected error while retrieving round")
+		}
+	}
+}
+
+// Validates mpk unless it has already passed validation
+func (r *Repository) validate(mpk data.MPK) error {
+	fingerprint := mpk.Fingerprint()
+	if r.valid[fingerprint] {
+		return nil
+	}
+	if err := gofe.ValidateMPK(mpk); err != nil {
+		return err
+	}
+	r.valid[fingerprint] = true
+	return nil
+}
+
+// Begins a new key epoch: rounds following the last published one are
+// encrypted under given mpk
+//
+// Returns number of the new key epoch. Earlier rounds stay encrypted under
+// mpk of their epochs, so they're still searchable by keys of these epochs.
+// Current key epoch must have at least one round, so no two epochs begin at
+// the same round.
+func (r *Repository) BeginKeyEpoch(mpk data.MPK) (int, error) {
+	epochs, err := r.KeyEpochs()
+	if err != nil {
+		return 0, err
+	}
+	if current := epochs[len(epochs)-1].MPK; mpk.L != current.L {
+		return 0, errors.Errorf("new mpk has %d slots, expected %d", mpk.L, current.L)
+	}
+	if err := r.validate(mpk); err != nil {
+		return 0, err
+	}
+	n, _, err := r.GetLastRound()
+	if err != nil {
+		return 0, errors.Wrap(err, "retrieve last round")
+	}
+	if current := epochs[len(epochs)-1]; current.FirstRound > n {
+		return 0, errors.Errorf("key epoch %d has no rounds yet, publish a round before beginning another one", len(epochs)-1)
+	}
+
+	k := len(epochs)
+	filename := path.Join(r.path, fmt.Sprintf("keys_%d.json", k))
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666)
+	if err != nil {
+		return 0, errors.Wrap(err, "create file for key epoch")
+	}
+	err = json.NewEncoder(file).Encode(data.KeyEpoch{FirstRound: n + 1, MPK: mpk})
 	if err != nil {
-		return data.MPK{}, errors.Wrap(err, "open round.json")
+		_ = file.Close()
+		return 0, errors.Wrap(err, "encode/write to file")
+	}
+	if err = file.Close(); err != nil {
+		return 0, errors.Wrap(err, "close file")
+	}
+	return k, nil
+}
+
+// Returns number of key epoch which n-th round belongs to
+func keyEpochOf(epochs []data.KeyEpoch, n int) int {
+	k := 0
+	for i, epoch := range epochs {
+		if epoch.FirstRound <= n {
+			k = i
+		}
+	}
+	return k
+}
+
+func readJSON(filepath string, v interface{}) error {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return errors.Wrap(err, "open file")
 	}
 	defer func() {
 		_ = file.Close()
 	}()
 
-	var mpk data.MPK
-	err = json.NewDecoder(file).Decode(&mpk)
+	err = json.NewDecoder(file).Decode(v)
 	if err != nil {
-		return data.MPK{}, errors.Wrap(err, "decode/read round")
+		return errors.Wrapf(err, "decode/read %s", path.Base(filepath))
 	}
+	return nil
+}
 
-	return mpk, nil
+// Retrieves assignment of slots to recipients
+//
+// Repository without `{path}/members.json` is the one which keys were issued
+// for all the slots at once, so every slot is considered assigned.
+func (r *Repository) GetMembership() (data.Membership, error) {
+	file, err := os.Open(path.Join(r.path, "members.json"))
+	if os.IsNotExist(err) {
+		mpk, err := r.GetMPK()
+		if err != nil {
+			return data.Membership{}, errors.Wrap(err, "retrieve mpk")
+		}
+		membership := data.Membership{Assigned: make([]int, mpk.L)}
+		for i := range membership.Assigned {
+			membership.Assigned[i] = i
+		}
+		return membership, nil
+	} else if err != nil {
+		return data.Membership{}, errors.Wrap(err, "open members.json")
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var membership data.Membership
+	err = json.NewDecoder(file).Decode(&membership)
+	if err != nil {
+		return data.Membership{}, errors.Wrap(err, "decode/read members")
+	}
+	return membership, nil
+}
+
+// Records that keys were issued only for the first `parties` slots, the rest
+// are reserved
+func (r *Repository) ReserveSlots(parties int) error {
+	membership := data.Membership{Assigned: make([]int, parties)}
+	for i := range membership.Assigned {
+		membership.Assigned[i] = i
+	}
+	return r.saveMembership(membership)
+}
+
+// Records that key of i-th slot (0-indexed) was issued
+//
+// It's an error if slot doesn't exist or is already assigned.
+func (r *Repository) AssignSlot(i int) error {
+	mpk, err := r.GetMPK()
+	if err != nil {
+		return errors.Wrap(err, "retrieve mpk")
+	}
+	if i < 0 || i >= mpk.L {
+		return errors.Errorf("expected slot in range [1; %d]", mpk.L)
+	}
+	membership, err := r.GetMembership()
+	if err != nil {
+		return err
+	}
+	if membership.IsAssigned(i) {
+		return errors.Errorf("slot %d is already assigned", i+1)
+	}
+	membership.Assigned = append(membership.Assigned, i)
+	return r.saveMembership(membership)
+}
+
+// Records that i-th slot (0-indexed) is retired, it's never signalled again
+//
+// It's an error if slot isn't assigned or is already revoked.
+func (r *Repository) RevokeSlot(i int) error {
+	membership, err := r.GetMembership()
+	if err != nil {
+		return err
+	}
+	if !membership.IsAssigned(i) {
+		return errors.Errorf("slot %d isn't assigned", i+1)
+	}
+	if membership.IsRevoked(i) {
+		return errors.Errorf("slot %d is already revoked", i+1)
+	}
+	membership.Revoked = append(membership.Revoked, i)
+	return r.saveMembership(membership)
+}
+
+// Overwrites `{path}/members.json`
+func (r *Repository) saveMembership(membership data.Membership) error {
+	return errors.Wrap(r.overwriteJSON("members", membership), "write members")
+}
+
+// Overwrites `{path}/{name}.json` atomically, so concurrent readers never
+// see it partially written
+func (r *Repository) overwriteJSON(name string, v interface{}) error {
+	file, err := ioutil.TempFile(r.path, name+"_*.json")
+	if err != nil {
+		return errors.Wrap(err, "create temp file")
+	}
+	err = json.NewEncoder(file).Encode(v)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path.Join(r.path, name+".json"))
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
+
+// Retrieves directory of memo public keys of recipients, keyed by slot
+// (0-indexed)
+func (r *Repository) GetMemoKeys() (map[int][]byte, error) {
+	keys := map[int][]byte{}
+	err := readJSON(path.Join(r.path, "memo_keys.json"), &keys)
+	if err != nil && !os.IsNotExist(errors.Cause(err)) {
+		return nil, errors.Wrap(err, "read memo keys")
+	}
+	return keys, nil
+}
+
+// Retrieves memo public key registered by recipient of i-th slot (0-indexed)
+func (r *Repository) GetMemoKey(i int) ([]byte, error) {
+	keys, err := r.GetMemoKeys()
+	if err != nil {
+		return nil, err
+	}
+	key, ok := keys[i]
+	if !ok {
+		return nil, errors.Errorf("party %d has no memo key registered", i+1)
+	}
+	return key, nil
+}
+
+// Registers memo public key of recipient of i-th slot (0-indexed) in
+// directory, replacing key it registered before
+func (r *Repository) RegisterMemoKey(i int, key []byte) error {
+	mpk, err := r.GetMPK()
+	if err != nil {
+		return errors.Wrap(err, "retrieve mpk")
+	}
+	if i < 0 || i >= mpk.L {
+		return errors.Errorf("expected slot in range [1; %d]", mpk.L)
+	}
+	if len(key) != memo.KeySize {
+		return errors.Errorf("expected memo key of %d bytes, got %d", memo.KeySize, len(key))
+	}
+	keys, err := r.GetMemoKeys()
+	if err != nil {
+		return err
+	}
+	keys[i] = key
+	return errors.Wrap(r.overwriteJSON("memo_keys", keys), "write memo keys")
 }
@@ -1,6 +1,10 @@
 package rounds
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"math/big"
 	"os"
@@ -8,27 +12,19 @@ import (
 	"testing"
 
 	gofe "github.com/fentec-project/gofe/data"
-	"github.com/fentec-project/gofe/innerprod/simple"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+	gofe2 "github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/memo"
 )
 
 func TestRepository(t *testing.T) {
 	var r *Repository
-	mpk := data.MPK{
-		DDH: &simple.DDH{Params: &simple.DDHParams{
-			L:     4,
-			Bound: big.NewInt(1),
-			G:     big.NewInt(2),
-			P:     big.NewInt(3),
-			Q:     big.NewInt(4),
-		}},
-		Vector: gofe.NewVector([]*big.Int{
-			big.NewInt(1),
-			big.NewInt(2),
-			big.NewInt(3),
-		}),
+	mpk, _, err := gofe2.GenerateMasterKeys(3)
+	if err != nil {
+		panic(err)
 	}
 
 	// Create temp dir
@@ -74,15 +70,17 @@ func TestRepository(t *testing.T) {
 		assert.Nil(t, ciphertext)
 	})
 
-	ciphertext1 := data.Ciphertext{Vector: gofe.NewConstantVector(5, big.NewInt(10))}
-	ciphertext2 := data.Ciphertext{Vector: gofe.NewConstantVector(5, big.NewInt(6))}
+	// round1 signals party 1, round2 = round1 + signal to party 3
+	round1 := signalRound(mpk, nil, 0)
+	round2 := signalRound(mpk, &round1.Ciphertext, 2)
+	ciphertext1, ciphertext2 := round1.Ciphertext, round2.Ciphertext
 	roundsPublished := t.Run("Publish rounds", func(t *testing.T) {
 		if !repositoryCreated {
 			t.Skip()
 		}
-		err := r.PublishRound(1, &ciphertext1)
+		err := r.PublishRound(1, round1)
 		assert.NoError(t, err, "publish round1")
-		err = r.PublishRound(2, &ciphertext2)
+		err = r.PublishRound(2, round2)
 		assert.NoError(t, err, "publish round2")
 	})
 
@@ -90,10 +88,41 @@ func TestRepository(t *testing.T) {
 		if !repositoryCreated || !roundsPublished {
 			t.Skip()
 		}
-		err := r.PublishRound(1, &ciphertext2)
+		err := r.PublishRound(1, round2)
 		assert.Error(t, err)
 	})
 
+	t.Run("Round without valid proof is refused", func(t *testing.T) {
+		if !repositoryCreated || !roundsPublished {
+			t.Skip()
+		}
+		// x = [0 500 0]
+		x := gofe.NewConstantVector(3, big.NewInt(0))
+		x[1] = big.NewInt(500)
+		delta, err := gofe2.Encrypt(mpk, x)
+		assert.NoError(t, err)
+		ciphertext := ciphertext2.Copy()
+		assert.NoError(t, gofe2.Accumulate(mpk, ciphertext, &delta))
+
+		err = r.PublishRound(3, &data.Round{Ciphertext: *ciphertext, Delta: &delta})
+		assert.Error(t, err, "published round without proof")
+		err = r.PublishRound(3, &data.Round{Ciphertext: *ciphertext, Delta: &delta, Proof: round2.Proof})
+		assert.Error(t, err, "published round with proof of another round")
+		err = r.VerifyRound(3)
+		assert.Error(t, err, "round 3 must not be published")
+	})
+
+	t.Run("Published rounds verify", func(t *testing.T) {
+		if !repositoryCreated || !roundsPublished {
+			t.Skip()
+		}
+		assert.NoError(t, r.VerifyRound(1), "verify round1")
+		assert.NoError(t, r.VerifyRound(2), "verify round2")
+		n, err := r.VerifyChain()
+		assert.NoError(t, err, "verify chain")
+		assert.Equal(t, 2, n)
+	})
+
 	t.Run("Retrieve rounds", func(t *testing.T) {
 		if !repositoryCreated || !roundsPublished {
 			t.Skip()
@@ -115,4 +144,435 @@ func TestRepository(t *testing.T) {
 		assert.Equal(t, &ciphertext2, c)
 		assert.Equal(t, 2, n)
 	})
+
+	t.Run("Chain reset is detected", func(t *testing.T) {
+		if !repositoryCreated || !roundsPublished {
+			t.Skip()
+		}
+		// Round 3 drops everything accumulated before, it's written bypassing
+		// PublishRound which would refuse it
+		round3 := signalRound(mpk, nil, 1)
+		file, err := os.Create(path.Join(repo, "round_3.json"))
+		assert.NoError(t, err)
+		assert.NoError(t, json.NewEncoder(file).Encode(round3))
+		assert.NoError(t, file.Close())
+
+		n, err := r.VerifyChain()
+		assert.Error(t, err, "verify chain")
+		assert.Equal(t, 2, n, "wrong amount of consistent rounds")
+	})
+
+	t.Run("Malformed rounds are refused", func(t *testing.T) {
+		if !repositoryCreated || !roundsPublished {
+			t.Skip()
+		}
+		var malformed *MalformedRoundError
+		short := ciphertext2.Copy()
+		short.Vector = short.Vector[:3]
+		err := r.PublishRound(4, &data.Round{Ciphertext: *short, Delta: round2.Delta, Proof: round2.Proof})
+		assert.True(t, errors.As(err, &malformed), "published round of wrong length")
+		err = r.PublishRound(4, &data.Round{Ciphertext: round2.Ciphertext, Delta: round2.Delta, Proof: round2.Proof, Memo: []byte("memo")})
+		assert.True(t, errors.As(err, &malformed), "published round with memo of wrong size")
+
+		// Round 4 has element outside of the group, it's written bypassing
+		// PublishRound
+		round4 := *round2
+		round4.Ciphertext = *ciphertext2.Copy()
+		round4.Vector[0] = big.NewInt(0)
+		file, err := os.Create(path.Join(repo, "round_4.json"))
+		assert.NoError(t, err)
+		assert.NoError(t, json.NewEncoder(file).Encode(&round4))
+		assert.NoError(t, file.Close())
+
+		_, err = r.GetRound(4)
+		assert.True(t, errors.As(err, &malformed), "retrieved malformed round")
+		assert.Equal(t, 4, malformed.Round)
+		_, _, err = r.GetLastRound()
+		assert.True(t, errors.As(err, &malformed), "retrieved malformed last round")
+	})
+}
+
+func TestEpochs(t *testing.T) {
+	// Accumulator of such mpk is restarted every 2 rounds
+	scheme, profile, _, err := gofe2.ConfigureScheme(gofe2.DefaultScheme, gofe2.Profile{ModulusBits: 512, MaxSignals: 2})
+	if err != nil {
+		panic(err)
+	}
+	mpk, _, err := gofe2.GenerateMasterKeysWith(scheme, 2)
+	if err != nil {
+		panic(err)
+	}
+	mpk.Profile = &profile
+
+	dir, err := ioutil.TempDir("", "epochs")
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	r, err := NewEmptyRepository(path.Join(dir, "repo"), mpk)
+	if err != nil {
+		panic(err)
+	}
+
+	length, err := r.EpochLength()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, length)
+	for n, epoch := range []int{0, 0, 0, 1, 1, 2} {
+		assert.Equal(t, epoch, EpochOf(n, length), "epoch of round %d", n)
+	}
+	assert.Equal(t, 3, EpochStart(4, length))
+
+	round1 := signalRound(mpk, nil, 0)
+	round2 := signalRound(mpk, &round1.Ciphertext, 1)
+	assert.NoError(t, r.PublishRound(1, round1), "publish round1")
+	assert.NoError(t, r.PublishRound(2, round2), "publish round2")
+
+	err = r.PublishRound(3, signalRound(mpk, &round2.Ciphertext, 0))
+	assert.Error(t, err, "accumulated across epochs")
+	round3 := signalRound(mpk, nil, 0)
+	assert.NoError(t, r.PublishRound(3, round3), "publish round3")
+	round4 := signalRound(mpk, &round3.Ciphertext, 0)
+	assert.NoError(t, r.PublishRound(4, round4), "publish round4")
+	assert.NoError(t, r.PublishRound(5, signalRound(mpk, nil, 1)), "publish round5")
+
+	n, err := r.VerifyChain()
+	assert.NoError(t, err, "verify chain")
+	assert.Equal(t, 5, n)
+}
+
+// Drives a few hundred rounds through the repository, checking that stored
+// rounds don't grow while signals accumulate
+func TestStoredRoundsHaveConstantSize(t *testing.T) {
+	mpk, sk, err := gofe2.GenerateMasterKeys(2)
+	if err != nil {
+		panic(err)
+	}
+	dir, err := ioutil.TempDir("", "stored_rounds")
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	r, err := NewEmptyRepository(path.Join(dir, "repo"), mpk)
+	if err != nil {
+		panic(err)
+	}
+	length, err := r.EpochLength()
+	assert.NoError(t, err)
+
+	const rounds = 300
+	if length != 0 && length < rounds {
+		t.Fatalf("accumulator is restarted every %d rounds", length)
+	}
+	var previous *data.Ciphertext
+	for n := 1; n <= rounds; n++ {
+		if !assert.NoError(t, r.PublishRound(n, signalRound(mpk, previous, n%2)), "publish round %d", n) {
+			return
+		}
+		last, ciphertext, err := r.GetLastRound()
+		assert.NoError(t, err, "get last round")
+		assert.Equal(t, n, last)
+		previous = ciphertext
+	}
+	assert.NoError(t, r.VerifyRound(rounds), "verify last round")
+
+	stat := func(n int) int64 {
+		info, err := os.Stat(path.Join(dir, "repo", fmt.Sprintf("round_%d.json", n)))
+		if err != nil {
+			panic(err)
+		}
+		return info.Size()
+	}
+	// Elements of different rounds might differ in a few leading zeros
+	assert.LessOrEqual(t, stat(rounds), stat(1)*11/10, "round %d is larger than round 1", rounds)
+
+	for i, expected := range []int64{rounds / 2, rounds / 2} {
+		v, err := gofe2.Decrypt(mpk, sk[i], previous)
+		assert.NoError(t, err, "decrypt last round")
+		assert.Equal(t, big.NewInt(expected), v, "wrong amount of signals of party %d", i+1)
+	}
+}
+
+func TestMembership(t *testing.T) {
+	mpk, _, err := gofe2.GenerateMasterKeys(4)
+	if err != nil {
+		panic(err)
+	}
+	dir, err := ioutil.TempDir("", "membership")
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	r, err := NewEmptyRepository(path.Join(dir, "repo"), mpk)
+	if err != nil {
+		panic(err)
+	}
+
+	membership, err := r.GetMembership()
+	assert.NoError(t, err, "get membership")
+	assert.Equal(t, []int{0, 1, 2, 3}, membership.Assigned, "slots of repository without reservation")
+
+	assert.NoError(t, r.ReserveSlots(2), "reserve slots")
+	membership, err = r.GetMembership()
+	assert.NoError(t, err, "get membership")
+	assert.True(t, membership.IsAssigned(1))
+	assert.False(t, membership.IsAssigned(2))
+	next, err := membership.NextFree(mpk.L)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, next)
+
+	assert.NoError(t, r.AssignSlot(3), "assign slot")
+	assert.Error(t, r.AssignSlot(3), "assigned slot twice")
+	assert.Error(t, r.AssignSlot(4), "assigned slot out of range")
+	assert.NoError(t, r.AssignSlot(2), "assign slot")
+
+	membership, err = r.GetMembership()
+	assert.NoError(t, err, "get membership")
+	assert.Equal(t, []int{0, 1, 3, 2}, membership.Assigned)
+	_, err = membership.NextFree(mpk.L)
+	assert.Error(t, err, "free slot found")
+
+	assert.NoError(t, r.RevokeSlot(1), "revoke slot")
+	assert.Error(t, r.RevokeSlot(1), "revoked slot twice")
+	membership, err = r.GetMembership()
+	assert.NoError(t, err, "get membership")
+	assert.True(t, membership.IsRevoked(1))
+	assert.False(t, membership.IsRevoked(0))
+}
+
+func TestMemoKeys(t *testing.T) {
+	mpk, _, err := gofe2.GenerateMasterKeys(2)
+	if err != nil {
+		panic(err)
+	}
+	dir, err := ioutil.TempDir("", "memo_keys")
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	r, err := NewEmptyRepository(path.Join(dir, "repo"), mpk)
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = r.GetMemoKey(0)
+	assert.Error(t, err, "got memo key which isn't registered")
+
+	key := bytes.Repeat([]byte{1}, memo.KeySize)
+	assert.NoError(t, r.RegisterMemoKey(1, key), "register memo key")
+	assert.Error(t, r.RegisterMemoKey(2, key), "registered memo key of slot out of range")
+	assert.Error(t, r.RegisterMemoKey(0, key[1:]), "registered memo key of wrong size")
+
+	registered, err := r.GetMemoKey(1)
+	assert.NoError(t, err, "get memo key")
+	assert.Equal(t, key, registered)
+
+	replaced := bytes.Repeat([]byte{2}, memo.KeySize)
+	assert.NoError(t, r.RegisterMemoKey(1, replaced), "replace memo key")
+	registered, err = r.GetMemoKey(1)
+	assert.NoError(t, err, "get memo key")
+	assert.Equal(t, replaced, registered, "memo key isn't replaced")
+}
+
+func TestKeyEpochs(t *testing.T) {
+	mpk, _, err := gofe2.GenerateMasterKeys(2)
+	if err != nil {
+		panic(err)
+	}
+	rekeyed, _, err := gofe2.RegenerateMasterKeys(rand.Reader, mpk)
+	if err != nil {
+		panic(err)
+	}
+	dir, err := ioutil.TempDir("", "key_epochs")
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	r, err := NewEmptyRepository(path.Join(dir, "repo"), mpk)
+	if err != nil {
+		panic(err)
+	}
+
+	round1 := signalRound(mpk, nil, 0)
+	round2 := signalRound(mpk, &round1.Ciphertext, 1)
+	assert.NoError(t, r.PublishRound(1, round1), "publish round1")
+	assert.NoError(t, r.PublishRound(2, round2), "publish round2")
+
+	another, _, err := gofe2.GenerateMasterKeys(3)
+	if err != nil {
+		panic(err)
+	}
+	_, err = r.BeginKeyEpoch(another)
+	assert.Error(t, err, "began key epoch of another length")
+	epoch, err := r.BeginKeyEpoch(rekeyed)
+	assert.NoError(t, err, "begin key epoch")
+	assert.Equal(t, 1, epoch)
+
+	current, err := r.GetMPK()
+	assert.NoError(t, err, "get mpk")
+	assert.Equal(t, rekeyed, current, "current mpk isn't the new one")
+	for n, expected := range map[int]int{1: 0, 2: 0, 3: 1, 10: 1} {
+		_, epoch, err := r.GetMPKOf(n)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, epoch, "key epoch of round %d", n)
+	}
+
+	err = r.PublishRound(3, signalRound(rekeyed, &round2.Ciphertext, 0))
+	assert.Error(t, err, "accumulated across key epochs")
+	err = r.PublishRound(3, signalRound(mpk, nil, 0))
+	assert.Error(t, err, "published round under mpk of previous epoch")
+	round3 := signalRound(rekeyed, nil, 0)
+	assert.NoError(t, r.PublishRound(3, round3), "publish round3")
+	assert.NoError(t, r.PublishRound(4, signalRound(rekeyed, &round3.Ciphertext, 1)), "publish round4")
+
+	start, err := r.ChainStart(4)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, start)
+	n, err := r.VerifyChain()
+	assert.NoError(t, err, "verify chain")
+	assert.Equal(t, 4, n)
+
+	// Key epoch 2 can't be rotated before any of its rounds is published
+	epoch, err = r.BeginKeyEpoch(mpk)
+	assert.NoError(t, err, "begin key epoch")
+	assert.Equal(t, 2, epoch)
+	_, err = r.BeginKeyEpoch(rekeyed)
+	assert.Error(t, err, "began key epoch after epoch without rounds")
+	epochs, err := r.KeyEpochs()
+	assert.NoError(t, err, "get key epochs")
+	assert.Len(t, epochs, 3)
+	assert.Equal(t, 5, epochs[2].FirstRound)
+	_, epoch, err = r.GetMPKOf(5)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, epoch, "round belongs to epoch without rounds")
+
+	// Malicious operator moves beginning of key epoch
+	tamper := func(change func(epoch *data.KeyEpoch)) error {
+		var tampered data.KeyEpoch
+		assert.NoError(t, readJSON(path.Join(dir, "repo", "keys_2.json"), &tampered))
+		original, err := json.Marshal(tampered)
+		if err != nil {
+			panic(err)
+		}
+		change(&tampered)
+		encoded, err := json.Marshal(tampered)
+		if err != nil {
+			panic(err)
+		}
+		if err := ioutil.WriteFile(path.Join(dir, "repo", "keys_2.json"), encoded, 0666); err != nil {
+			panic(err)
+		}
+		defer func() {
+			if err := ioutil.WriteFile(path.Join(dir, "repo", "keys_2.json"), original, 0666); err != nil {
+				panic(err)
+			}
+		}()
+		r, err := OpenRepository(path.Join(dir, "repo"))
+		assert.NoError(t, err, "open repository")
+		_, err = r.GetMPK()
+		return err
+	}
+	for _, firstRound := range []int{-1, 0, 1, 3, 6} {
+		err := tamper(func(epoch *data.KeyEpoch) { epoch.FirstRound = firstRound })
+		assert.Error(t, err, "key epoch beginning at round %d accepted", firstRound)
+	}
+
+	// Malicious operator replaces mpk of key epoch by malformed one
+	err = tamper(func(epoch *data.KeyEpoch) {
+		epoch.MPK.Key = json.RawMessage(`{"Params":{"L":2,"Bound":1024,"G":4,"P":23,"Q":11},"Vector":[2,3]}`)
+	})
+	assert.Error(t, err, "malformed mpk accepted")
+}
+
+// Search decrypts every round by key of its key epoch, so its range may span
+// rotations
+func TestSearchAcrossKeyEpochs(t *testing.T) {
+	mpk, sk, err := gofe2.GenerateMasterKeys(3)
+	if err != nil {
+		panic(err)
+	}
+	rekeyed, msk, err := gofe2.RegenerateMasterKeys(rand.Reader, mpk)
+	if err != nil {
+		panic(err)
+	}
+	dir, err := ioutil.TempDir("", "search_key_epochs")
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	r, err := NewEmptyRepository(path.Join(dir, "repo"), mpk)
+	if err != nil {
+		panic(err)
+	}
+
+	// Party 1 is signalled in rounds 2, 3 and 5, key is rotated after round 3
+	round1 := signalRound(mpk, nil, 0)
+	round2 := signalRound(mpk, &round1.Ciphertext, 1)
+	round3 := signalRound(mpk, &round2.Ciphertext, 1)
+	for n, round := range []*data.Round{round1, round2, round3} {
+		assert.NoError(t, r.PublishRound(n+1, round), "publish round %d", n+1)
+	}
+	_, err = r.BeginKeyEpoch(rekeyed)
+	assert.NoError(t, err, "begin key epoch")
+	round4 := signalRound(rekeyed, nil, 2)
+	round5 := signalRound(rekeyed, &round4.Ciphertext, 1)
+	assert.NoError(t, r.PublishRound(4, round4), "publish round 4")
+	assert.NoError(t, r.PublishRound(5, round5), "publish round 5")
+
+	keys := []data.RecipientSecretKey{sk[1]}
+	rekeyedSk, err := gofe2.DeriveKey(rekeyed, msk, 1)
+	if err != nil {
+		panic(err)
+	}
+	keys = append(keys, rekeyedSk)
+
+	// Counts signals received within rounds [1; n] like search does
+	var count func(n int) int64
+	count = func(n int) int64 {
+		if n == 0 {
+			return 0
+		}
+		mpk, epoch, err := r.GetMPKOf(n)
+		assert.NoError(t, err, "get mpk of round %d", n)
+		ciphertext, err := r.GetRound(n)
+		assert.NoError(t, err, "get round %d", n)
+		v, err := gofe2.Decrypt(mpk, keys[epoch], ciphertext)
+		assert.NoError(t, err, "decrypt round %d", n)
+		start, err := r.ChainStart(n)
+		assert.NoError(t, err, "chain start of round %d", n)
+		return v.Int64() + count(start-1)
+	}
+
+	expected := []int64{0, 0, 1, 2, 2, 3}
+	for n := 0; n <= 5; n++ {
+		assert.Equal(t, expected[n], count(n), "signals received within rounds [1; %d]", n)
+	}
+	assert.Equal(t, int64(2), count(5)-count(2), "signals received within rounds (2; 5]")
+}
+
+// Encrypts signal to i-th party and accumulates it into previous round
+func signalRound(mpk data.MPK, previous *data.Ciphertext, i int) *data.Round {
+	delta, proof, err := gofe2.EncryptSignal(mpk, i)
+	if err != nil {
+		panic(err)
+	}
+	ciphertext := delta.Copy()
+	if previous != nil {
+		ciphertext = previous.Copy()
+		if err := gofe2.Accumulate(mpk, ciphertext, &delta); err != nil {
+			panic(err)
+		}
+	}
+	return &data.Round{Ciphertext: *ciphertext, Delta: &delta, Proof: proof}
 }
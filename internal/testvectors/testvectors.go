@@ -0,0 +1,281 @@
+// Package testvectors generates known-answer test vectors from the real
+// scheme implementations and checks other implementations against them
+//
+// Suite is self-contained: it holds MPKs, derived keys of recipients, rounds
+// in the same format as repository stores them and values every recipient
+// must decrypt from every round. It's derived from a seed, so generating it
+// twice with the same seed gives the same suite.
+package testvectors
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+
+	"github.com/pkg/errors"
+
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/data"
+	"github.com/ZenGo-X/fe-hackaton-demo/internal/gofe"
+)
+
+// Version of suite format, it's increased on every incompatible change
+const Version = 1
+
+// Suite of known-answer test vectors
+type Suite struct {
+	Version int
+	// Seed all the randomness of the suite is derived from
+	Seed  string
+	Cases []Case
+}
+
+// Keys and rounds of a single repository
+type Case struct {
+	Scheme  string
+	Parties int
+	MPK     data.MPK
+	// Derived key of every party, Keys[j] belongs to party j+1
+	Keys   []data.RecipientSecretKey
+	Rounds []Round
+}
+
+// Round of repository along with expected decryptions
+type Round struct {
+	// Party the round signals (1-indexed)
+	Recipient int
+	data.Round
+	// Decryptions[j] is amount of signals party j+1 received within rounds
+	// [1; t], i.e. what its key decrypts from accumulated ciphertext
+	Decryptions []int64
+}
+
+// Outputs of implementation under test, cases and rounds go in the same
+// order as in the suite
+type Outputs struct {
+	Cases []CaseOutputs
+}
+
+type CaseOutputs struct {
+	// Decryptions[t][j] is value decrypted from round t+1 by key of party j+1
+	Decryptions [][]int64
+	// Accumulated ciphertexts of every round, they're optional, as receiver
+	// side doesn't need to accumulate rounds
+	Ciphertexts []data.Ciphertext `json:",omitempty"`
+}
+
+// Generates suite of test vectors for every scheme and amount of parties
+//
+// Every case has twice as many rounds as parties, recipients of signals are
+// chosen pseudo-randomly, so some parties receive several signals and some
+// none. Schemes must implement gofe.SeedableScheme.
+func Generate(seed string, schemes []string, parties []int) (*Suite, error) {
+	suite := &Suite{Version: Version, Seed: seed}
+	for _, id := range schemes {
+		scheme, err := gofe.LookupScheme(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range parties {
+			c, err := generateCase(seed, scheme, n)
+			if err != nil {
+				return nil, errors.Wrapf(err, "generate case %s with %d parties", id, n)
+			}
+			suite.Cases = append(suite.Cases, *c)
+		}
+	}
+	return suite, nil
+}
+
+func generateCase(seed string, scheme gofe.Scheme, parties int) (*Case, error) {
+	if parties < 2 {
+		return nil, errors.New("expected at least 2 parties")
+	}
+	random := func(label string) io.Reader {
+		return gofe.NewSeededReader([]byte(seed), fmt.Sprintf("testvectors/%s/%d/%s", scheme.ID(), parties, label))
+	}
+
+	mpk, keys, err := gofe.GenerateMasterKeysFrom(random("keygen"), scheme, parties)
+	if err != nil {
+		return nil, errors.Wrap(err, "keygen")
+	}
+	c := &Case{Scheme: scheme.ID(), Parties: parties, MPK: mpk, Keys: keys}
+
+	recipients := random("recipients")
+	counts := make([]int64, parties)
+	var previous *data.Ciphertext
+	for t := 1; t <= 2*parties; t++ {
+		j, err := rand.Int(recipients, big.NewInt(int64(parties)))
+		if err != nil {
+			return nil, errors.Wrap(err, "choose recipient")
+		}
+		i := int(j.Int64())
+		counts[i]++
+
+		delta, proof, err := gofe.EncryptSignalFrom(random(fmt.Sprintf("round/%d", t)), mpk, i)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encrypt round %d", t)
+		}
+		ciphertext := delta.Copy()
+		if previous != nil {
+			ciphertext = previous.Copy()
+			if err := gofe.Accumulate(mpk, ciphertext, &delta); err != nil {
+				return nil, errors.Wrapf(err, "accumulate round %d", t)
+			}
+		}
+		round := Round{
+			Recipient:   i + 1,
+			Round:       data.Round{Ciphertext: *ciphertext, Delta: &delta, Proof: proof},
+			Decryptions: append([]int64(nil), counts...),
+		}
+		c.Rounds = append(c.Rounds, round)
+		previous = ciphertext
+	}
+
+	// Expected values come from signals sent, decryption must agree with them
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Checks that suite is consistent: every round extends the previous one and
+// decrypts to expected values
+//
+// It detects corrupted suite files, as well as changes of schemes which
+// break compatibility with previously published vectors.
+func (s *Suite) Check() error {
+	if s.Version != Version {
+		return errors.Errorf("expected suite of version %d, got %d", Version, s.Version)
+	}
+	for k := range s.Cases {
+		c := &s.Cases[k]
+		if err := c.check(); err != nil {
+			return errors.Wrapf(err, "case %d (%s, %d parties)", k+1, c.Scheme, c.Parties)
+		}
+	}
+	return nil
+}
+
+func (c *Case) check() error {
+	if c.MPK.Scheme != c.Scheme || c.MPK.L != c.Parties || len(c.Keys) != c.Parties {
+		return errors.New("mpk and keys don't match scheme and amount of parties")
+	}
+	var previous *data.Ciphertext
+	for t := range c.Rounds {
+		round := &c.Rounds[t]
+		if err := gofe.VerifyRound(c.MPK, previous, &round.Round); err != nil {
+			return errors.Wrapf(err, "round %d", t+1)
+		}
+		if len(round.Decryptions) != c.Parties {
+			return errors.Errorf("round %d: expected %d decryptions, got %d", t+1, c.Parties, len(round.Decryptions))
+		}
+		for j, key := range c.Keys {
+			v, err := gofe.Decrypt(c.MPK, key, &round.Ciphertext)
+			if err != nil {
+				return errors.Wrapf(err, "round %d: decrypt by party %d", t+1, j+1)
+			}
+			if !v.IsInt64() || v.Int64() != round.Decryptions[j] {
+				return errors.Errorf("round %d: party %d decrypts %v, expected %d", t+1, j+1, v, round.Decryptions[j])
+			}
+		}
+		previous = &round.Ciphertext
+	}
+	return nil
+}
+
+// Compares outputs of implementation under test with the suite, every
+// mismatch is described by a line of returned list
+func Compare(suite *Suite, outputs *Outputs) []string {
+	var mismatches []string
+	if len(outputs.Cases) != len(suite.Cases) {
+		return []string{fmt.Sprintf("expected outputs of %d cases, got %d", len(suite.Cases), len(outputs.Cases))}
+	}
+	for k, c := range suite.Cases {
+		out := outputs.Cases[k]
+		prefix := fmt.Sprintf("case %d (%s, %d parties)", k+1, c.Scheme, c.Parties)
+		if len(out.Decryptions) != len(c.Rounds) {
+			mismatches = append(mismatches, fmt.Sprintf("%s: expected decryptions of %d rounds, got %d", prefix, len(c.Rounds), len(out.Decryptions)))
+			continue
+		}
+		if out.Ciphertexts != nil && len(out.Ciphertexts) != len(c.Rounds) {
+			mismatches = append(mismatches, fmt.Sprintf("%s: expected %d ciphertexts, got %d", prefix, len(c.Rounds), len(out.Ciphertexts)))
+			continue
+		}
+		for t, round := range c.Rounds {
+			if len(out.Decryptions[t]) != c.Parties {
+				mismatches = append(mismatches, fmt.Sprintf("%s, round %d: expected %d decryptions, got %d", prefix, t+1, c.Parties, len(out.Decryptions[t])))
+				continue
+			}
+			for j, expected := range round.Decryptions {
+				if got := out.Decryptions[t][j]; got != expected {
+					mismatches = append(mismatches, fmt.Sprintf("%s, round %d: party %d decrypted %d, expected %d", prefix, t+1, j+1, got, expected))
+				}
+			}
+			if out.Ciphertexts != nil && !out.Ciphertexts[t].Equal(&round.Ciphertext) {
+				mismatches = append(mismatches, fmt.Sprintf("%s, round %d: accumulated ciphertext differs", prefix, t+1))
+			}
+		}
+	}
+	return mismatches
+}
+
+// Saves suite to file, overwriting it if it exists
+//
+// File is replaced atomically, so a failed write never leaves truncated suite
+// behind.
+func (s *Suite) Save(filename string) error {
+	file, err := ioutil.TempFile(path.Dir(filename), path.Base(filename)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "create temp file")
+	}
+	// Suite is public, unlike keys saved by the rest of the code
+	err = file.Chmod(0644)
+	if err == nil {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = errors.Wrap(encoder.Encode(s), "encode suite")
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), filename)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return errors.Wrap(err, "write suite")
+	}
+	return nil
+}
+
+// Loads suite from file
+func LoadSuite(filename string) (*Suite, error) {
+	var suite Suite
+	if err := readJSON(filename, &suite); err != nil {
+		return nil, errors.Wrap(err, "load suite")
+	}
+	return &suite, nil
+}
+
+// Loads outputs of implementation under test from file
+func LoadOutputs(filename string) (*Outputs, error) {
+	var outputs Outputs
+	if err := readJSON(filename, &outputs); err != nil {
+		return nil, errors.Wrap(err, "load outputs")
+	}
+	return &outputs, nil
+}
+
+func readJSON(filename string, v interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return errors.Wrap(err, "open file")
+	}
+	defer file.Close()
+	return errors.Wrap(json.NewDecoder(file).Decode(v), "decode file")
+}
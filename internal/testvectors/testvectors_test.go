@@ -0,0 +1,82 @@
+package testvectors
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuite(t *testing.T) {
+	suite, err := Generate("seed", []string{"ddh", "ecddh"}, []int{2, 3})
+	assert.NoError(t, err, "generate")
+	assert.Len(t, suite.Cases, 4, "wrong amount of cases")
+	assert.NoError(t, suite.Check(), "generated suite is inconsistent")
+
+	// Create temp dir
+	dir, err := ioutil.TempDir("", "testvectors")
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	t.Run("Reproducible", func(t *testing.T) {
+		suite2, err := Generate("seed", []string{"ddh", "ecddh"}, []int{2, 3})
+		assert.NoError(t, err, "generate")
+		assert.Equal(t, suite, suite2, "suite isn't reproducible")
+	})
+
+	t.Run("SaveLoad", func(t *testing.T) {
+		filename := path.Join(dir, "suite.json")
+		assert.NoError(t, suite.Save(filename), "save")
+		loaded, err := LoadSuite(filename)
+		assert.NoError(t, err, "load")
+		assert.NoError(t, loaded.Check(), "loaded suite is inconsistent")
+		assert.Equal(t, suite.Cases[0].Rounds[0].Decryptions, loaded.Cases[0].Rounds[0].Decryptions)
+
+		// Overwriting leaves no temp files behind
+		assert.NoError(t, suite.Save(filename), "overwrite")
+		files, err := ioutil.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, files, 1, "temp file left behind")
+		assert.Error(t, suite.Save(path.Join(dir, "missing", "suite.json")), "saved into missing dir")
+	})
+
+	t.Run("Compare", func(t *testing.T) {
+		outputs := &Outputs{}
+		for _, c := range suite.Cases {
+			out := CaseOutputs{}
+			for _, round := range c.Rounds {
+				out.Decryptions = append(out.Decryptions, append([]int64(nil), round.Decryptions...))
+				out.Ciphertexts = append(out.Ciphertexts, *round.Ciphertext.Copy())
+			}
+			outputs.Cases = append(outputs.Cases, out)
+		}
+		assert.Empty(t, Compare(suite, outputs), "correct outputs don't conform")
+
+		outputs.Cases[1].Decryptions[2][0]++
+		outputs.Cases[3].Ciphertexts[1] = outputs.Cases[3].Ciphertexts[0]
+		assert.Len(t, Compare(suite, outputs), 2, "wrong outputs conform")
+
+		outputs.Cases = outputs.Cases[1:]
+		assert.Len(t, Compare(suite, outputs), 1, "missing case isn't reported")
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		loaded, err := LoadSuite(path.Join(dir, "suite.json"))
+		assert.NoError(t, err, "load")
+		loaded.Cases[0].Rounds[1].Decryptions[0]++
+		assert.Error(t, loaded.Check(), "tampered decryption passed check")
+
+		loaded, _ = LoadSuite(path.Join(dir, "suite.json"))
+		loaded.Cases[2].Rounds[1].Delta = loaded.Cases[2].Rounds[0].Delta
+		assert.Error(t, loaded.Check(), "tampered delta passed check")
+	})
+
+	_, err = Generate("seed", []string{"dmcfe"}, []int{2})
+	assert.Error(t, err, "generated vectors of scheme which can't be seeded")
+}